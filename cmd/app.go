@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"onlearn-backend/config"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/internal/repository"
+	"onlearn-backend/internal/usecase"
+	"onlearn-backend/pkg/logging"
+	"onlearn-backend/pkg/mailer"
+	"onlearn-backend/pkg/oidc"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// app - Semua repository dan usecase yang sudah dirangkai; dipakai server maupun command CLI
+type app struct {
+	db     *config.Database
+	mail   mailer.Mailer
+	logger *slog.Logger
+
+	userRepo   domain.UserRepository
+	gridFSRepo repository.GridFSRepository
+
+	authUsecase         domain.AuthUsecase
+	userUsecase         domain.UserUsecase
+	courseUsecase       domain.CourseUsecase
+	labUsecase          domain.LabUsecase
+	certUsecase         domain.CertificateUsecase
+	dashboardUsecase    domain.DashboardUsecase
+	reportUsecase       domain.ReportUsecase
+	oidcUsecase         domain.OIDCUsecase
+	auditUsecase        domain.AuditUsecase
+	accountUsecase      domain.AccountUsecase
+	invitationUsecase   domain.InvitationUsecase
+	organizationUsecase domain.OrganizationUsecase
+}
+
+// newApp merangkai repository dan usecase. Migrasi tidak dijalankan di sini.
+func newApp(cfg *config.Config, db *config.Database, logger *slog.Logger) (*app, error) {
+	postgres := db.PG
+	mongo := db.Mongo
+	appURL := cfg.AppURL
+
+	// ========== Initialize Repositories ==========
+	userRepo := repository.NewUserRepository(postgres)
+	courseRepo := repository.NewCourseRepository(postgres)
+	enrollmentRepo := repository.NewEnrollmentRepository(postgres)
+	moduleProgressRepo := repository.NewModuleProgressRepository(postgres)
+	assignmentRepo := repository.NewAssignmentRepository(postgres)
+	labRepo := repository.NewLabRepository(postgres)
+	certRepo := repository.NewCertificateRepository(postgres)
+	moduleRepo := repository.NewModuleRepository(mongo)
+	sessionRepo := repository.NewSessionRepository(postgres)
+	verificationRepo := repository.NewEmailVerificationRepository(postgres)
+	passwordResetRepo := repository.NewPasswordResetRepository(postgres)
+	loginThrottleRepo := repository.NewLoginThrottleRepository(postgres)
+	lockoutEventRepo := repository.NewLockoutEventRepository(postgres)
+	mfaRepo := repository.NewMFARepository(postgres)
+	oidcRepo := repository.NewOIDCRepository(postgres)
+	auditRepo := repository.NewAuditLogRepository(postgres)
+	apiKeyRepo := repository.NewAPIKeyRepository(postgres)
+	accountRepo := repository.NewAccountRepository(postgres)
+	invitationRepo := repository.NewInvitationRepository(postgres)
+	organizationRepo := repository.NewOrganizationRepository(postgres)
+
+	// Initialize GridFS Repository for file storage
+	gridFSRepo, err := repository.NewGridFSRepository(mongo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize GridFS: %w", err)
+	}
+	logger.Info("GridFS initialized", "bucket", "uploads")
+
+	// Initialize mailer (MAIL_DRIVER: "smtp" atau "log")
+	mail, err := newMailer(cfg.Mail, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+
+	// ========== Initialize Usecases ==========
+	// Audit log untuk aksi privileged (nilai, sertifikat, user, course, lab, impersonasi)
+	auditUsecase := usecase.NewAuditUsecase(auditRepo, logger)
+
+	authUsecase := usecase.NewAuthUsecase(
+		userRepo,
+		sessionRepo,
+		verificationRepo,
+		passwordResetRepo,
+		loginThrottleRepo,
+		lockoutEventRepo,
+		mfaRepo,
+		apiKeyRepo,
+		auditUsecase,
+		mail,
+		domain.UnverifiedLoginPolicy(cfg.UnverifiedLoginPolicy),
+		appURL,
+		logger,
+	)
+
+	// Export data dan penghapusan akun (Postgres + GridFS)
+	accountUsecase := usecase.NewAccountUsecase(
+		accountRepo,
+		userRepo,
+		courseRepo,
+		labRepo,
+		gridFSRepo,
+		auditUsecase,
+		logger,
+	)
+
+	// Policy layer: pemetaan role -> permission dan pengecekan kepemilikan resource
+	policy := usecase.NewPolicy(userRepo)
+
+	courseUsecase := usecase.NewCourseUsecase(
+		courseRepo,
+		moduleRepo,
+		enrollmentRepo,
+		moduleProgressRepo,
+		assignmentRepo,
+		certRepo,
+		userRepo,
+		policy,
+		auditUsecase,
+	)
+
+	labUsecase := usecase.NewLabUsecase(
+		labRepo,
+		userRepo,
+		certRepo,
+		policy,
+		auditUsecase,
+	)
+
+	certUsecase := usecase.NewCertificateUsecase(
+		certRepo,
+		userRepo,
+		courseRepo,
+		labRepo,
+		policy,
+		auditUsecase,
+	)
+
+	dashboardUsecase := usecase.NewDashboardUsecase(
+		userRepo,
+		courseRepo,
+		enrollmentRepo,
+		moduleRepo,
+		moduleProgressRepo,
+		assignmentRepo,
+		labRepo,
+		certRepo,
+	)
+
+	// Undangan pendaftaran dan aturan pendaftaran publik
+	invitationUsecase := usecase.NewInvitationUsecase(
+		invitationRepo,
+		organizationRepo,
+		userRepo,
+		courseRepo,
+		labRepo,
+		enrollmentRepo,
+		authUsecase,
+		policy,
+		mail,
+		auditUsecase,
+		newSignupPolicy(cfg, logger),
+		appURL,
+		logger,
+	)
+
+	// Login SSO; akun baru lewat OIDC tunduk pada undangan dan aturan pendaftaran publik
+	oidcUsecase := usecase.NewOIDCUsecase(
+		userRepo,
+		oidcRepo,
+		authUsecase,
+		invitationUsecase,
+		newOIDCProvider(cfg.OIDC, logger),
+		usecase.OIDCOptions{
+			DefaultRole:   domain.Role(cfg.OIDC.DefaultRole),
+			AutoProvision: cfg.OIDC.AutoProvision,
+			EmailClaim:    cfg.OIDC.EmailClaim,
+			NameClaim:     cfg.OIDC.NameClaim,
+			RoleClaim:     cfg.OIDC.RoleClaim,
+		},
+		logger,
+	)
+
+	userUsecase := usecase.NewUserUsecase(
+		userRepo,
+		courseRepo,
+		enrollmentRepo,
+		labRepo,
+		invitationUsecase,
+		auditUsecase,
+		accountUsecase,
+	)
+
+	// Organisasi (multi-tenant), dikelola super admin
+	organizationUsecase := usecase.NewOrganizationUsecase(
+		organizationRepo,
+		userRepo,
+		courseRepo,
+		labRepo,
+		authUsecase,
+		auditUsecase,
+		logger,
+	)
+
+	reportUsecase := usecase.NewReportUsecase(
+		userRepo,
+		enrollmentRepo,
+		assignmentRepo,
+		certRepo,
+	)
+
+	return &app{
+		db:     db,
+		mail:   mail,
+		logger: logger,
+
+		userRepo:   userRepo,
+		gridFSRepo: gridFSRepo,
+
+		authUsecase:         authUsecase,
+		userUsecase:         userUsecase,
+		courseUsecase:       courseUsecase,
+		labUsecase:          labUsecase,
+		certUsecase:         certUsecase,
+		dashboardUsecase:    dashboardUsecase,
+		reportUsecase:       reportUsecase,
+		oidcUsecase:         oidcUsecase,
+		auditUsecase:        auditUsecase,
+		accountUsecase:      accountUsecase,
+		invitationUsecase:   invitationUsecase,
+		organizationUsecase: organizationUsecase,
+	}, nil
+}
+
+func (a *app) Close() {
+	a.mail.Close()
+}
+
+// newMailer membuat mailer sesuai MAIL_DRIVER ("smtp" atau "log")
+func newMailer(cfg config.MailConfig, logger *slog.Logger) (mailer.Mailer, error) {
+	var transport mailer.Transport
+	switch cfg.Driver {
+	case "smtp":
+		transport = mailer.NewSMTPTransport(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword.Value(),
+		})
+		logger.Info("Mailer using SMTP", "host", cfg.SMTPHost)
+	default:
+		transport = mailer.NewLogTransport(cfg.LogDir, logger)
+		logger.Info("Mailer using log transport, emails are not sent", "dir", cfg.LogDir)
+	}
+
+	return mailer.New(transport, mailer.Options{
+		From:          cfg.From,
+		DefaultLocale: cfg.Locale,
+		Logger:        logger,
+	})
+}
+
+// newOIDCProvider membuat provider OpenID Connect jika OIDC_ISSUER_URL diisi
+func newOIDCProvider(cfg config.OIDCConfig, logger *slog.Logger) domain.OIDCProvider {
+	if cfg.IssuerURL == "" {
+		return nil
+	}
+
+	logger.Info("OIDC login enabled", "issuer", cfg.IssuerURL)
+	return oidc.New(oidc.Config{
+		IssuerURL:    cfg.IssuerURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret.Value(),
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	})
+}
+
+// newSignupPolicy - Aturan pendaftaran publik (REGISTRATION_MODE, REGISTRATION_ALLOWED_DOMAINS)
+func newSignupPolicy(cfg *config.Config, logger *slog.Logger) domain.SignupPolicy {
+	policy := domain.SignupPolicy{
+		InviteOnly:     cfg.RegistrationMode == "invite_only",
+		AllowedDomains: cfg.RegistrationAllowedDomains,
+	}
+
+	if policy.InviteOnly {
+		logger.Info("Public registration disabled (invite only)")
+	} else if len(policy.AllowedDomains) > 0 {
+		logger.Info("Public registration limited to allowed domains", "domains", policy.AllowedDomains)
+	}
+	return policy
+}
+
+// newTracerProvider membuat TracerProvider OpenTelemetry sesuai OTEL_TRACES_EXPORTER;
+// nil jika "none". Span di-batch di background; trace dari pemanggil yang sudah di-sample
+// selalu diikuti, trace baru di-sample sebesar OTEL_TRACES_SAMPLER_ARG.
+func newTracerProvider(cfg config.TracingConfig, logger *slog.Logger) (*sdktrace.TracerProvider, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		logger.Info("Tracing enabled", "exporter", "stdout", "sample_ratio", cfg.SampleRatio)
+	case "otlp":
+		// OTLP/HTTP ke collector (mis. http://otel-collector:4318); path /v1/traces ditambahkan jika belum ada
+		endpoint := strings.TrimRight(cfg.OTLPEndpoint, "/")
+		if !strings.HasSuffix(endpoint, "/v1/traces") {
+			endpoint += "/v1/traces"
+		}
+		exporter, err = otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpointURL(endpoint),
+			otlptracehttp.WithHeaders(cfg.Headers()),
+		)
+		logger.Info("Tracing enabled", "exporter", "otlp", "endpoint", cfg.OTLPEndpoint, "sample_ratio", cfg.SampleRatio)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create trace exporter: %w", err)
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("Tracing error", "error", err)
+	}))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	), nil
+}
+
+// newLogger membuat logger slog sesuai LOG_LEVEL dan LOG_FORMAT
+func newLogger(cfg config.LogConfig) *slog.Logger {
+	level, _ := logging.ParseLevel(cfg.Level) // Sudah divalidasi oleh config.Load
+	return logging.New(logging.Options{
+		Level:        level,
+		Format:       cfg.Format,
+		Output:       os.Stdout,
+		ContextAttrs: logContextAttrs,
+	})
+}
+
+// logContextAttrs - Atribut korelasi yang ikut di setiap log: request, user, organisasi dan trace
+func logContextAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	if requestID := domain.RequestIDFromContext(ctx); requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	if user, ok := domain.UserFromContext(ctx); ok {
+		attrs = append(attrs, slog.Any("user_id", user.ID), slog.String("role", string(user.Role)))
+	}
+	if orgID, ok := domain.OrganizationFromContext(ctx); ok {
+		attrs = append(attrs, slog.Any("organization_id", orgID))
+	}
+	if impersonatorID, ok := domain.ImpersonatorFromContext(ctx); ok {
+		attrs = append(attrs, slog.Any("impersonator_id", impersonatorID))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return attrs
+}
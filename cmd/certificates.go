@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"onlearn-backend/config"
+)
+
+// runCertificates - certificates regenerate [-id 1,2,3]
+func runCertificates(cfg *config.Config, logger *slog.Logger, args []string) error {
+	sub, args, err := subcommand("certificates", args)
+	if err != nil {
+		return err
+	}
+	if sub != "regenerate" {
+		return fmt.Errorf("unknown certificates subcommand %q", sub)
+	}
+
+	fs := flag.NewFlagSet("certificates regenerate", flag.ExitOnError)
+	idList := fs.String("id", "", "comma separated certificate IDs; all certificates when empty")
+	fs.Parse(args)
+
+	var ids []uint
+	for _, part := range strings.Split(*idList, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid certificate id %q", part)
+		}
+		ids = append(ids, uint(id))
+	}
+
+	a, err := newApp(cfg, config.ConnectDB(cfg, logger), logger)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	regenerated, err := a.certUsecase.RegenerateCertificates(context.Background(), ids, 0)
+	fmt.Printf("Regenerated %d certificate(s)\n", regenerated)
+	return err
+}
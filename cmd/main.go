@@ -1,193 +1,97 @@
 package main
 
 import (
-	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
 
 	"onlearn-backend/config"
-	httpDelivery "onlearn-backend/internal/delivery/http"
-	"onlearn-backend/internal/domain"
-	"onlearn-backend/internal/repository"
-	"onlearn-backend/internal/usecase"
-
-	"github.com/joho/godotenv"
+	"onlearn-backend/pkg/utils"
 )
 
-func main() {
-	// Load .env
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
-	}
-
-	// Connect to databases
-	db := config.ConnectDB()
-	postgres := db.PG
-	mongo := db.Mongo
-
-	// Auto migrate
-	if err := config.AutoMigrate(postgres); err != nil {
-		log.Fatal("Migration failed:", err)
-	}
-
-	// ========== Initialize Repositories ==========
-	userRepo := repository.NewUserRepository(postgres)
-	courseRepo := repository.NewCourseRepository(postgres)
-	enrollmentRepo := repository.NewEnrollmentRepository(postgres)
-	moduleProgressRepo := repository.NewModuleProgressRepository(postgres)
-	assignmentRepo := repository.NewAssignmentRepository(postgres)
-	labRepo := repository.NewLabRepository(postgres)
-	certRepo := repository.NewCertificateRepository(postgres)
-	moduleRepo := repository.NewModuleRepository(mongo)
-
-	// Initialize GridFS Repository for file storage
-	gridFSRepo, err := repository.NewGridFSRepository(mongo)
-	if err != nil {
-		log.Fatal("Failed to initialize GridFS:", err)
-	}
-	log.Println("✅ GridFS initialized successfully")
-
-	// ========== Initialize Usecases ==========
-	authUsecase := usecase.NewAuthUsecase(userRepo)
-
-	userUsecase := usecase.NewUserUsecase(userRepo)
-
-	courseUsecase := usecase.NewCourseUsecase(
-		courseRepo,
-		moduleRepo,
-		enrollmentRepo,
-		moduleProgressRepo,
-		assignmentRepo,
-		certRepo,
-		userRepo,
-	)
-
-	labUsecase := usecase.NewLabUsecase(
-		labRepo,
-		userRepo,
-		certRepo,
-	)
-
-	certUsecase := usecase.NewCertificateUsecase(
-		certRepo,
-		userRepo,
-		courseRepo,
-		labRepo,
-	)
-
-	dashboardUsecase := usecase.NewDashboardUsecase(
-		userRepo,
-		courseRepo,
-		enrollmentRepo,
-		moduleRepo,
-		moduleProgressRepo,
-		assignmentRepo,
-		labRepo,
-		certRepo,
-	)
-
-	reportUsecase := usecase.NewReportUsecase(
-		userRepo,
-		enrollmentRepo,
-		assignmentRepo,
-		certRepo,
-	)
-
-	// Seed demo users
-	seedUsers(authUsecase)
+// commands - Subcommand CLI; tanpa argumen, server dijalankan ("serve")
+var commands = map[string]func(cfg *config.Config, logger *slog.Logger, args []string) error{
+	"serve":        runServe,
+	"migrate":      runMigrate,
+	"seed":         runSeed,
+	"user":         runUser,
+	"certificates": runCertificates,
+	"config":       runShowConfig,
+}
 
-	// ========== Initialize Handlers ==========
-	apiHandler := httpDelivery.NewHandler(
-		authUsecase,
-		userUsecase,
-		courseUsecase,
-		labUsecase,
-		certUsecase,
-		dashboardUsecase,
-		reportUsecase,
-	)
+const usage = `Usage: onlearn [-config FILE] [-env ENV] [-port PORT] [-app-url URL] <command> [arguments]
 
-	webHandler := httpDelivery.NewWebHandler(
-		authUsecase,
-		courseUsecase,
-		labUsecase,
-		certUsecase,
-		dashboardUsecase,
-	)
+Configuration is read from flags, environment variables, the -config file
+(or CONFIG_FILE) and .env, in that order of precedence.
 
-	fileHandler := httpDelivery.NewFileHandler(gridFSRepo)
-	fileHandler.SetCourseUsecase(courseUsecase)
+Commands:
+  serve [-migrate=false]                     Start the HTTP server (default)
+  migrate up                                 Apply pending migrations
+  migrate down [-steps N]                    Roll back the last N migrations (default 1)
+  migrate status                             Show applied and pending migrations
+  seed --demo                                Create demo accounts (password123), never in production
+  user create-admin -email E -name N [-password P] [-org SLUG | -super]
+                                             Create an organization admin or a super admin
+  user reset-password -email E [-password P] Set a new password and revoke all sessions
+  certificates regenerate [-id N,...]        Rebuild certificate file URLs (all if -id is empty)
+  config                                     Print the effective configuration (secrets redacted)
 
-	// ========== Initialize Router ==========
-	router := httpDelivery.InitRouter(apiHandler)
-	httpDelivery.InitWebRouter(router, webHandler)
-	httpDelivery.InitFileRouter(router, fileHandler)
+Passwords that are not given are generated and printed once.
+`
 
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "help" {
+		fmt.Print(usage)
+		return
 	}
 
-	log.Printf("=================================================")
-	log.Printf(" OnLearn Backend Server Starting...")
-	log.Printf("=================================================")
-	log.Printf(" Server running on port %s", port)
-	log.Printf(" Web UI: http://localhost:%s", port)
-	log.Printf(" API: http://localhost:%s/api/v1", port)
-	log.Printf("=================================================")
-	log.Printf(" Demo Accounts:")
-	log.Printf("   Student: student@onlearn.com / password123")
-	log.Printf("   Instructor: instructor@onlearn.com / password123")
-	log.Printf("=================================================")
-
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	// Konfigurasi tidak valid = berhenti sebelum menyentuh database
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
 	}
-}
+	utils.SetJWTSecret(cfg.JWTSecret.Value())
 
-func seedUsers(authUsecase domain.AuthUsecase) {
-	ctx := context.Background()
+	// Logger terstruktur (LOG_LEVEL, LOG_FORMAT); log standar ikut diarahkan ke sini
+	logger := newLogger(cfg.Log)
+	slog.SetDefault(logger)
 
-	// Student
-	student := &domain.User{
-		Name:     "Demo Student",
-		Email:    "student@onlearn.com",
-		Password: "password123",
-		Role:     domain.RoleStudent,
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
-	err := authUsecase.Register(ctx, student)
-	if err != nil && err.Error() != "email already exists" {
-		log.Printf("Failed to seed student: %v", err)
-	} else if err == nil {
-		log.Println("✅ Demo student account created")
+	if name == "help" {
+		fmt.Print(usage)
+		return
 	}
-
-	// Instructor
-	instructor := &domain.User{
-		Name:     "Demo Instructor",
-		Email:    "instructor@onlearn.com",
-		Password: "password123",
-		Role:     domain.RoleInstructor,
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
 	}
-	err = authUsecase.Register(ctx, instructor)
-	if err != nil && err.Error() != "email already exists" {
-		log.Printf("Failed to seed instructor: %v", err)
-	} else if err == nil {
-		log.Println("✅ Demo instructor account created")
+	if err := run(cfg, logger, args); err != nil {
+		logger.Error("Command failed", "command", name, "error", err)
+		os.Exit(1)
 	}
+}
 
-	// Admin
-	admin := &domain.User{
-		Name:     "Demo Admin",
-		Email:    "admin@onlearn.com",
-		Password: "password123",
-		Role:     domain.RoleAdmin,
+// runShowConfig mencetak konfigurasi efektif sebagai JSON; nilai Secret disamarkan
+func runShowConfig(cfg *config.Config, logger *slog.Logger, args []string) error {
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
 	}
-	err = authUsecase.Register(ctx, admin)
-	if err != nil && err.Error() != "email already exists" {
-		log.Printf("Failed to seed admin: %v", err)
-	} else if err == nil {
-		log.Println("✅ Demo admin account created")
+	fmt.Println(string(out))
+	return nil
+}
+
+// subcommand memisahkan sub-subcommand (mis. "up" pada "migrate up") dari argumennya
+func subcommand(command string, args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", nil, fmt.Errorf("missing %s subcommand, see \"help\"", command)
 	}
+	return args[0], args[1:], nil
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"onlearn-backend/config"
+)
+
+// runMigrate - migrate up | down [-steps N] | status
+func runMigrate(cfg *config.Config, logger *slog.Logger, args []string) error {
+	sub, args, err := subcommand("migrate", args)
+	if err != nil {
+		return err
+	}
+
+	db := config.ConnectDB(cfg, logger)
+	migrator, err := config.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch sub {
+	case "up":
+		return config.Migrate(db)
+
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "number of migrations to roll back")
+		fs.Parse(args)
+		if *steps < 1 {
+			return fmt.Errorf("steps must be at least 1")
+		}
+
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("Rolled back migration %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to roll back")
+		}
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q", sub)
+	}
+}
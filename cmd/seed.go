@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+
+	"onlearn-backend/config"
+	"onlearn-backend/internal/domain"
+)
+
+// runSeed - seed --demo membuat akun demo dengan password "password123".
+// Harus diminta secara eksplisit dan ditolak di production agar akun demo dengan
+// password yang diketahui publik tidak pernah ada di sana.
+func runSeed(cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	demo := fs.Bool("demo", false, "create demo accounts with password \"password123\"")
+	fs.Parse(args)
+	if !*demo {
+		return errors.New("nothing to seed, use --demo to create demo accounts")
+	}
+	if cfg.IsProduction() {
+		return errors.New("refusing to create demo accounts in production")
+	}
+
+	a, err := newApp(cfg, config.ConnectDB(cfg, logger), logger)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	seedUsers(a.authUsecase, logger)
+
+	logger.Info("Demo accounts ready",
+		"student", "student@onlearn.com",
+		"instructor", "instructor@onlearn.com",
+		"admin", "admin@onlearn.com",
+		"super_admin", "superadmin@onlearn.com",
+	)
+	return nil
+}
+
+func seedUsers(authUsecase domain.AuthUsecase, logger *slog.Logger) {
+	ctx := context.Background()
+
+	// Student
+	student := &domain.User{
+		Name:       "Demo Student",
+		Email:      "student@onlearn.com",
+		Password:   "password123",
+		Role:       domain.RoleStudent,
+		IsVerified: true,
+	}
+	err := authUsecase.Register(ctx, student)
+	if err != nil && !errors.Is(err, domain.ErrEmailExists) {
+		logger.Warn("Failed to seed demo account", "email", student.Email, "error", err)
+	} else if err == nil {
+		logger.Info("Demo account created", "email", student.Email)
+	}
+
+	// Instructor
+	instructor := &domain.User{
+		Name:       "Demo Instructor",
+		Email:      "instructor@onlearn.com",
+		Password:   "password123",
+		Role:       domain.RoleInstructor,
+		IsVerified: true,
+	}
+	err = authUsecase.Register(ctx, instructor)
+	if err != nil && !errors.Is(err, domain.ErrEmailExists) {
+		logger.Warn("Failed to seed demo account", "email", instructor.Email, "error", err)
+	} else if err == nil {
+		logger.Info("Demo account created", "email", instructor.Email)
+	}
+
+	// Admin
+	admin := &domain.User{
+		Name:       "Demo Admin",
+		Email:      "admin@onlearn.com",
+		Password:   "password123",
+		Role:       domain.RoleAdmin,
+		IsVerified: true,
+	}
+	err = authUsecase.Register(ctx, admin)
+	if err != nil && !errors.Is(err, domain.ErrEmailExists) {
+		logger.Warn("Failed to seed demo account", "email", admin.Email, "error", err)
+	} else if err == nil {
+		logger.Info("Demo account created", "email", admin.Email)
+	}
+
+	// Super Admin (lintas organisasi)
+	superAdmin := &domain.User{
+		Name:       "Demo Super Admin",
+		Email:      "superadmin@onlearn.com",
+		Password:   "password123",
+		Role:       domain.RoleSuperAdmin,
+		IsVerified: true,
+	}
+	err = authUsecase.Register(ctx, superAdmin)
+	if err != nil && !errors.Is(err, domain.ErrEmailExists) {
+		logger.Warn("Failed to seed demo account", "email", superAdmin.Email, "error", err)
+	} else if err == nil {
+		logger.Info("Demo account created", "email", superAdmin.Email)
+	}
+}
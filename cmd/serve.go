@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"onlearn-backend/config"
+	httpDelivery "onlearn-backend/internal/delivery/http"
+	"onlearn-backend/internal/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// runServe menjalankan HTTP server. Migrasi dijalankan lebih dulu kecuali -migrate=false;
+// akun demo tidak pernah dibuat di sini (pakai "seed --demo").
+func runServe(cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	migrate := fs.Bool("migrate", true, "apply pending database migrations before starting")
+	fs.Parse(args)
+
+	db := config.ConnectDB(cfg, logger)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.Close(ctx); err != nil {
+			logger.Warn("Failed to close database connections", "error", err)
+		}
+	}()
+	if *migrate {
+		// Migrasi skema berversi (lihat config/migrations)
+		if err := config.Migrate(db); err != nil {
+			return err
+		}
+	}
+
+	a, err := newApp(cfg, db, logger)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	// Tracing (OTEL_TRACES_EXPORTER); span yang tersisa dikirim saat shutdown
+	provider, err := newTracerProvider(cfg.Tracing, logger)
+	if err != nil {
+		return err
+	}
+	if provider != nil {
+		otel.SetTracerProvider(provider)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := provider.Shutdown(ctx); err != nil {
+				logger.Warn("Failed to flush traces", "error", err)
+			}
+		}()
+	}
+
+	// SIGINT/SIGTERM memulai graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Hapus akun yang masa tenggangnya sudah lewat
+	purgerDone := make(chan struct{})
+	go func() {
+		defer close(purgerDone)
+		runAccountPurger(ctx, a.accountUsecase, logger, time.Hour)
+	}()
+
+	// ========== Initialize Handlers ==========
+	apiHandler := httpDelivery.NewHandler(
+		a.authUsecase,
+		a.userUsecase,
+		a.courseUsecase,
+		a.labUsecase,
+		a.certUsecase,
+		a.dashboardUsecase,
+		a.reportUsecase,
+		a.oidcUsecase,
+		a.auditUsecase,
+		a.accountUsecase,
+		a.invitationUsecase,
+		a.organizationUsecase,
+		logger,
+	)
+
+	webHandler := httpDelivery.NewWebHandler(
+		a.authUsecase,
+		a.courseUsecase,
+		a.labUsecase,
+		a.certUsecase,
+		a.dashboardUsecase,
+		a.oidcUsecase,
+		a.accountUsecase,
+		a.invitationUsecase,
+		logger,
+	)
+
+	fileHandler := httpDelivery.NewFileHandler(a.gridFSRepo, logger)
+	fileHandler.SetCourseUsecase(a.courseUsecase)
+
+	// ========== Initialize Router ==========
+	router := httpDelivery.InitRouter(apiHandler)
+	// IP klien (login throttle, audit, session) hanya boleh dari X-Forwarded-For proxy tepercaya
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	httpDelivery.InitWebRouter(router, webHandler)
+	httpDelivery.InitFileRouter(router, fileHandler, a.authUsecase)
+
+	health := httpDelivery.NewHealthHandler(cfg.ReadinessTimeout, logger,
+		httpDelivery.HealthCheck{Name: "postgres", Check: db.PingPostgres},
+		httpDelivery.HealthCheck{Name: "mongo", Check: db.PingMongo},
+	)
+	httpDelivery.InitHealthRouter(router, health)
+	httpDelivery.InitMetricsRouter(router, cfg.MetricsToken.Value())
+
+	port := cfg.Port
+	logger.Info("OnLearn Backend Server starting",
+		"port", port,
+		"web_ui", "http://localhost:"+port,
+		"api", "http://localhost:"+port+"/api/v1",
+		"env", cfg.Env,
+	)
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	stop() // Sinyal kedua langsung menghentikan proses
+
+	// Readiness gagal lebih dulu dan server tetap melayani request baru selama
+	// ShutdownDelay sampai load balancer berhenti mengirim traffic, lalu tunggu
+	// request yang sedang berjalan (mis. upload GridFS)
+	health.SetDraining()
+	if cfg.ShutdownDelay > 0 {
+		logger.Info("Shutting down, waiting for load balancers to stop sending traffic", "delay", cfg.ShutdownDelay.String())
+		select {
+		case <-time.After(cfg.ShutdownDelay):
+		case err := <-serveErr:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+		}
+	}
+	logger.Info("Shutting down, draining in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Graceful shutdown did not finish", "error", err)
+		srv.Close()
+	}
+
+	select {
+	case <-purgerDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("Account purger did not stop before the shutdown timeout")
+	}
+	logger.Info("Server stopped")
+	return nil
+}
+
+// runAccountPurger menjalankan penghapusan akun terjadwal secara berkala sampai ctx selesai
+func runAccountPurger(ctx context.Context, account domain.AccountUsecase, logger *slog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Satu putaran penghapusan tidak dipotong di tengah jalan oleh shutdown
+		runCtx, span := otel.Tracer("onlearn-backend/cmd").Start(context.Background(), "AccountPurger.Run")
+		purged, err := account.PurgeDueDeletions(runCtx)
+		span.SetAttributes(attribute.Int("account.purged", purged))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if err != nil {
+			logger.WarnContext(runCtx, "Failed to process scheduled account deletions", "error", err)
+		} else if purged > 0 {
+			logger.InfoContext(runCtx, "Deleted accounts after grace period", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
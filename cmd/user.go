@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"onlearn-backend/config"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/utils"
+)
+
+// runUser - user create-admin | reset-password
+func runUser(cfg *config.Config, logger *slog.Logger, args []string) error {
+	sub, args, err := subcommand("user", args)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "create-admin":
+		return runCreateAdmin(cfg, logger, args)
+	case "reset-password":
+		return runResetPassword(cfg, logger, args)
+	default:
+		return fmt.Errorf("unknown user subcommand %q", sub)
+	}
+}
+
+func runCreateAdmin(cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("user create-admin", flag.ExitOnError)
+	email := fs.String("email", "", "email address (required)")
+	name := fs.String("name", "", "full name (required)")
+	password := fs.String("password", "", "password; generated when empty")
+	super := fs.Bool("super", false, "create a super admin (all organizations) instead of an organization admin")
+	org := fs.String("org", "", "organization slug for an organization admin; default organization when empty")
+	fs.Parse(args)
+
+	if strings.TrimSpace(*email) == "" || strings.TrimSpace(*name) == "" {
+		return errors.New("-email and -name are required")
+	}
+	if *super && *org != "" {
+		return errors.New("super admins do not belong to an organization, drop -org")
+	}
+
+	a, err := newApp(cfg, config.ConnectDB(cfg, logger), logger)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	user := &domain.User{
+		Name:       strings.TrimSpace(*name),
+		Email:      strings.ToLower(strings.TrimSpace(*email)),
+		Role:       domain.RoleAdmin,
+		IsVerified: true,
+	}
+	if *super {
+		user.Role = domain.RoleSuperAdmin
+	}
+	if *org != "" {
+		orgID, err := organizationIDBySlug(ctx, a, *org)
+		if err != nil {
+			return err
+		}
+		user.OrganizationID = &orgID
+	}
+
+	plain, generated, err := passwordOrGenerate(*password)
+	if err != nil {
+		return err
+	}
+	user.Password = plain
+	if err := a.authUsecase.Register(ctx, user); err != nil {
+		return err
+	}
+	a.auditUsecase.Record(ctx, 0, domain.AuditUserCreate, domain.AuditTargetUser, strconv.FormatUint(uint64(user.ID), 10), nil, user)
+
+	fmt.Printf("Created %s %s (id %d)\n", user.Role, user.Email, user.ID)
+	if generated {
+		fmt.Printf("Password: %s\n", plain)
+	}
+	return nil
+}
+
+func runResetPassword(cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ExitOnError)
+	email := fs.String("email", "", "email address of the account (required)")
+	password := fs.String("password", "", "new password; generated when empty")
+	fs.Parse(args)
+
+	if strings.TrimSpace(*email) == "" {
+		return errors.New("-email is required")
+	}
+
+	a, err := newApp(cfg, config.ConnectDB(cfg, logger), logger)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	user, err := a.userRepo.GetByEmail(ctx, strings.ToLower(strings.TrimSpace(*email)))
+	if err != nil || user == nil || user.ID == 0 {
+		return errors.New("user not found")
+	}
+
+	plain, generated, err := passwordOrGenerate(*password)
+	if err != nil {
+		return err
+	}
+	if err := a.authUsecase.SetPassword(ctx, user.ID, plain, 0); err != nil {
+		return err
+	}
+
+	fmt.Printf("Password of %s updated, all sessions revoked\n", user.Email)
+	if generated {
+		fmt.Printf("Password: %s\n", plain)
+	}
+	return nil
+}
+
+// passwordOrGenerate memakai password dari flag, atau membuat password acak jika kosong
+func passwordOrGenerate(password string) (string, bool, error) {
+	if password != "" {
+		return password, false, nil
+	}
+	generated, err := utils.GenerateSecureToken(12)
+	return generated, true, err
+}
+
+func organizationIDBySlug(ctx context.Context, a *app, slug string) (uint, error) {
+	orgs, err := a.organizationUsecase.ListOrganizations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, org := range orgs {
+		if org.Slug == strings.ToLower(strings.TrimSpace(slug)) {
+			return org.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("organization %q not found", slug)
+}
@@ -0,0 +1,493 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"onlearn-backend/pkg/logging"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	// devJWTSecret hanya dipakai di development jika JWT_SECRET kosong; ditolak di production
+	devJWTSecret = "default-secret-key-change-in-production"
+
+	minProductionSecretLength = 32
+)
+
+// Secret - Nilai rahasia (password, secret key) yang disamarkan saat dicetak atau di-marshal
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Value mengembalikan nilai asli secret
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// Config - Seluruh konfigurasi aplikasi. Dibaca sekali saat start oleh Load lalu
+// diteruskan ke komponen yang membutuhkan; tidak ada kode lain yang membaca env langsung.
+type Config struct {
+	Env    string `json:"env"`
+	Port   string `json:"port"`
+	AppURL string `json:"app_url"` // Public base URL (dipakai untuk link di email)
+
+	// TrustedProxies - IP/CIDR reverse proxy yang boleh mengisi X-Forwarded-For. Kosong (default)
+	// = header diabaikan dan IP klien diambil dari koneksi, sehingga tidak bisa dipalsukan.
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	// ShutdownDelay - Jeda antara /readyz gagal dan server berhenti menerima koneksi, agar load
+	// balancer sempat mengeluarkan instance ini (mis. lebih dari periode readiness probe);
+	// ShutdownTimeout - Batas waktu menunggu request yang sedang berjalan (mis. upload) saat berhenti;
+	// ReadinessTimeout - Batas waktu ping tiap dependency di /readyz
+	ShutdownDelay    time.Duration `json:"shutdown_delay"`
+	ShutdownTimeout  time.Duration `json:"shutdown_timeout"`
+	ReadinessTimeout time.Duration `json:"readiness_timeout"`
+
+	Postgres PostgresConfig `json:"postgres"`
+	Mongo    MongoConfig    `json:"mongo"`
+
+	JWTSecret Secret `json:"jwt_secret"`
+
+	// MetricsToken - Bearer token untuk GET /metrics; kosong = endpoint terbuka (batasi lewat jaringan)
+	MetricsToken Secret `json:"metrics_token"`
+
+	// "block" atau "limited" (default)
+	UnverifiedLoginPolicy string `json:"unverified_login_policy"`
+
+	// RegistrationMode: "open" (default) atau "invite_only";
+	// RegistrationAllowedDomains: domain email yang boleh mendaftar sendiri (kosong = semua)
+	RegistrationMode           string   `json:"registration_mode"`
+	RegistrationAllowedDomains []string `json:"registration_allowed_domains"`
+
+	Log     LogConfig     `json:"log"`
+	Mail    MailConfig    `json:"mail"`
+	OIDC    OIDCConfig    `json:"oidc"`
+	Tracing TracingConfig `json:"tracing"`
+}
+
+// LogConfig - Level "debug", "info" (default), "warn" atau "error"; format "json" (default) atau "text"
+type LogConfig struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+type PostgresConfig struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password Secret `json:"password"`
+	Name     string `json:"name"`
+	SSLMode  string `json:"sslmode"`
+	TimeZone string `json:"timezone"`
+}
+
+// DSN - Connection string PostgreSQL (berisi password, jangan di-log)
+func (c PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s",
+		c.Host, c.User, c.Password.Value(), c.Name, c.Port, c.SSLMode, c.TimeZone)
+}
+
+type MongoConfig struct {
+	URI      Secret `json:"uri"` // Bisa berisi username/password
+	Database string `json:"database"`
+}
+
+type MailConfig struct {
+	Driver       string `json:"driver"` // "log" (default) atau "smtp"
+	From         string `json:"from"`
+	Locale       string `json:"locale"`
+	LogDir       string `json:"log_dir"`
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword Secret `json:"smtp_password"`
+}
+
+// OIDCConfig - Login OpenID Connect aktif jika IssuerURL diisi.
+// Untuk pengujian lokal, arahkan IssuerURL ke mock provider (mis. http://localhost:9000/default).
+type OIDCConfig struct {
+	IssuerURL     string   `json:"issuer_url"`
+	ClientID      string   `json:"client_id"`
+	ClientSecret  Secret   `json:"client_secret"`
+	RedirectURL   string   `json:"redirect_url"`
+	Scopes        []string `json:"scopes"`
+	DefaultRole   string   `json:"default_role"`
+	AutoProvision bool     `json:"auto_provision"`
+	EmailClaim    string   `json:"email_claim"`
+	NameClaim     string   `json:"name_claim"`
+	RoleClaim     string   `json:"role_claim"`
+}
+
+// TracingConfig - Exporter "none" (default), "stdout" untuk development lokal, atau
+// "otlp" untuk collector OpenTelemetry (OTLP/HTTP, mis. http://otel-collector:4318)
+type TracingConfig struct {
+	Exporter     string  `json:"exporter"`
+	OTLPEndpoint string  `json:"otlp_endpoint"`
+	OTLPHeaders  Secret  `json:"otlp_headers"` // "key=value,key2=value2", bisa berisi API key
+	ServiceName  string  `json:"service_name"`
+	SampleRatio  float64 `json:"sample_ratio"`
+}
+
+// Headers mengurai OTLPHeaders menjadi map
+func (c TracingConfig) Headers() map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(c.OTLPHeaders.Value(), ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if ok && strings.TrimSpace(key) != "" {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
+
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// Load membaca konfigurasi dari (prioritas tertinggi dulu): flag global di args,
+// environment variable, file -config / CONFIG_FILE, file .env, lalu nilai default.
+// Mengembalikan sisa args (subcommand beserta argumennya).
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("onlearn", flag.ContinueOnError)
+	configFile := fs.String("config", "", "env-style config file (KEY=VALUE per line)")
+	env := fs.String("env", "", "environment: development or production (APP_ENV)")
+	port := fs.String("port", "", "HTTP port (PORT)")
+	appURL := fs.String("app-url", "", "public base URL used in emails (APP_URL)")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	files := make(map[string]string)
+	if values, err := godotenv.Read(".env"); err == nil {
+		files = values
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("read .env: %w", err)
+	} else {
+		slog.Info(".env file not found, using system environment variables")
+	}
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		values, err := godotenv.Read(*configFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read config file: %w", err)
+		}
+		for k, v := range values {
+			files[k] = v
+		}
+	}
+
+	s := source{files: files}
+	cfg := &Config{
+		Env:    s.str("APP_ENV", EnvDevelopment),
+		Port:   s.str("PORT", "8080"),
+		AppURL: s.str("APP_URL", ""),
+
+		TrustedProxies: s.list("TRUSTED_PROXIES"),
+
+		ShutdownDelay:    s.duration("SHUTDOWN_DELAY", 0),
+		ShutdownTimeout:  s.duration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		ReadinessTimeout: s.duration("READINESS_TIMEOUT", 2*time.Second),
+
+		Postgres: PostgresConfig{
+			Host:     s.str("DB_HOST", "localhost"),
+			Port:     s.str("DB_PORT", "5432"),
+			User:     s.str("DB_USER", ""),
+			Password: Secret(s.str("DB_PASSWORD", "")),
+			Name:     s.str("DB_NAME", ""),
+			SSLMode:  s.str("DB_SSLMODE", "disable"),
+			TimeZone: s.str("DB_TIMEZONE", "Asia/Jakarta"),
+		},
+		Mongo: MongoConfig{
+			URI:      Secret(s.str("MONGO_URI", "")),
+			Database: s.str("MONGO_DB_NAME", ""),
+		},
+
+		JWTSecret:    Secret(s.str("JWT_SECRET", "")),
+		MetricsToken: Secret(s.str("METRICS_TOKEN", "")),
+
+		UnverifiedLoginPolicy:      s.str("UNVERIFIED_LOGIN_POLICY", "limited"),
+		RegistrationMode:           s.str("REGISTRATION_MODE", "open"),
+		RegistrationAllowedDomains: s.list("REGISTRATION_ALLOWED_DOMAINS"),
+
+		Log: LogConfig{
+			Level:  s.str("LOG_LEVEL", "info"),
+			Format: s.str("LOG_FORMAT", "json"),
+		},
+		Mail: MailConfig{
+			Driver:       s.str("MAIL_DRIVER", "log"),
+			From:         s.str("MAIL_FROM", "OnLearn <no-reply@onlearn.com>"),
+			Locale:       s.str("MAIL_LOCALE", ""),
+			LogDir:       s.str("MAIL_LOG_DIR", ""),
+			SMTPHost:     s.str("SMTP_HOST", ""),
+			SMTPPort:     s.int("SMTP_PORT", 0),
+			SMTPUsername: s.str("SMTP_USERNAME", ""),
+			SMTPPassword: Secret(s.str("SMTP_PASSWORD", "")),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:     s.str("OIDC_ISSUER_URL", ""),
+			ClientID:      s.str("OIDC_CLIENT_ID", ""),
+			ClientSecret:  Secret(s.str("OIDC_CLIENT_SECRET", "")),
+			RedirectURL:   s.str("OIDC_REDIRECT_URL", ""),
+			Scopes:        s.list("OIDC_SCOPES"),
+			DefaultRole:   s.str("OIDC_DEFAULT_ROLE", ""),
+			AutoProvision: s.bool("OIDC_AUTO_PROVISION", true),
+			EmailClaim:    s.str("OIDC_EMAIL_CLAIM", ""),
+			NameClaim:     s.str("OIDC_NAME_CLAIM", ""),
+			RoleClaim:     s.str("OIDC_ROLE_CLAIM", ""),
+		},
+		Tracing: TracingConfig{
+			Exporter:     s.str("OTEL_TRACES_EXPORTER", "none"),
+			OTLPEndpoint: s.str("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+			OTLPHeaders:  Secret(s.str("OTEL_EXPORTER_OTLP_HEADERS", "")),
+			ServiceName:  s.str("OTEL_SERVICE_NAME", "onlearn-backend"),
+			SampleRatio:  s.float("OTEL_TRACES_SAMPLER_ARG", 1),
+		},
+	}
+
+	// Flag mengalahkan environment
+	if *env != "" {
+		cfg.Env = *env
+	}
+	if *port != "" {
+		cfg.Port = *port
+	}
+	if *appURL != "" {
+		cfg.AppURL = *appURL
+	}
+
+	if cfg.AppURL == "" {
+		cfg.AppURL = "http://localhost:" + cfg.Port
+	}
+	if cfg.OIDC.RedirectURL == "" {
+		cfg.OIDC.RedirectURL = strings.TrimRight(cfg.AppURL, "/") + "/auth/oidc/callback"
+	}
+
+	problems := append(s.errs, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	if cfg.JWTSecret == "" {
+		slog.Warn("JWT_SECRET is not set, using the insecure development default")
+		cfg.JWTSecret = devJWTSecret
+	}
+	return cfg, fs.Args(), nil
+}
+
+// validate mengumpulkan semua kesalahan konfigurasi sekaligus agar bisa diperbaiki dalam satu kali jalan
+func (c *Config) validate() []string {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch c.Env {
+	case EnvDevelopment, EnvProduction:
+	default:
+		addf("APP_ENV must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env)
+	}
+	if p, err := strconv.Atoi(c.Port); err != nil || p < 1 || p > 65535 {
+		addf("PORT must be a TCP port number, got %q", c.Port)
+	}
+	if u, err := url.Parse(c.AppURL); err != nil || u.Scheme == "" || u.Host == "" {
+		addf("APP_URL must be an absolute URL, got %q", c.AppURL)
+	}
+
+	if c.ShutdownDelay < 0 {
+		addf("SHUTDOWN_DELAY must not be negative, got %s", c.ShutdownDelay)
+	}
+	if c.ShutdownTimeout <= 0 {
+		addf("SHUTDOWN_TIMEOUT must be positive, got %s", c.ShutdownTimeout)
+	}
+	if c.ReadinessTimeout <= 0 {
+		addf("READINESS_TIMEOUT must be positive, got %s", c.ReadinessTimeout)
+	}
+
+	if c.Postgres.User == "" {
+		addf("DB_USER is required")
+	}
+	if c.Postgres.Name == "" {
+		addf("DB_NAME is required")
+	}
+	switch c.Postgres.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		addf("DB_SSLMODE %q is not a valid PostgreSQL sslmode", c.Postgres.SSLMode)
+	}
+	if c.Mongo.URI == "" {
+		addf("MONGO_URI is required")
+	}
+	if c.Mongo.Database == "" {
+		addf("MONGO_DB_NAME is required")
+	}
+
+	switch c.UnverifiedLoginPolicy {
+	case "block", "limited":
+	default:
+		addf("UNVERIFIED_LOGIN_POLICY must be \"block\" or \"limited\", got %q", c.UnverifiedLoginPolicy)
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				addf("TRUSTED_PROXIES must contain IP addresses or CIDR ranges, got %q", proxy)
+			}
+		}
+	}
+
+	switch c.RegistrationMode {
+	case "open", "invite_only":
+	default:
+		addf("REGISTRATION_MODE must be \"open\" or \"invite_only\", got %q", c.RegistrationMode)
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		addf("LOG_LEVEL must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		addf("LOG_FORMAT must be \"json\" or \"text\", got %q", c.Log.Format)
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+	case "smtp":
+		if c.Mail.SMTPHost == "" || c.Mail.SMTPPort == 0 {
+			addf("SMTP_HOST and SMTP_PORT are required when MAIL_DRIVER=smtp")
+		}
+	default:
+		addf("unknown MAIL_DRIVER %q", c.Mail.Driver)
+	}
+
+	if c.OIDC.IssuerURL != "" && c.OIDC.ClientID == "" {
+		addf("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			addf("OTEL_EXPORTER_OTLP_ENDPOINT must be an absolute URL, got %q", c.Tracing.OTLPEndpoint)
+		}
+	default:
+		addf("OTEL_TRACES_EXPORTER must be \"none\", \"stdout\" or \"otlp\", got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		addf("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	if c.IsProduction() {
+		switch {
+		case c.JWTSecret == "" || c.JWTSecret == devJWTSecret:
+			addf("JWT_SECRET must be set to a random value in production")
+		case len(c.JWTSecret) < minProductionSecretLength:
+			addf("JWT_SECRET must be at least %d characters in production", minProductionSecretLength)
+		}
+		if c.Postgres.SSLMode == "disable" {
+			addf("DB_SSLMODE=disable is not allowed in production")
+		}
+		if c.Mail.Driver == "log" {
+			addf("MAIL_DRIVER=log is not allowed in production, emails would not be sent")
+		}
+	}
+	return problems
+}
+
+// source - Nilai dari environment, lalu dari file konfigurasi
+type source struct {
+	files map[string]string
+	errs  []string
+}
+
+func (s *source) str(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	if v := s.files[key]; v != "" {
+		return v
+	}
+	return def
+}
+
+func (s *source) int(key string, def int) int {
+	v := s.str(key, "")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Sprintf("%s must be a number, got %q", key, v))
+	}
+	return n
+}
+
+func (s *source) bool(key string, def bool) bool {
+	v := s.str(key, "")
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Sprintf("%s must be true or false, got %q", key, v))
+	}
+	return b
+}
+
+func (s *source) float(key string, def float64) float64 {
+	v := s.str(key, "")
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Sprintf("%s must be a number, got %q", key, v))
+	}
+	return f
+}
+
+// duration membaca durasi format Go, mis. "30s" atau "1m30s"
+func (s *source) duration(key string, def time.Duration) time.Duration {
+	v := s.str(key, "")
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Sprintf("%s must be a duration like \"30s\", got %q", key, v))
+	}
+	return d
+}
+
+// list membaca daftar yang dipisah koma dan/atau spasi
+func (s *source) list(key string) []string {
+	v := s.str(key, "")
+	if v == "" {
+		return nil
+	}
+	return strings.Fields(strings.ReplaceAll(v, ",", " "))
+}
@@ -2,13 +2,13 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"onlearn-backend/internal/domain"
+	"log/slog"
 	"os"
 	"time"
 
-	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"gorm.io/driver/postgres"
@@ -20,38 +20,31 @@ type Database struct {
 	Mongo *mongo.Database
 }
 
-func ConnectDB() *Database {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Note: .env file not found, using system environment variables")
-	}
-
+// ConnectDB membuka koneksi PostgreSQL dan MongoDB; log GORM diteruskan ke logger
+func ConnectDB(cfg *Config, logger *slog.Logger) *Database {
 	// 1. PostgreSQL Connection
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Jakarta",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"),
-	)
-	pgDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	pgDB, err := gorm.Open(postgres.Open(cfg.Postgres.DSN()), &gorm.Config{Logger: newGORMLogger(logger)})
 	if err != nil {
-		log.Fatal("Failed to connect to PostgreSQL:", err)
+		logger.Error("Failed to connect to PostgreSQL", "error", err)
+		os.Exit(1)
+	}
+	if err := errors.Join(instrumentGORM(pgDB), traceGORM(pgDB)); err != nil {
+		logger.Warn("Failed to register query instrumentation", "error", err)
 	}
 
 	// 2. MongoDB Connection
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	mongoURI := os.Getenv("MONGO_URI")
-	clientOptions := options.Client().ApplyURI(mongoURI)
+	clientOptions := options.Client().ApplyURI(cfg.Mongo.URI.Value()).SetMonitor(mongoMonitor(logger))
 	mongoClient, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		log.Fatal("Failed to connect to MongoDB:", err)
+		logger.Error("Failed to connect to MongoDB", "error", err)
+		os.Exit(1)
 	}
 
-	mongoDB := mongoClient.Database(os.Getenv("MONGO_DB_NAME"))
+	mongoDB := mongoClient.Database(cfg.Mongo.Database)
 
-	log.Println("Connected to PostgreSQL and MongoDB successfully!")
+	logger.Info("Connected to PostgreSQL and MongoDB")
 
 	return &Database{
 		PG:    pgDB,
@@ -59,41 +52,53 @@ func ConnectDB() *Database {
 	}
 }
 
-func AutoMigrate(db *gorm.DB) error {
-	// Pre-migration: Convert existing letter grades to numeric values
-	// Check if lab_grades table exists and has grade column as varchar
-	var columnType string
-	db.Raw("SELECT data_type FROM information_schema.columns WHERE table_name = 'lab_grades' AND column_name = 'grade'").Scan(&columnType)
-	
-	if columnType == "character varying" || columnType == "varchar" {
-		log.Println("Converting letter grades to numeric values...")
-		// Convert A=90, B=80, C=70, D=60, E=50, empty/null=null
-		db.Exec(`
-			UPDATE lab_grades SET grade = CASE 
-				WHEN grade = 'A' THEN '90'
-				WHEN grade = 'B' THEN '80'
-				WHEN grade = 'C' THEN '70'
-				WHEN grade = 'D' THEN '60'
-				WHEN grade = 'E' THEN '50'
-				WHEN grade = '' OR grade IS NULL THEN NULL
-				ELSE grade
-			END
-		`)
+// mongoMonitor - Metrics dan tracing untuk setiap command MongoDB; command yang gagal
+// dicatat dengan context pemanggil (request_id, user_id) seperti log GORM
+func mongoMonitor(logger *slog.Logger) *event.CommandMonitor {
+	logger = logger.With("component", "mongo")
+	spans := &mongoSpans{}
+	return &event.CommandMonitor{
+		Started: spans.start,
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			observeMongoCommand(e.CommandFinishedEvent, false)
+			spans.finish(e.CommandFinishedEvent, "")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			observeMongoCommand(e.CommandFinishedEvent, true)
+			logger.WarnContext(ctx, "command failed",
+				"command", e.CommandName,
+				"duration_ms", float64(e.Duration.Microseconds())/1000,
+				"error", e.Failure,
+			)
+			spans.finish(e.CommandFinishedEvent, e.Failure)
+		},
 	}
-	
-	err := db.AutoMigrate(
-		&domain.User{},
-		&domain.Course{},
-		&domain.Lab{},
-		&domain.Enrollment{},
-		&domain.LabGrade{},
-		&domain.Certificate{},
-		&domain.ModuleProgress{},
-		&domain.Assignment{},
-	)
+}
+
+// PingPostgres memastikan koneksi PostgreSQL masih bisa dipakai (untuk readiness check)
+func (db *Database) PingPostgres(ctx context.Context) error {
+	sqlDB, err := db.PG.DB()
 	if err != nil {
 		return err
 	}
-	log.Println("Database migration completed!")
-	return nil
+	return sqlDB.PingContext(ctx)
+}
+
+// PingMongo memastikan primary MongoDB bisa dijangkau (untuk readiness check)
+func (db *Database) PingMongo(ctx context.Context) error {
+	return db.Mongo.Client().Ping(ctx, nil)
+}
+
+// Close menutup koneksi PostgreSQL dan MongoDB saat aplikasi berhenti
+func (db *Database) Close(ctx context.Context) error {
+	var errs []error
+	if sqlDB, err := db.PG.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close postgres: %w", err))
+		}
+	}
+	if err := db.Mongo.Client().Disconnect(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("disconnect mongo: %w", err))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold - Query lebih lama dari ini dicatat sebagai warning
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger meneruskan log GORM ke slog sehingga log dari semua repository PostgreSQL
+// ikut membawa request_id dan user_id dari context
+type gormLogger struct {
+	logger *slog.Logger
+	level  gormlogger.LogLevel
+}
+
+func newGORMLogger(logger *slog.Logger) gormlogger.Interface {
+	level := gormlogger.Warn
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		level = gormlogger.Info
+	}
+	return &gormLogger{logger: logger.With("component", "gorm"), level: level}
+}
+
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &gormLogger{logger: l.logger, level: level}
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace dipanggil GORM setelah setiap query. Record not found bukan error (repository
+// memakainya untuk "tidak ada"), jadi hanya dicatat di level debug.
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		sql, rows := fc()
+		l.logger.ErrorContext(ctx, "query failed", queryAttrs(sql, rows, elapsed, err)...)
+	case elapsed > slowQueryThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "slow query", queryAttrs(sql, rows, elapsed, nil)...)
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		l.logger.DebugContext(ctx, "query", queryAttrs(sql, rows, elapsed, err)...)
+	}
+}
+
+// ParamsFilter membuang nilai parameter dari SQL yang dicatat (password hash, token, dll.)
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+func queryAttrs(sql string, rows int64, elapsed time.Duration, err error) []any {
+	attrs := []any{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	return attrs
+}
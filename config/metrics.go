@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+	"gorm.io/gorm"
+)
+
+// dbBuckets - Query database umumnya jauh lebih cepat dari request HTTP
+var dbBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+
+var (
+	postgresQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "onlearn_postgres_query_duration_seconds",
+		Help:    "GORM query latency by operation and table.",
+		Buckets: dbBuckets,
+	}, []string{"operation", "table"})
+	postgresQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onlearn_postgres_query_errors_total",
+		Help: "GORM queries that returned an error (record not found is not an error).",
+	}, []string{"operation", "table"})
+	mongoCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "onlearn_mongo_command_duration_seconds",
+		Help:    "MongoDB command latency by command name.",
+		Buckets: dbBuckets,
+	}, []string{"command"})
+	mongoCommandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onlearn_mongo_command_errors_total",
+		Help: "MongoDB commands that failed.",
+	}, []string{"command"})
+)
+
+const queryStartKey = "metrics:start"
+
+// instrumentGORM memasang callback di awal dan akhir setiap jenis operasi GORM
+func instrumentGORM(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("metrics:before_create", startQuery),
+		cb.Create().After("*").Register("metrics:after_create", finishQuery("create")),
+		cb.Query().Before("*").Register("metrics:before_query", startQuery),
+		cb.Query().After("*").Register("metrics:after_query", finishQuery("query")),
+		cb.Update().Before("*").Register("metrics:before_update", startQuery),
+		cb.Update().After("*").Register("metrics:after_update", finishQuery("update")),
+		cb.Delete().Before("*").Register("metrics:before_delete", startQuery),
+		cb.Delete().After("*").Register("metrics:after_delete", finishQuery("delete")),
+		cb.Row().Before("*").Register("metrics:before_row", startQuery),
+		cb.Row().After("*").Register("metrics:after_row", finishQuery("row")),
+		cb.Raw().Before("*").Register("metrics:before_raw", startQuery),
+		cb.Raw().After("*").Register("metrics:after_raw", finishQuery("raw")),
+	)
+}
+
+func startQuery(tx *gorm.DB) {
+	tx.InstanceSet(queryStartKey, time.Now())
+}
+
+func finishQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		observeGORM(tx, operation)
+	}
+}
+
+func observeGORM(tx *gorm.DB, operation string) {
+	value, ok := tx.InstanceGet(queryStartKey)
+	if !ok {
+		return
+	}
+	start, ok := value.(time.Time)
+	if !ok {
+		return
+	}
+
+	table := tx.Statement.Table
+	if table == "" {
+		table = "unknown"
+	}
+	postgresQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		postgresQueryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
+
+// observeMongoCommand mencatat latency command MongoDB (termasuk operasi GridFS)
+func observeMongoCommand(e event.CommandFinishedEvent, failed bool) {
+	mongoCommandDuration.WithLabelValues(e.CommandName).Observe(e.Duration.Seconds())
+	if failed {
+		mongoCommandErrors.WithLabelValues(e.CommandName).Inc()
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"log/slog"
+	"onlearn-backend/pkg/migrate"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Script SQL berversi: migrations/NNNN_nama.up.sql dan NNNN_nama.down.sql.
+// Migrasi baru ditambahkan sebagai file baru, jangan mengubah file yang sudah dirilis.
+//
+//go:embed migrations/*.sql
+var sqlMigrations embed.FS
+
+// NewMigrator menggabungkan migrasi SQL (PostgreSQL) dan migrasi MongoDB dalam satu
+// riwayat versi di tabel schema_migrations
+func NewMigrator(db *Database) (*migrate.Migrator, error) {
+	migrations, err := migrate.LoadSQL(sqlMigrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations = append(migrations, mongoMigrations(db.Mongo)...)
+
+	sqlDB, err := db.PG.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, migrations)
+}
+
+// Migrate menerapkan semua migrasi yang belum diterapkan. Aman dijalankan oleh
+// beberapa replika sekaligus karena dijaga advisory lock.
+func Migrate(db *Database) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Database migration completed")
+	return nil
+}
+
+// mongoMigrations - Migrasi MongoDB; harus idempotent karena tidak ikut transaksi PostgreSQL
+func mongoMigrations(mongoDB *mongo.Database) []migrate.Migration {
+	modules := mongoDB.Collection("modules")
+	files := mongoDB.Collection("uploads.files")
+
+	return []migrate.Migration{
+		{
+			Version: 4,
+			Name:    "mongo_modules_organization",
+			Up: func(ctx context.Context, tx *sql.Tx) error {
+				return backfillModuleOrganizations(ctx, tx, modules)
+			},
+			Down: func(ctx context.Context, tx *sql.Tx) error {
+				_, err := modules.UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"organization_id": ""}})
+				return err
+			},
+		},
+		{
+			Version: 5,
+			Name:    "mongo_indexes",
+			Up: func(ctx context.Context, tx *sql.Tx) error {
+				if _, err := modules.Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "course_id", Value: 1}, {Key: "order", Value: 1}},
+					Options: options.Index().SetName("course_id_order"),
+				}); err != nil {
+					return err
+				}
+				_, err := files.Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "metadata.uploaded_by", Value: 1}},
+					Options: options.Index().SetName("metadata_uploaded_by"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, tx *sql.Tx) error {
+				if err := dropMongoIndex(ctx, modules, "course_id_order"); err != nil {
+					return err
+				}
+				return dropMongoIndex(ctx, files, "metadata_uploaded_by")
+			},
+		},
+		{
+			// Satu collection hanya boleh punya satu text index. Bahasa "none": tanpa
+			// stemming, karena MongoDB tidak mendukung bahasa Indonesia.
+			Version: 7,
+			Name:    "mongo_modules_text_index",
+			Up: func(ctx context.Context, tx *sql.Tx) error {
+				_, err := modules.Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+					Options: options.Index().
+						SetName("title_description_text").
+						SetWeights(bson.D{{Key: "title", Value: 5}, {Key: "description", Value: 1}}).
+						SetDefaultLanguage("none"),
+				})
+				return err
+			},
+			Down: func(ctx context.Context, tx *sql.Tx) error {
+				return dropMongoIndex(ctx, modules, "title_description_text")
+			},
+		},
+		{
+			// Pencarian memfilter module dengan course_published, bukan daftar $in course
+			Version: 10,
+			Name:    "mongo_modules_course_published",
+			Up: func(ctx context.Context, tx *sql.Tx) error {
+				return backfillModuleCoursePublished(ctx, tx, modules)
+			},
+			Down: func(ctx context.Context, tx *sql.Tx) error {
+				_, err := modules.UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"course_published": ""}})
+				return err
+			},
+		},
+	}
+}
+
+// backfillModuleOrganizations - Module lama mengikuti organisasi course-nya
+func backfillModuleOrganizations(ctx context.Context, tx *sql.Tx, modules *mongo.Collection) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id, organization_id FROM courses WHERE organization_id IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var courseID, orgID uint
+		if err := rows.Scan(&courseID, &orgID); err != nil {
+			return err
+		}
+		_, err := modules.UpdateMany(ctx,
+			bson.M{"course_id": courseID, "organization_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"organization_id": orgID}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// backfillModuleCoursePublished - Module lama mengikuti status publish course-nya
+func backfillModuleCoursePublished(ctx context.Context, tx *sql.Tx, modules *mongo.Collection) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM courses WHERE is_published")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	published := []uint{}
+	for rows.Next() {
+		var courseID uint
+		if err := rows.Scan(&courseID); err != nil {
+			return err
+		}
+		published = append(published, courseID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := modules.UpdateMany(ctx,
+		bson.M{"course_id": bson.M{"$nin": published}},
+		bson.M{"$set": bson.M{"course_published": false}},
+	); err != nil {
+		return err
+	}
+	_, err = modules.UpdateMany(ctx,
+		bson.M{"course_id": bson.M{"$in": published}},
+		bson.M{"$set": bson.M{"course_published": true}},
+	)
+	return err
+}
+
+func dropMongoIndex(ctx context.Context, collection *mongo.Collection, name string) error {
+	_, err := collection.Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
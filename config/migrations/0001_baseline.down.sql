@@ -0,0 +1,24 @@
+DROP TABLE IF EXISTS
+    invitations,
+    account_deletions,
+    api_keys,
+    audit_logs,
+    o_id_c_login_states,
+    user_identities,
+    mfa_policies,
+    mfa_challenges,
+    mfa_recovery_codes,
+    user_mfas,
+    lockout_events,
+    login_throttles,
+    password_resets,
+    email_verifications,
+    sessions,
+    assignments,
+    module_progresses,
+    certificates,
+    lab_grades,
+    enrollments,
+    labs,
+    courses,
+    users;
@@ -0,0 +1,323 @@
+-- Skema awal, sama dengan hasil GORM AutoMigrate sebelum migrasi berversi dipakai.
+-- Memakai IF NOT EXISTS agar database lama yang dibuat AutoMigrate bisa langsung
+-- dicatat sebagai baseline. CREATE TABLE dilewati untuk tabel yang sudah ada, jadi
+-- kolom yang tidak ada di model lama ditambahkan lewat ADD COLUMN IF NOT EXISTS.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL,
+    role varchar(20) DEFAULT 'student',
+    is_verified boolean DEFAULT false,
+    profile_picture text,
+    locale varchar(5) DEFAULT 'id',
+    created_at timestamptz,
+    updated_at timestamptz
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale varchar(5) DEFAULT 'id';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS courses (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    description text,
+    thumbnail text,
+    instructor_id bigint NOT NULL,
+    is_published boolean DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_courses_instructor FOREIGN KEY (instructor_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS labs (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    description text,
+    start_time timestamptz,
+    end_time timestamptz,
+    status varchar(20) DEFAULT 'scheduled',
+    created_at timestamptz,
+    updated_at timestamptz,
+    instructor_id bigint
+);
+ALTER TABLE labs ADD COLUMN IF NOT EXISTS instructor_id bigint;
+CREATE INDEX IF NOT EXISTS idx_labs_instructor_id ON labs (instructor_id);
+
+CREATE TABLE IF NOT EXISTS enrollments (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    course_id bigint NOT NULL,
+    progress decimal DEFAULT 0,
+    is_finished boolean DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_enrollments_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_enrollments_course FOREIGN KEY (course_id) REFERENCES courses (id)
+);
+CREATE INDEX IF NOT EXISTS idx_enrollments_course_id ON enrollments (course_id);
+CREATE INDEX IF NOT EXISTS idx_enrollments_user_id ON enrollments (user_id);
+
+CREATE TABLE IF NOT EXISTS lab_grades (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    lab_id bigint NOT NULL,
+    grade decimal(5,2),
+    feedback text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_lab_grades_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_lab_grades_lab FOREIGN KEY (lab_id) REFERENCES labs (id)
+);
+CREATE INDEX IF NOT EXISTS idx_lab_grades_lab_id ON lab_grades (lab_id);
+CREATE INDEX IF NOT EXISTS idx_lab_grades_user_id ON lab_grades (user_id);
+
+CREATE TABLE IF NOT EXISTS certificates (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    course_id bigint,
+    lab_id bigint,
+    title text NOT NULL,
+    url text NOT NULL,
+    status varchar(20) DEFAULT 'pending',
+    approved_by bigint,
+    approved_at timestamptz,
+    issue_date timestamptz,
+    CONSTRAINT fk_certificates_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_certificates_course FOREIGN KEY (course_id) REFERENCES courses (id),
+    CONSTRAINT fk_certificates_lab FOREIGN KEY (lab_id) REFERENCES labs (id),
+    CONSTRAINT fk_certificates_approver FOREIGN KEY (approved_by) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_certificates_approved_by ON certificates (approved_by);
+CREATE INDEX IF NOT EXISTS idx_certificates_lab_id ON certificates (lab_id);
+CREATE INDEX IF NOT EXISTS idx_certificates_course_id ON certificates (course_id);
+CREATE INDEX IF NOT EXISTS idx_certificates_user_id ON certificates (user_id);
+
+CREATE TABLE IF NOT EXISTS module_progresses (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    module_id text NOT NULL,
+    course_id bigint NOT NULL,
+    is_complete boolean DEFAULT false,
+    last_slide_number bigint DEFAULT null,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_module_progresses_course_id ON module_progresses (course_id);
+CREATE INDEX IF NOT EXISTS idx_module_progresses_module_id ON module_progresses (module_id);
+CREATE INDEX IF NOT EXISTS idx_module_progresses_user_id ON module_progresses (user_id);
+
+CREATE TABLE IF NOT EXISTS assignments (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    module_id text NOT NULL,
+    course_id bigint NOT NULL,
+    file_url text,
+    submitted_at timestamptz,
+    grade decimal,
+    feedback text,
+    graded_at timestamptz,
+    graded_by_id bigint,
+    CONSTRAINT fk_assignments_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_assignments_graded_by FOREIGN KEY (graded_by_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_assignments_course_id ON assignments (course_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_module_id ON assignments (module_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_user_id ON assignments (user_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    refresh_token_hash varchar(64) NOT NULL,
+    previous_token_hash varchar(64),
+    user_agent text,
+    ip_address varchar(45),
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    last_used_at timestamptz,
+    impersonator_id bigint,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_impersonator_id ON sessions (impersonator_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token_hash ON sessions (previous_token_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    code_hash varchar(64) NOT NULL,
+    attempts bigint DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications (user_id);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_resets_token_hash ON password_resets (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+    id bigserial PRIMARY KEY,
+    scope varchar(20) NOT NULL,
+    identifier text NOT NULL,
+    failed_count bigint DEFAULT 0,
+    last_failed_at timestamptz,
+    locked_until timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_throttle_identifier ON login_throttles (scope, identifier);
+
+CREATE TABLE IF NOT EXISTS lockout_events (
+    id bigserial PRIMARY KEY,
+    action varchar(20) NOT NULL,
+    scope varchar(20) NOT NULL,
+    identifier text NOT NULL,
+    user_id bigint,
+    ip_address text,
+    user_agent text,
+    failed_count bigint,
+    locked_until timestamptz,
+    actor_id bigint,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_lockout_events_created_at ON lockout_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_lockout_events_user_id ON lockout_events (user_id);
+CREATE INDEX IF NOT EXISTS idx_lockout_events_identifier ON lockout_events (identifier);
+CREATE INDEX IF NOT EXISTS idx_lockout_events_action ON lockout_events (action);
+
+CREATE TABLE IF NOT EXISTS user_mfas (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    secret varchar(64) NOT NULL,
+    confirmed_at timestamptz,
+    last_used_step bigint,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_mfas_user_id ON user_mfas (user_id);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    code_hash varchar(64) NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    purpose varchar(20) NOT NULL,
+    attempts bigint DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mfa_challenges_token_hash ON mfa_challenges (token_hash);
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user_id ON mfa_challenges (user_id);
+
+CREATE TABLE IF NOT EXISTS mfa_policies (
+    role varchar(20) PRIMARY KEY,
+    required boolean DEFAULT false,
+    updated_by bigint,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    provider text NOT NULL,
+    subject text NOT NULL,
+    email text,
+    last_login_at timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_identity_provider_subject ON user_identities (provider, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS o_id_c_login_states (
+    id bigserial PRIMARY KEY,
+    state_hash varchar(64) NOT NULL,
+    nonce text NOT NULL,
+    code_verifier text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_o_id_c_login_states_state_hash ON o_id_c_login_states (state_hash);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id bigserial PRIMARY KEY,
+    actor_id bigint,
+    impersonator_id bigint,
+    action varchar(64) NOT NULL,
+    target_type varchar(32) NOT NULL,
+    target_id varchar(64) NOT NULL,
+    changes jsonb,
+    ip_address text,
+    user_agent text,
+    created_at timestamptz,
+    CONSTRAINT fk_audit_logs_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_target ON audit_logs (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_impersonator_id ON audit_logs (impersonator_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    name text NOT NULL,
+    prefix varchar(16) NOT NULL,
+    key_hash varchar(64) NOT NULL,
+    scopes jsonb,
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    last_used_ip varchar(45),
+    revoked_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    reason text,
+    scheduled_for timestamptz NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_account_deletions_scheduled_for ON account_deletions (scheduled_for);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_deletions_user_id ON account_deletions (user_id);
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id bigserial PRIMARY KEY,
+    email text NOT NULL,
+    role varchar(20) NOT NULL,
+    course_id bigint,
+    token_hash varchar(64) NOT NULL,
+    invited_by bigint NOT NULL,
+    expires_at timestamptz NOT NULL,
+    accepted_at timestamptz,
+    accepted_user_id bigint,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_invitations_course FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE CASCADE,
+    CONSTRAINT fk_invitations_inviter FOREIGN KEY (invited_by) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations (email);
+CREATE INDEX IF NOT EXISTS idx_invitations_invited_by ON invitations (invited_by);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_token_hash ON invitations (token_hash);
+CREATE INDEX IF NOT EXISTS idx_invitations_course_id ON invitations (course_id);
@@ -0,0 +1,2 @@
+-- Sengaja kosong: nilai angka tetap dipakai, nilai huruf lama tidak dikembalikan.
+SELECT 1;
@@ -0,0 +1,25 @@
+-- Nilai lab dulu disimpan sebagai huruf (varchar). Ubah ke angka:
+-- A=90, B=80, C=70, D=60, E=50, kosong = NULL. Tidak berbuat apa pun jika kolom
+-- sudah numeric (database baru dari baseline).
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND table_name = 'lab_grades'
+          AND column_name = 'grade'
+          AND data_type IN ('character varying', 'text')
+    ) THEN
+        ALTER TABLE lab_grades ALTER COLUMN grade TYPE decimal(5,2) USING (
+            CASE
+                WHEN grade = 'A' THEN 90
+                WHEN grade = 'B' THEN 80
+                WHEN grade = 'C' THEN 70
+                WHEN grade = 'D' THEN 60
+                WHEN grade = 'E' THEN 50
+                WHEN grade IS NULL OR btrim(grade) = '' THEN NULL
+                ELSE btrim(grade)::decimal(5,2)
+            END
+        );
+    END IF;
+END $$;
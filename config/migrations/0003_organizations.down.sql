@@ -0,0 +1,5 @@
+ALTER TABLE invitations DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE labs DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE courses DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE users DROP COLUMN IF EXISTS organization_id;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    slug varchar(64) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_slug ON organizations (slug);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS organization_id bigint;
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS organization_id bigint;
+ALTER TABLE labs ADD COLUMN IF NOT EXISTS organization_id bigint;
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS organization_id bigint;
+CREATE INDEX IF NOT EXISTS idx_users_organization_id ON users (organization_id);
+CREATE INDEX IF NOT EXISTS idx_courses_organization_id ON courses (organization_id);
+CREATE INDEX IF NOT EXISTS idx_labs_organization_id ON labs (organization_id);
+CREATE INDEX IF NOT EXISTS idx_invitations_organization_id ON invitations (organization_id);
+
+-- Data sebelum multi-tenant masuk ke organisasi default, atau ke organisasi pemiliknya.
+-- Super admin tidak punya organisasi.
+INSERT INTO organizations (name, slug, created_at, updated_at)
+VALUES ('Default', 'default', now(), now())
+ON CONFLICT (slug) DO NOTHING;
+
+UPDATE users SET organization_id = (SELECT id FROM organizations WHERE slug = 'default')
+WHERE organization_id IS NULL AND role <> 'super_admin';
+
+UPDATE courses SET organization_id = COALESCE(
+    (SELECT organization_id FROM users WHERE users.id = courses.instructor_id),
+    (SELECT id FROM organizations WHERE slug = 'default'))
+WHERE organization_id IS NULL;
+
+UPDATE labs SET organization_id = COALESCE(
+    (SELECT organization_id FROM users WHERE users.id = labs.instructor_id),
+    (SELECT id FROM organizations WHERE slug = 'default'))
+WHERE organization_id IS NULL;
+
+UPDATE invitations SET organization_id = COALESCE(
+    (SELECT organization_id FROM users WHERE users.id = invitations.invited_by),
+    (SELECT id FROM organizations WHERE slug = 'default'))
+WHERE organization_id IS NULL;
@@ -0,0 +1,5 @@
+-- Extension pg_trgm sengaja tidak dihapus: bisa dipakai objek lain di database.
+DROP INDEX IF EXISTS idx_users_name_trgm;
+DROP INDEX IF EXISTS idx_courses_title_trgm;
+DROP INDEX IF EXISTS idx_courses_search_vector;
+ALTER TABLE courses DROP COLUMN IF EXISTS search_vector;
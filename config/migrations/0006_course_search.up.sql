@@ -0,0 +1,15 @@
+-- Pencarian katalog: full-text search untuk judul dan deskripsi course (judul berbobot
+-- lebih tinggi) dan indeks trigram untuk toleransi salah ketik pada judul course dan
+-- nama user (instructor di pencarian course, student di pencarian student).
+-- Konfigurasi 'simple' dipakai karena konten campuran bahasa Indonesia dan Inggris.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_courses_search_vector ON courses USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_courses_title_trgm ON courses USING gin (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING gin (name gin_trgm_ops);
@@ -0,0 +1,2 @@
+-- Sengaja kosong: tidak tercatat lab mana yang sebelumnya tanpa pemilik.
+SELECT 1;
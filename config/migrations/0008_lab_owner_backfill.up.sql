@@ -0,0 +1,12 @@
+-- Lab dari sebelum kolom instructor_id ada tidak punya pemilik. Lab tanpa pemilik hanya
+-- bisa dikelola admin, jadi pemiliknya diisi admin organisasi lab yang paling lama
+-- terdaftar, atau admin mana pun jika organisasinya tidak punya admin. Admin bisa
+-- memindahkan lab ke instructor yang tepat lewat update lab.
+UPDATE labs SET instructor_id = COALESCE(
+    (SELECT id FROM users
+     WHERE users.role = 'admin' AND users.organization_id = labs.organization_id
+     ORDER BY id LIMIT 1),
+    (SELECT id FROM users
+     WHERE users.role IN ('admin', 'super_admin')
+     ORDER BY id LIMIT 1))
+WHERE instructor_id IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE invitations DROP COLUMN IF EXISTS lab_ids;
+ALTER TABLE invitations DROP COLUMN IF EXISTS course_ids;
+ALTER TABLE invitations DROP COLUMN IF EXISTS name;
@@ -0,0 +1,5 @@
+-- Undangan dari import massal membawa nama dari file serta daftar course dan lab
+-- tempat student didaftarkan saat undangan diterima.
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS name text;
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS course_ids jsonb;
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS lab_ids jsonb;
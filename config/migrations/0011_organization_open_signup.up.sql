@@ -0,0 +1,4 @@
+-- Pendaftaran publik ke organisasi hanya jika organisasi membukanya; organisasi lain
+-- hanya lewat undangan. Organisasi default tetap terbuka seperti sebelumnya.
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS open_signup boolean NOT NULL DEFAULT false;
+UPDATE organizations SET open_signup = true WHERE slug = 'default';
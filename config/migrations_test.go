@@ -0,0 +1,143 @@
+package config
+
+import (
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// preVersionedSchema - Kolom hasil AutoMigrate sebelum migrasi berversi dipakai
+// (model User, Course, Lab, Enrollment, LabGrade, Certificate, ModuleProgress, Assignment)
+var preVersionedSchema = map[string][]string{
+	"users":             {"id", "name", "email", "password", "role", "is_verified", "profile_picture", "created_at", "updated_at"},
+	"courses":           {"id", "title", "description", "thumbnail", "instructor_id", "is_published", "created_at", "updated_at"},
+	"labs":              {"id", "title", "description", "start_time", "end_time", "status", "created_at", "updated_at"},
+	"enrollments":       {"id", "user_id", "course_id", "progress", "is_finished", "created_at", "updated_at"},
+	"lab_grades":        {"id", "user_id", "lab_id", "grade", "feedback", "created_at", "updated_at"},
+	"certificates":      {"id", "user_id", "course_id", "lab_id", "title", "url", "status", "approved_by", "approved_at", "issue_date"},
+	"module_progresses": {"id", "user_id", "module_id", "course_id", "is_complete", "last_slide_number", "created_at", "updated_at"},
+	"assignments":       {"id", "user_id", "module_id", "course_id", "file_url", "submitted_at", "grade", "feedback", "graded_at", "graded_by_id"},
+}
+
+var (
+	createTableStmt = regexp.MustCompile(`(?s)^CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\);`)
+	addColumnStmt   = regexp.MustCompile(`^ALTER TABLE (\w+) ADD COLUMN IF NOT EXISTS (\w+)`)
+	createIndexStmt = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX IF NOT EXISTS \w+ ON (\w+) (?:USING \w+ )?\((\w+)`)
+	updateStmt      = regexp.MustCompile(`^UPDATE (\w+) SET (\w+) =`)
+	qualifiedColumn = regexp.MustCompile(`\b(\w+)\.(\w+)\b`)
+)
+
+// schema - Simulasi sederhana tabel dan kolom Postgres untuk statement DDL di migrasi
+type schema map[string]map[string]bool
+
+func newSchema(tables map[string][]string) schema {
+	s := make(schema)
+	for table, columns := range tables {
+		s[table] = make(map[string]bool)
+		for _, column := range columns {
+			s[table][column] = true
+		}
+	}
+	return s
+}
+
+// apply menjalankan statement CREATE TABLE / ADD COLUMN dari script up dan mengembalikan
+// kolom yang dirujuk index, UPDATE atau table.column padahal belum ada (di Postgres
+// migrasi tersebut gagal)
+func (s schema) apply(t *testing.T, name, script string) []string {
+	t.Helper()
+	var missing []string
+	lines := strings.Split(script, "\n")
+	for i := 0; i < len(lines); i++ {
+		rest := strings.Join(lines[i:], "\n")
+		if m := createTableStmt.FindStringSubmatch(rest); m != nil {
+			table := m[1]
+			body := strings.Split(m[2], "\n")
+			if s[table] == nil {
+				s[table] = make(map[string]bool)
+				for _, line := range body {
+					field := strings.Fields(line)
+					if len(field) == 0 || field[0] == "CONSTRAINT" {
+						continue
+					}
+					s[table][field[0]] = true
+				}
+			}
+			i += len(body) // Baris terakhir ");" dilewati i++
+			continue
+		}
+		line := lines[i]
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		if m := addColumnStmt.FindStringSubmatch(line); m != nil {
+			if s[m[1]] == nil {
+				missing = append(missing, name+": table "+m[1])
+				continue
+			}
+			s[m[1]][m[2]] = true
+			continue
+		}
+		if m := createIndexStmt.FindStringSubmatch(line); m != nil {
+			if !s[m[1]][m[2]] {
+				missing = append(missing, name+": "+m[1]+"."+m[2])
+			}
+			continue
+		}
+		if m := updateStmt.FindStringSubmatch(line); m != nil && !s[m[1]][m[2]] {
+			missing = append(missing, name+": "+m[1]+"."+m[2])
+		}
+		for _, m := range qualifiedColumn.FindAllStringSubmatch(line, -1) {
+			if s[m[1]] != nil && !s[m[1]][m[2]] {
+				missing = append(missing, name+": "+m[1]+"."+m[2])
+			}
+		}
+	}
+	return missing
+}
+
+func (s schema) columns(table string) []string {
+	var columns []string
+	for column := range s[table] {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// TestSQLMigrationsUpgradePreVersionedSchema memastikan database lama hasil AutoMigrate
+// berakhir dengan kolom yang sama seperti database baru setelah semua migrasi SQL
+func TestSQLMigrationsUpgradePreVersionedSchema(t *testing.T) {
+	entries, err := fs.Glob(sqlMigrations, "migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(entries)
+	if len(entries) == 0 {
+		t.Fatal("no SQL migrations found")
+	}
+
+	fresh := newSchema(nil)
+	upgraded := newSchema(preVersionedSchema)
+	for _, name := range entries {
+		script, err := fs.ReadFile(sqlMigrations, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if missing := fresh.apply(t, name, string(script)); len(missing) > 0 {
+			t.Errorf("fresh database: missing columns %v", missing)
+		}
+		if missing := upgraded.apply(t, name, string(script)); len(missing) > 0 {
+			t.Errorf("upgraded database: missing columns %v", missing)
+		}
+	}
+
+	for table := range fresh {
+		want := strings.Join(fresh.columns(table), ",")
+		if got := strings.Join(upgraded.columns(table), ","); got != want {
+			t.Errorf("table %s after upgrade has columns %s, want %s", table, got, want)
+		}
+	}
+}
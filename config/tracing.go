@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var tracer = otel.Tracer("onlearn-backend/config")
+
+// traceGORM memasang plugin otelgorm: satu span client per query GORM. Nilai parameter
+// diganti "?" agar data user tidak bocor ke trace; metric query sudah dari Prometheus.
+func traceGORM(db *gorm.DB) error {
+	return db.Use(otelgorm.NewPlugin(otelgorm.WithoutQueryVariables(), otelgorm.WithoutMetrics()))
+}
+
+// mongoSpans - Span command MongoDB yang sedang berjalan, dipasangkan lewat RequestID.
+// Driver v1 tidak punya instrumentasi resmi yang cocok, jadi span dibuat dari CommandMonitor.
+type mongoSpans struct {
+	active sync.Map // int64 -> trace.Span
+}
+
+// start hanya membuat span di dalam trace yang sudah ada agar command latar (heartbeat,
+// migrasi) tidak muncul sebagai root trace
+func (m *mongoSpans) start(ctx context.Context, e *event.CommandStartedEvent) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.name", e.DatabaseName),
+		attribute.String("db.operation", e.CommandName),
+	}
+	name := "mongo " + e.CommandName
+	// Elemen pertama command berisi nama collection, mis. {"find": "modules", ...}
+	if first, err := e.Command.IndexErr(0); err == nil {
+		if collection, ok := first.Value().StringValueOK(); ok {
+			attrs = append(attrs, attribute.String("db.mongodb.collection", collection))
+			name += " " + collection
+		}
+	}
+	_, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	m.active.Store(e.RequestID, span)
+}
+
+func (m *mongoSpans) finish(e event.CommandFinishedEvent, failure string) {
+	value, ok := m.active.LoadAndDelete(e.RequestID)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	if failure != "" {
+		span.RecordError(errors.New(failure))
+		span.SetStatus(codes.Error, failure)
+	}
+	span.End()
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"onlearn-backend/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// errorBody - Envelope JSON untuk semua error API. "error" tetap berisi pesan
+// (klien lama membaca field ini), "code" stabil untuk dibandingkan oleh program.
+type errorBody struct {
+	Error      string            `json:"error"`
+	Code       string            `json:"code"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	RetryAfter int               `json:"retry_after,omitempty"`
+}
+
+// errorKinds - Kategori error domain -> status HTTP dan kode default
+var errorKinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{domain.ErrValidation, http.StatusBadRequest, "validation_failed"},
+	{domain.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{domain.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{domain.ErrNotFound, http.StatusNotFound, "not_found"},
+	{domain.ErrConflict, http.StatusConflict, "conflict"},
+	{domain.ErrTooManyRequests, http.StatusTooManyRequests, "too_many_requests"},
+	{domain.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+}
+
+// respondError adalah satu-satunya cara handler API dan middleware mengirim error.
+// Error yang tidak dikenal menjadi 500 tanpa detail; detailnya dicatat di access log.
+func respondError(c *gin.Context, err error) {
+	status, body := errorResponse(err)
+	body.RequestID = c.GetString("request_id")
+
+	if body.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(body.RetryAfter))
+	}
+	if status >= http.StatusInternalServerError {
+		c.Error(err)
+	}
+	c.AbortWithStatusJSON(status, body)
+}
+
+// errorStatus mengembalikan status HTTP untuk err, sama dengan yang dipakai respondError
+func errorStatus(err error) int {
+	status, _ := errorResponse(err)
+	return status
+}
+
+// errorCode mengembalikan kode mesin untuk err, mis. "email_already_exists"
+func errorCode(err error) string {
+	_, body := errorResponse(err)
+	return body.Code
+}
+
+// errorMessage mengembalikan pesan yang aman ditampilkan ke user; dipakai halaman web
+func errorMessage(err error) string {
+	_, body := errorResponse(err)
+	return body.Error
+}
+
+func errorResponse(err error) (int, errorBody) {
+	var locked *domain.LoginLockedError
+	if errors.As(err, &locked) {
+		return http.StatusTooManyRequests, errorBody{
+			Error:      err.Error(),
+			Code:       "login_locked",
+			RetryAfter: locked.RetryAfterSeconds(),
+		}
+	}
+
+	var forbidden *domain.ForbiddenError
+	if errors.As(err, &forbidden) {
+		return http.StatusForbidden, errorBody{Error: err.Error(), Code: "permission_denied"}
+	}
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		for _, k := range errorKinds {
+			if domainErr.Kind == k.kind {
+				code := domainErr.Code
+				if code == "" {
+					code = k.code
+				}
+				return k.status, errorBody{Error: domainErr.Message, Code: code, Fields: domainErr.Fields}
+			}
+		}
+	}
+
+	// Error kategori yang dibungkus tanpa *domain.Error (mis. fmt.Errorf("...: %w", domain.ErrNotFound))
+	for _, k := range errorKinds {
+		if errors.Is(err, k.kind) {
+			return k.status, errorBody{Error: err.Error(), Code: k.code}
+		}
+	}
+
+	return http.StatusInternalServerError, errorBody{Error: "Internal server error", Code: "internal_error"}
+}
+
+// bindError mengubah error ShouldBind* menjadi error validasi dengan pesan per field
+func bindError(err error) error {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		e := domain.NewValidation("validation_failed", "Validation failed")
+		e.Fields = make(map[string]string, len(ve))
+		for _, f := range ve {
+			e.Fields[f.Field()] = fmt.Sprintf("Field validation for '%s' failed on the '%s' tag", f.Field(), f.Tag())
+		}
+		return e
+	}
+	return domain.NewValidation("invalid_request", "Invalid request: "+err.Error())
+}
+
+// invalidParam - Error untuk parameter path/query yang tidak valid, mis. invalidParam("id", "Invalid lab ID")
+func invalidParam(param, message string) error {
+	return domain.NewFieldError(param, "invalid_parameter", message)
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"onlearn-backend/internal/domain"
 	"onlearn-backend/internal/repository"
@@ -14,11 +15,12 @@ import (
 type FileHandler struct {
 	gridFS       repository.GridFSRepository
 	courseUsecase domain.CourseUsecase
+	logger       *slog.Logger
 }
 
 // NewFileHandler creates a new FileHandler
-func NewFileHandler(gridFS repository.GridFSRepository) *FileHandler {
-	return &FileHandler{gridFS: gridFS}
+func NewFileHandler(gridFS repository.GridFSRepository, logger *slog.Logger) *FileHandler {
+	return &FileHandler{gridFS: gridFS, logger: logger}
 }
 
 // SetCourseUsecase sets the course usecase for enrollment verification
@@ -30,22 +32,21 @@ func (h *FileHandler) SetCourseUsecase(cu domain.CourseUsecase) {
 func (h *FileHandler) UploadFile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, domain.NewUnauthorized("unauthorized", "Unauthorized"))
 		return
 	}
 
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		respondError(c, domain.NewFieldError("file", "file_required", "File is required"))
 		return
 	}
 	defer file.Close()
 
 	// Validate file size (50MB max)
 	if header.Size > repository.MaxLargeFileSize {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Ukuran file terlalu besar. Maksimal %dMB", repository.MaxLargeFileSize/(1024*1024)),
-		})
+		respondError(c, domain.NewFieldError("file", "file_too_large",
+			fmt.Sprintf("Ukuran file terlalu besar. Maksimal %dMB", repository.MaxLargeFileSize/(1024*1024))))
 		return
 	}
 
@@ -55,7 +56,7 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 
 	fileInfo, err := h.gridFS.Upload(c.Request.Context(), file, header, metadata)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -75,13 +76,13 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 func (h *FileHandler) StreamFile(c *gin.Context) {
 	fileID := c.Param("id")
 	if fileID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File ID is required"})
+		respondError(c, invalidParam("id", "File ID is required"))
 		return
 	}
 
 	stream, fileInfo, err := h.gridFS.Download(c.Request.Context(), fileID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	defer stream.Close()
@@ -108,7 +109,7 @@ func (h *FileHandler) StreamFile(c *gin.Context) {
 	_, err = io.Copy(c.Writer, stream)
 	if err != nil {
 		// Log error but don't send response since headers are already sent
-		fmt.Printf("Error streaming file: %v\n", err)
+		h.logger.WarnContext(c.Request.Context(), "Failed to stream file", "file_id", fileID, "error", err)
 	}
 }
 
@@ -116,21 +117,21 @@ func (h *FileHandler) StreamFile(c *gin.Context) {
 func (h *FileHandler) StreamFileProtected(c *gin.Context) {
 	userIDVal, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, domain.NewUnauthorized("unauthorized", "Unauthorized"))
 		return
 	}
 	userID := userIDVal.(uint)
 
 	fileID := c.Param("id")
 	if fileID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File ID is required"})
+		respondError(c, invalidParam("id", "File ID is required"))
 		return
 	}
 
 	// Get file info to check course_id
 	fileInfo, err := h.gridFS.GetFileInfo(c.Request.Context(), fileID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		respondError(c, err)
 		return
 	}
 
@@ -143,12 +144,12 @@ func (h *FileHandler) StreamFileProtected(c *gin.Context) {
 			role = roleVal.(string)
 		}
 
-		// Instructors and admins have full access
-		if role != "instructor" && role != "admin" {
+		// Role dengan akses materi penuh (instructor, admin) tidak perlu enroll
+		if !domain.Role(role).Can(domain.PermCourseViewContent) {
 			// For students, verify enrollment
 			courseDetail, err := h.courseUsecase.GetCourseDetails(c.Request.Context(), fileInfo.Metadata.CourseID, &userID)
 			if err != nil || !courseDetail.IsEnrolled {
-				c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. You must be enrolled in this course."})
+				respondError(c, domain.NewForbidden("not_enrolled", "Access denied. You must be enrolled in this course."))
 				return
 			}
 		}
@@ -157,7 +158,7 @@ func (h *FileHandler) StreamFileProtected(c *gin.Context) {
 	// Download and stream the file
 	stream, _, err := h.gridFS.Download(c.Request.Context(), fileID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	defer stream.Close()
@@ -185,7 +186,7 @@ func (h *FileHandler) StreamFileProtected(c *gin.Context) {
 	_, err = io.Copy(c.Writer, stream)
 	if err != nil {
 		// Log error but don't send response since headers are already sent
-		fmt.Printf("Error streaming file: %v\n", err)
+		h.logger.WarnContext(c.Request.Context(), "Failed to stream file", "file_id", fileID, "error", err)
 	}
 }
 
@@ -193,13 +194,13 @@ func (h *FileHandler) StreamFileProtected(c *gin.Context) {
 func (h *FileHandler) GetFileInfo(c *gin.Context) {
 	fileID := c.Param("id")
 	if fileID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File ID is required"})
+		respondError(c, invalidParam("id", "File ID is required"))
 		return
 	}
 
 	fileInfo, err := h.gridFS.GetFileInfo(c.Request.Context(), fileID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -218,13 +219,13 @@ func (h *FileHandler) GetFileInfo(c *gin.Context) {
 func (h *FileHandler) DeleteFile(c *gin.Context) {
 	fileID := c.Param("id")
 	if fileID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File ID is required"})
+		respondError(c, invalidParam("id", "File ID is required"))
 		return
 	}
 
 	err := h.gridFS.Delete(c.Request.Context(), fileID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -238,13 +239,14 @@ func (h *FileHandler) UploadModuleFile(c *gin.Context, formFieldName string, use
 		if err == http.ErrMissingFile {
 			return "", nil // No file uploaded, return empty
 		}
-		return "", err
+		return "", domain.NewFieldError(formFieldName, "invalid_file", "Failed to read file")
 	}
 	defer file.Close()
 
 	// Validate file size
 	if header.Size > repository.MaxLargeFileSize {
-		return "", fmt.Errorf("ukuran file terlalu besar. Maksimal %dMB", repository.MaxLargeFileSize/(1024*1024))
+		return "", domain.NewFieldError(formFieldName, "file_too_large",
+			fmt.Sprintf("ukuran file terlalu besar. Maksimal %dMB", repository.MaxLargeFileSize/(1024*1024)))
 	}
 
 	metadata := repository.FileMetadata{
@@ -1,25 +1,34 @@
 package http
 
 import (
-	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/spreadsheet"
 	"onlearn-backend/pkg/utils"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type Handler struct {
-	AuthUsecase      domain.AuthUsecase
-	UserUsecase      domain.UserUsecase
-	CourseUsecase    domain.CourseUsecase
-	LabUsecase       domain.LabUsecase
-	CertUsecase      domain.CertificateUsecase
-	DashboardUsecase domain.DashboardUsecase
-	ReportUsecase    domain.ReportUsecase
+	AuthUsecase       domain.AuthUsecase
+	UserUsecase       domain.UserUsecase
+	CourseUsecase     domain.CourseUsecase
+	LabUsecase        domain.LabUsecase
+	CertUsecase       domain.CertificateUsecase
+	DashboardUsecase  domain.DashboardUsecase
+	ReportUsecase     domain.ReportUsecase
+	OIDCUsecase       domain.OIDCUsecase
+	AuditUsecase      domain.AuditUsecase
+	AccountUsecase    domain.AccountUsecase
+	InvitationUsecase domain.InvitationUsecase
+	OrgUsecase        domain.OrganizationUsecase
+	Logger            *slog.Logger
 }
 
 func NewHandler(
@@ -30,36 +39,36 @@ func NewHandler(
 	certu domain.CertificateUsecase,
 	du domain.DashboardUsecase,
 	ru domain.ReportUsecase,
+	ou domain.OIDCUsecase,
+	audu domain.AuditUsecase,
+	accu domain.AccountUsecase,
+	invu domain.InvitationUsecase,
+	orgu domain.OrganizationUsecase,
+	logger *slog.Logger,
 ) *Handler {
 	return &Handler{
-		AuthUsecase:      au,
-		UserUsecase:      uu,
-		CourseUsecase:    cu,
-		LabUsecase:       lu,
-		CertUsecase:      certu,
-		DashboardUsecase: du,
-		ReportUsecase:    ru,
+		AuthUsecase:       au,
+		UserUsecase:       uu,
+		CourseUsecase:     cu,
+		LabUsecase:        lu,
+		CertUsecase:       certu,
+		DashboardUsecase:  du,
+		ReportUsecase:     ru,
+		OIDCUsecase:       ou,
+		AuditUsecase:      audu,
+		AccountUsecase:    accu,
+		InvitationUsecase: invu,
+		OrgUsecase:        orgu,
+		Logger:            logger,
 	}
 }
 
 // ========== UTILITY FUNCTIONS ==========
 
-func formatValidationErrors(err error) gin.H {
-	var ve validator.ValidationErrors
-	if errors.As(err, &ve) {
-		errors := make(map[string]string)
-		for _, f := range ve {
-			errors[f.Field()] = fmt.Sprintf("Field validation for '%s' failed on the '%s' tag", f.Field(), f.Tag())
-		}
-		return gin.H{"error": "Validation failed", "details": errors}
-	}
-	return gin.H{"error": "Invalid request: " + err.Error()}
-}
-
 func getUserID(c *gin.Context) (uint, error) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		return 0, errors.New("user ID not found in token")
+		return 0, domain.NewUnauthorized("unauthorized", "user ID not found in token")
 	}
 	return userID.(uint), nil
 }
@@ -67,27 +76,45 @@ func getUserID(c *gin.Context) (uint, error) {
 func getUserRole(c *gin.Context) (string, error) {
 	role, exists := c.Get("role")
 	if !exists {
-		return "", errors.New("role not found in token")
+		return "", domain.NewUnauthorized("unauthorized", "role not found in token")
 	}
 	return role.(string), nil
 }
 
 // ========== AUTH HANDLERS ==========
 
+// Register - Pendaftaran publik (selalu student), atau lewat undangan jika invite_token diisi
 func (h *Handler) Register(c *gin.Context) {
-	var user domain.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
-		return
+	var req struct {
+		Name         string `json:"name" binding:"required"`
+		Email        string `json:"email" binding:"omitempty,email"`
+		Password     string `json:"password" binding:"required"`
+		InviteToken  string `json:"invite_token"`
+		Organization string `json:"organization"` // Slug organisasi; kosong = organisasi default
 	}
-
-	if user.Role == "" {
-		user.Role = domain.RoleStudent
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
 	}
 
-	if err := h.AuthUsecase.Register(c.Request.Context(), &user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	var user *domain.User
+	if req.InviteToken != "" {
+		var err error
+		user, err = h.InvitationUsecase.AcceptInvitation(c.Request.Context(), req.InviteToken, req.Name, req.Password)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+	} else {
+		if req.Email == "" {
+			respondError(c, domain.NewFieldError("email", "email_required", "email is required"))
+			return
+		}
+		user = &domain.User{Name: req.Name, Email: req.Email, Password: req.Password}
+		if err := h.InvitationUsecase.SignUp(c.Request.Context(), user, req.Organization); err != nil {
+			respondError(c, err)
+			return
+		}
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
@@ -107,1006 +134,2176 @@ func (h *Handler) Login(c *gin.Context) {
 		Password string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&creds); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+		respondError(c, bindError(err))
 		return
 	}
 
-	token, err := h.AuthUsecase.Login(c.Request.Context(), creds.Email, creds.Password)
+	result, err := h.AuthUsecase.Login(c.Request.Context(), creds.Email, creds.Password, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	// Jika 2FA aktif/wajib, yang dikirim adalah challenge; token diberikan setelah VerifyMFA
+	loginResultResponse(c, result)
+}
+
+// loginResultResponse mengirim token, atau challenge 2FA jika login belum selesai
+func loginResultResponse(c *gin.Context, result *domain.LoginResult) {
+	if result.MFARequired {
+		setMFACookie(c, result.MFAToken, result.MFAExpiresIn)
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":   true,
+			"mfa_token":      result.MFAToken,
+			"mfa_purpose":    result.MFAPurpose,
+			"mfa_expires_in": result.MFAExpiresIn,
+		})
 		return
 	}
 
-	// PERBAIKAN: Set Cookie di Server
-	// Name: "token", Value: token, MaxAge: 86400 (24h), Path: "/", Domain: "", Secure: false (localhost), HttpOnly: false
-	c.SetCookie("token", token, 86400, "/", "", false, false)
+	// Set Cookie di Server (dipakai halaman web)
+	setAuthCookies(c, result.Tokens)
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokenResponse(result.Tokens))
 }
 
-func (h *Handler) UpdateProfile(c *gin.Context) {
-	userID, err := getUserID(c)
+// ========== OPENID CONNECT ==========
+
+// OIDCAuthorize mengembalikan URL login identity provider. Setelah login, provider
+// redirect ke OIDC_REDIRECT_URL membawa code dan state untuk OIDCCallback.
+func (h *Handler) OIDCAuthorize(c *gin.Context) {
+	if !h.OIDCUsecase.Enabled() {
+		respondError(c, domain.ErrOIDCNotConfigured)
+		return
+	}
+
+	req, err := h.OIDCUsecase.BeginLogin(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	var user domain.User
-	user.ID = userID
-	user.Name = c.PostForm("name")
-	user.Password = c.PostForm("password")
+	c.JSON(http.StatusOK, req)
+}
 
-	filePath, err := utils.HandleUpload(c, "profile_picture")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file: " + err.Error()})
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	if !h.OIDCUsecase.Enabled() {
+		respondError(c, domain.ErrOIDCNotConfigured)
 		return
 	}
-	if filePath != "" {
-		user.ProfilePicture = filePath
+
+	var req struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
 	}
 
-	if err := h.AuthUsecase.UpdateUser(c.Request.Context(), &user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	result, err := h.OIDCUsecase.CompleteLogin(c.Request.Context(), req.State, req.Code, clientInfo(c))
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
+	loginResultResponse(c, result)
 }
 
-func (h *Handler) ForgotPassword(c *gin.Context) {
+// ========== TWO-FACTOR AUTHENTICATION ==========
+
+// mfaTokenFromRequest mengambil mfa_token dari body, atau dari cookie untuk halaman web
+func mfaTokenFromRequest(c *gin.Context, token string) string {
+	if token != "" {
+		return token
+	}
+	cookie, _ := c.Cookie(mfaTokenCookie)
+	return cookie
+}
+
+func (h *Handler) VerifyMFA(c *gin.Context) {
 	var req struct {
-		Email string `json:"email" binding:"required,email"`
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+		respondError(c, bindError(err))
 		return
 	}
 
-	h.AuthUsecase.ForgotPassword(c.Request.Context(), req.Email)
-	c.JSON(http.StatusOK, gin.H{"message": "If the email exists, a password reset link has been sent."})
-}
+	result, err := h.AuthUsecase.VerifyMFA(c.Request.Context(), mfaTokenFromRequest(c, req.MFAToken), req.Code, clientInfo(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-// ========== DASHBOARD HANDLERS ==========
+	clearMFACookie(c)
+	setAuthCookies(c, result.Tokens)
 
-func (h *Handler) GetStudentDashboard(c *gin.Context) {
-	userID, err := getUserID(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	response := tokenResponse(result.Tokens)
+	if len(result.RecoveryCodes) > 0 {
+		response["recovery_codes"] = result.RecoveryCodes
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SetupMFAChallenge dipakai saat 2FA wajib untuk role user tapi user belum enroll
+func (h *Handler) SetupMFAChallenge(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	data, err := h.DashboardUsecase.GetStudentDashboard(c.Request.Context(), userID)
+	setup, err := h.AuthUsecase.SetupMFAForChallenge(c.Request.Context(), mfaTokenFromRequest(c, req.MFAToken))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+	if setup == nil {
+		respondError(c, domain.NewConflict("mfa_already_enabled", "Two-factor authentication is already set up"))
 		return
 	}
 
-	c.JSON(http.StatusOK, data)
+	c.JSON(http.StatusOK, setup)
 }
 
-func (h *Handler) GetInstructorDashboard(c *gin.Context) {
+func (h *Handler) SetupMFA(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	data, err := h.DashboardUsecase.GetInstructorDashboard(c.Request.Context(), userID)
+	setup, err := h.AuthUsecase.SetupMFA(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, data)
+	c.JSON(http.StatusOK, setup)
 }
 
-func (h *Handler) GetAdminDashboard(c *gin.Context) {
-	data, err := h.DashboardUsecase.GetAdminDashboard(c.Request.Context())
+func (h *Handler) ConfirmMFA(c *gin.Context) {
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, data)
-}
-
-// ========== COURSE HANDLERS ==========
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
 
-func (h *Handler) CreateCourse(c *gin.Context) {
-	userID, err := getUserID(c)
+	codes, err := h.AuthUsecase.ConfirmMFA(c.Request.Context(), userID, req.Code)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	var course domain.Course
-	course.Title = c.PostForm("title")
-	course.Description = c.PostForm("description")
-	course.InstructorID = userID
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled. Store these recovery codes in a safe place.",
+		"recovery_codes": codes,
+	})
+}
 
-	if course.Title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+func (h *Handler) DisableMFA(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	filePath, err := utils.HandleUpload(c, "thumbnail")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload thumbnail: " + err.Error()})
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
-	course.Thumbnail = filePath
 
-	if err := h.CourseUsecase.CreateCourse(c.Request.Context(), &course); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.AuthUsecase.DisableMFA(c.Request.Context(), userID, req.Code); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, course)
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
 }
 
-func (h *Handler) UpdateCourse(c *gin.Context) {
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	idStr := c.Param("id")
-	courseID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	// Get existing course to verify ownership
-	existing, err := h.CourseUsecase.GetCourseDetails(c.Request.Context(), uint(courseID), nil)
+	codes, err := h.AuthUsecase.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		respondError(c, err)
 		return
 	}
 
-	// Verify instructor owns this course
-	if existing.InstructorID != userID {
-		role, _ := getUserRole(c)
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own courses"})
-			return
-		}
-	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
 
-	var course domain.Course
-	course.ID = uint(courseID)
-	course.Title = c.PostForm("title")
-	course.Description = c.PostForm("description")
-	course.InstructorID = existing.InstructorID // Keep original instructor
+// ========== PERSONAL API KEYS ==========
 
-	if course.Title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+// CreateAPIKey - Kunci mentah hanya ada di response ini; setelahnya hanya prefix yang terlihat
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	// Handle thumbnail upload if provided
-	filePath, err := utils.HandleUpload(c, "thumbnail")
+	var req struct {
+		Name          string              `json:"name" binding:"required"`
+		Scopes        []domain.Permission `json:"scopes" binding:"required,min=1"`
+		ExpiresInDays int                 `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	key, err := h.AuthUsecase.CreateAPIKey(c.Request.Context(), userID, req.Name, req.Scopes, ttl)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload thumbnail: " + err.Error()})
+		respondError(c, err)
 		return
 	}
-	if filePath != "" {
-		course.Thumbnail = filePath
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created. Copy it now, it will not be shown again.",
+		"api_key": key,
+	})
+}
+
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	if err := h.CourseUsecase.UpdateCourse(c.Request.Context(), &course); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	keys, err := h.AuthUsecase.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Course updated successfully", "course": course})
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": keys,
+		"count":    len(keys),
+	})
 }
 
-func (h *Handler) PublishCourse(c *gin.Context) {
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	idStr := c.Param("id")
-	courseID, err := strconv.ParseUint(idStr, 10, 32)
+	keyID, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		respondError(c, invalidParam("id", "Invalid API key ID"))
 		return
 	}
 
-	if err := h.CourseUsecase.PublishCourse(c.Request.Context(), uint(courseID), userID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.AuthUsecase.RevokeAPIKey(c.Request.Context(), userID, uint(keyID)); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Course published successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
 }
 
-func (h *Handler) UnpublishCourse(c *gin.Context) {
+// ========== INVITATIONS ==========
+
+func (h *Handler) CreateInvitation(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	idStr := c.Param("id")
-	courseID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+	var req struct {
+		Email         string      `json:"email" binding:"required,email"`
+		Role          domain.Role `json:"role"`
+		CourseID      *uint       `json:"course_id"`
+		ExpiresInDays int         `json:"expires_in_days" binding:"omitempty,min=1,max=30"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	if err := h.CourseUsecase.UnpublishCourse(c.Request.Context(), uint(courseID), userID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	invitation, err := h.InvitationUsecase.CreateInvitation(c.Request.Context(), req.Email, req.Role, req.CourseID, ttl, userID)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Course unpublished successfully"})
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitation sent successfully",
+		"invitation": invitation,
+	})
 }
 
-func (h *Handler) DeleteCourse(c *gin.Context) {
+func (h *Handler) ListInvitations(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	idStr := c.Param("id")
-	courseID, err := strconv.ParseUint(idStr, 10, 32)
+	invitations, err := h.InvitationUsecase.ListInvitations(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		respondError(c, err)
 		return
 	}
 
-	// Get existing course to verify ownership
-	existing, err := h.CourseUsecase.GetCourseDetails(c.Request.Context(), uint(courseID), nil)
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+		"count":       len(invitations),
+	})
+}
+
+func (h *Handler) RevokeInvitation(c *gin.Context) {
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		respondError(c, err)
 		return
 	}
 
-	// Verify instructor owns this course
-	if existing.InstructorID != userID {
-		role, _ := getUserRole(c)
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own courses"})
-			return
-		}
+	idStr := c.Param("id")
+	invitationID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid invitation ID"))
+		return
 	}
 
-	if err := h.CourseUsecase.DeleteCourse(c.Request.Context(), uint(courseID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.InvitationUsecase.RevokeInvitation(c.Request.Context(), uint(invitationID), userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Course deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
 }
 
-func (h *Handler) GetAllCourses(c *gin.Context) {
-	// Students only see published courses
-	// Instructors should use GetInstructorCourses to see all their courses
-	courses, err := h.CourseUsecase.GetAllCourses(c.Request.Context())
+// GetInvitation - Publik: detail undangan untuk mengisi form pendaftaran
+func (h *Handler) GetInvitation(c *gin.Context) {
+	invitation, err := h.InvitationUsecase.GetInvitation(c.Request.Context(), c.Param("token"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"courses": courses,
-		"count":   len(courses),
-	})
+	c.JSON(http.StatusOK, gin.H{"invitation": invitationSummary(invitation)})
 }
 
-func (h *Handler) GetCourseDetail(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
-		return
+// AcceptInvitation - Publik: membuat akun dari undangan
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Name     string `json:"name" binding:"required"`
+		Password string `json:"password" binding:"required"`
 	}
-
-	// Get user ID if authenticated
-	var userIDPtr *uint
-	if userID, err := getUserID(c); err == nil {
-		userIDPtr = &userID
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
 	}
 
-	detail, err := h.CourseUsecase.GetCourseDetails(c.Request.Context(), uint(id), userIDPtr)
+	user, err := h.InvitationUsecase.AcceptInvitation(c.Request.Context(), req.Token, req.Name, req.Password)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, detail)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invitation accepted, account created successfully",
+		"user": gin.H{
+			"id":    user.ID,
+			"name":  user.Name,
+			"email": user.Email,
+			"role":  user.Role,
+		},
+	})
 }
 
-func (h *Handler) EnrollCourse(c *gin.Context) {
+// invitationSummary - Data undangan yang aman ditampilkan ke pemegang link
+func invitationSummary(invitation *domain.Invitation) gin.H {
+	summary := gin.H{
+		"email":      invitation.Email,
+		"role":       invitation.Role,
+		"expires_at": invitation.ExpiresAt,
+	}
+	if invitation.Course != nil {
+		summary["course"] = gin.H{"id": invitation.Course.ID, "title": invitation.Course.Title}
+	}
+	if invitation.Inviter != nil {
+		summary["invited_by"] = invitation.Inviter.Name
+	}
+	return summary
+}
+
+// ========== ACCOUNT DATA (EXPORT & DELETION) ==========
+
+// ExportAccount - Mengunduh arsip zip berisi semua data milik user
+func (h *Handler) ExportAccount(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	idStr := c.Param("id")
-	courseID, err := strconv.ParseUint(idStr, 10, 32)
+	export, err := h.AccountUsecase.GetExport(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		respondError(c, err)
 		return
 	}
 
-	if err := h.CourseUsecase.EnrollStudent(c.Request.Context(), userID, uint(courseID)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+	writeAccountExport(c, h.AccountUsecase, h.Logger, export)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Successfully enrolled in course"})
+// writeAccountExport mengirim arsip sebagai download; header sudah terkirim saat
+// arsip mulai ditulis, jadi kegagalan di tengah hanya bisa dicatat
+func writeAccountExport(c *gin.Context, account domain.AccountUsecase, logger *slog.Logger, export *domain.AccountExport) {
+	filename := fmt.Sprintf("onlearn-export-%d-%s.zip", export.User.ID, export.ExportedAt.Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	if err := account.WriteExportArchive(c.Request.Context(), export, c.Writer); err != nil {
+		logger.WarnContext(c.Request.Context(), "Failed to write account export", "target_user_id", export.User.ID, "error", err)
+	}
 }
 
-func (h *Handler) GetMyEnrollments(c *gin.Context) {
+func (h *Handler) GetAccountDeletion(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	enrollments, err := h.CourseUsecase.GetStudentEnrollments(c.Request.Context(), userID)
+	deletion, err := h.AccountUsecase.GetDeletion(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"enrollments": enrollments,
-		"count":       len(enrollments),
-	})
+	c.JSON(http.StatusOK, gin.H{"deletion": deletion})
 }
 
-// ========== MODULE HANDLERS ==========
-
-func (h *Handler) AddModule(c *gin.Context) {
-	courseIDStr := c.PostForm("course_id")
-	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
+// RequestAccountDeletion - User harus mengetik ulang email sebagai konfirmasi
+func (h *Handler) RequestAccountDeletion(c *gin.Context) {
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course_id"})
+		respondError(c, err)
 		return
 	}
 
-	orderStr := c.PostForm("order")
-	order, err := strconv.Atoi(orderStr)
-	if err != nil {
-		order = 0
+	var req struct {
+		ConfirmEmail string `json:"confirm_email" binding:"required,email"`
+		Reason       string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
 	}
 
-	var module domain.Module
-	module.CourseID = uint(courseID)
-	module.Title = c.PostForm("title")
-	module.Type = domain.ModuleType(c.PostForm("type"))
-	module.Description = c.PostForm("description")
-	module.QuizLink = c.PostForm("quiz_link") // Exam link (Google Form atau link lainnya)
-	module.Order = order
-
-	if module.Title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+	user, err := h.AuthUsecase.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !strings.EqualFold(user.Email, req.ConfirmEmail) {
+		respondError(c, domain.NewFieldError("confirm_email", "confirmation_mismatch", "Confirmation email does not match"))
 		return
 	}
 
-	// Type validation - harus pdf atau ppt
-	if module.Type != "" && module.Type != domain.TypePDF && module.Type != domain.TypePPT {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Type must be 'pdf' or 'ppt'"})
+	deletion, err := h.AccountUsecase.RequestDeletion(c.Request.Context(), userID, req.Reason)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	// Handle file upload untuk PPT atau PDF
-	if module.Type == domain.TypePPT || module.Type == domain.TypePDF {
-		filePath, err := utils.HandleUpload(c, "content_url")
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload content: " + err.Error()})
-			return
-		}
-		if filePath != "" {
-			module.ContentURL = filePath
-		}
-	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Account deletion scheduled. You can cancel it until the scheduled time.",
+		"deletion": deletion,
+	})
+}
 
-	// Jika hanya exam link tanpa file, type bisa kosong atau kita set default
-	if module.QuizLink != "" && module.Type == "" {
-		// Jika hanya ada exam link, kita bisa set type sebagai "exam" atau biarkan kosong
-		// Untuk sekarang, kita akan require type tetap
+func (h *Handler) CancelAccountDeletion(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	if err := h.CourseUsecase.AddModule(c.Request.Context(), &module); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.AccountUsecase.CancelDeletion(c.Request.Context(), userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, module)
+	c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
 }
 
-func (h *Handler) UpdateModule(c *gin.Context) {
-	userID, err := getUserID(c)
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	tokens, err := h.AuthUsecase.RefreshToken(c.Request.Context(), req.RefreshToken, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	moduleID := c.Param("id")
-	if moduleID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Module ID is required"})
+	setAuthCookies(c, tokens)
+
+	c.JSON(http.StatusOK, tokenResponse(tokens))
+}
+
+func (h *Handler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	// Get existing module
-	existing, err := h.CourseUsecase.GetModuleByID(c.Request.Context(), moduleID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found"})
+	if err := h.AuthUsecase.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	// Verify instructor owns the course
-	courseDetail, err := h.CourseUsecase.GetCourseDetails(c.Request.Context(), existing.CourseID, nil)
+	clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// tokenResponse - "token" tetap dikirim untuk kompatibilitas client lama. refresh_token
+// tidak dikirim jika refresh token lama tetap berlaku (request paralel sudah merotasinya).
+func tokenResponse(tokens *domain.AuthTokens) gin.H {
+	resp := gin.H{
+		"token":        tokens.AccessToken,
+		"access_token": tokens.AccessToken,
+		"token_type":   tokens.TokenType,
+		"expires_in":   tokens.ExpiresIn,
+	}
+	if tokens.RefreshToken != "" {
+		resp["refresh_token"] = tokens.RefreshToken
+	}
+	return resp
+}
+
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		respondError(c, err)
 		return
 	}
 
-	if courseDetail.InstructorID != userID {
-		role, _ := getUserRole(c)
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You can only update modules in your own courses"})
+	var user domain.User
+	user.ID = userID
+	user.Name = c.PostForm("name")
+
+	// Password hanya diganti jika password lama cocok
+	if password := c.PostForm("password"); password != "" {
+		if err := h.AuthUsecase.ChangePassword(c.Request.Context(), userID, c.PostForm("current_password"), password); err != nil {
+			respondError(c, err)
 			return
 		}
 	}
 
-	var module domain.Module
-	module.ID = moduleID
-	module.CourseID = existing.CourseID
-	module.Title = c.PostForm("title")
-	module.Type = domain.ModuleType(c.PostForm("type"))
-	module.Description = c.PostForm("description")
-	module.QuizLink = c.PostForm("quiz_link")
+	filePath, err := utils.HandleUpload(c, "profile_picture")
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to upload file: %w", err))
+		return
+	}
+	if filePath != "" {
+		user.ProfilePicture = filePath
+	}
 
-	orderStr := c.PostForm("order")
-	if orderStr != "" {
-		order, err := strconv.Atoi(orderStr)
-		if err == nil {
-			module.Order = order
-		} else {
-			module.Order = existing.Order
-		}
-	} else {
-		module.Order = existing.Order
+	if err := h.AuthUsecase.UpdateUser(c.Request.Context(), &user); err != nil {
+		respondError(c, err)
+		return
 	}
 
-	if module.Title == "" {
-		module.Title = existing.Title
+	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
+}
+
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
 	}
-	if module.Type == "" {
-		module.Type = existing.Type
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
 	}
 
-	// Handle content upload if provided
-	filePath, err := utils.HandleUpload(c, "content_url")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload content: " + err.Error()})
-		return
+	h.AuthUsecase.ForgotPassword(c.Request.Context(), req.Email)
+	c.JSON(http.StatusOK, gin.H{"message": "If the email exists, a password reset link has been sent."})
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
 	}
-	if filePath != "" {
-		module.ContentURL = filePath
-	} else {
-		module.ContentURL = existing.ContentURL
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
 	}
 
-	if err := h.CourseUsecase.UpdateModule(c.Request.Context(), &module); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.AuthUsecase.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Module updated successfully", "module": module})
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset. Please log in with your new password."})
 }
 
-func (h *Handler) DeleteModule(c *gin.Context) {
-	userID, err := getUserID(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+		Code  string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	moduleID := c.Param("id")
-	if moduleID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Module ID is required"})
+	if err := h.AuthUsecase.VerifyEmail(c.Request.Context(), req.Email, req.Code); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	// Get existing module to verify ownership
-	existing, err := h.CourseUsecase.GetModuleByID(c.Request.Context(), moduleID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found"})
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	// Verify instructor owns the course
-	courseDetail, err := h.CourseUsecase.GetCourseDetails(c.Request.Context(), existing.CourseID, nil)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+	if err := h.AuthUsecase.ResendVerification(c.Request.Context(), req.Email); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if courseDetail.InstructorID != userID {
-		role, _ := getUserRole(c)
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete modules in your own courses"})
-			return
-		}
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered and not yet verified, a new code has been sent."})
+}
+
+// ========== DASHBOARD HANDLERS ==========
+
+func (h *Handler) GetStudentDashboard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	if err := h.CourseUsecase.DeleteModule(c.Request.Context(), moduleID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	data, err := h.DashboardUsecase.GetStudentDashboard(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Module deleted successfully"})
+	c.JSON(http.StatusOK, data)
 }
 
-func (h *Handler) GetModulesWithProgress(c *gin.Context) {
+func (h *Handler) GetInstructorDashboard(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	courseIDStr := c.Param("id")
-	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
+	data, err := h.DashboardUsecase.GetInstructorDashboard(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		respondError(c, err)
 		return
 	}
 
-	modules, err := h.CourseUsecase.GetModulesWithProgress(c.Request.Context(), userID, uint(courseID))
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetAdminDashboard(c *gin.Context) {
+	data, err := h.DashboardUsecase.GetAdminDashboard(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"modules": modules,
-		"count":   len(modules),
-	})
+	c.JSON(http.StatusOK, data)
 }
 
-func (h *Handler) MarkModuleComplete(c *gin.Context) {
+// ========== COURSE HANDLERS ==========
+
+func (h *Handler) CreateCourse(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	var req struct {
-		ModuleID string `json:"module_id" binding:"required"`
-		CourseID uint   `json:"course_id" binding:"required"`
+	var course domain.Course
+	course.Title = c.PostForm("title")
+	course.Description = c.PostForm("description")
+	course.InstructorID = userID
+
+	if course.Title == "" {
+		respondError(c, domain.NewFieldError("title", "title_required", "Title is required"))
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+	filePath, err := utils.HandleUpload(c, "thumbnail")
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to upload thumbnail: %w", err))
 		return
 	}
+	course.Thumbnail = filePath
 
-	if err := h.CourseUsecase.MarkModuleComplete(c.Request.Context(), userID, req.ModuleID, req.CourseID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.CourseUsecase.CreateCourse(c.Request.Context(), &course, userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Module marked as complete"})
+	c.JSON(http.StatusCreated, course)
 }
 
-func (h *Handler) SavePPTProgress(c *gin.Context) {
+func (h *Handler) UpdateCourse(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	var req struct {
-		ModuleID    string `json:"module_id" binding:"required"`
-		CourseID    uint   `json:"course_id" binding:"required"`
-		SlideNumber int    `json:"slide_number" binding:"required"`
+	idStr := c.Param("id")
+	courseID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid course ID"))
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+	var course domain.Course
+	course.ID = uint(courseID)
+	course.Title = c.PostForm("title")
+	course.Description = c.PostForm("description")
+
+	if course.Title == "" {
+		respondError(c, domain.NewFieldError("title", "title_required", "Title is required"))
 		return
 	}
 
-	if err := h.CourseUsecase.SavePPTProgress(c.Request.Context(), userID, req.ModuleID, req.CourseID, req.SlideNumber); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Handle thumbnail upload if provided
+	filePath, err := utils.HandleUpload(c, "thumbnail")
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to upload thumbnail: %w", err))
 		return
 	}
+	if filePath != "" {
+		course.Thumbnail = filePath
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "PPT progress saved"})
+	if err := h.CourseUsecase.UpdateCourse(c.Request.Context(), &course, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Course updated successfully", "course": course})
 }
 
-func (h *Handler) GetPPTProgress(c *gin.Context) {
+func (h *Handler) PublishCourse(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	moduleID := c.Query("module_id")
-	if moduleID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "module_id is required"})
+	idStr := c.Param("id")
+	courseID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid course ID"))
 		return
 	}
 
-	lastSlide, err := h.CourseUsecase.GetPPTProgress(c.Request.Context(), userID, moduleID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.CourseUsecase.PublishCourse(c.Request.Context(), uint(courseID), userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"last_slide": lastSlide})
+	c.JSON(http.StatusOK, gin.H{"message": "Course published successfully"})
 }
 
-// ========== ASSIGNMENT HANDLERS ==========
-
-func (h *Handler) SubmitAssignment(c *gin.Context) {
+func (h *Handler) UnpublishCourse(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	moduleID := c.PostForm("module_id")
-	courseIDStr := c.PostForm("course_id")
-	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
+	idStr := c.Param("id")
+	courseID, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course_id"})
+		respondError(c, invalidParam("id", "Invalid course ID"))
 		return
 	}
 
-	// Handle file upload (optional for quiz completion - HandleUpload returns empty string if no file)
-	filePath, err := utils.HandleUpload(c, "file")
+	if err := h.CourseUsecase.UnpublishCourse(c.Request.Context(), uint(courseID), userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Course unpublished successfully"})
+}
+
+func (h *Handler) DeleteCourse(c *gin.Context) {
+	userID, err := getUserID(c)
 	if err != nil {
-		// HandleUpload returns nil error if file is missing, so any error here is real
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file: " + err.Error()})
+		respondError(c, err)
 		return
 	}
-	// filePath can be empty for quiz completion without file submission
 
-	assignment := &domain.Assignment{
-		UserID:   userID,
-		ModuleID: moduleID,
-		CourseID: uint(courseID),
-		FileURL:  filePath,
+	idStr := c.Param("id")
+	courseID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid course ID"))
+		return
 	}
 
-	if err := h.CourseUsecase.SubmitAssignment(c.Request.Context(), assignment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.CourseUsecase.DeleteCourse(c.Request.Context(), uint(courseID), userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Assignment submitted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Course deleted successfully"})
 }
 
-func (h *Handler) GradeAssignment(c *gin.Context) {
+func (h *Handler) GetAllCourses(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	var req struct {
-		AssignmentID uint    `json:"assignment_id" binding:"required"`
-		Grade        float64 `json:"grade" binding:"required"`
-		Feedback     string  `json:"feedback"`
+	q, err := parseListQuery(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Default hanya course published; draft (published=false) hanya untuk yang boleh publish
+	courses, page, err := h.CourseUsecase.ListCourses(c.Request.Context(), q, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"courses":    courses,
+		"count":      len(courses),
+		"pagination": page,
+	})
+}
+
+// Search - Pencarian course published dan module: GET /search?q=...&limit=...
+func (h *Handler) Search(c *gin.Context) {
+	limit := 0
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			respondError(c, invalidParam("limit", "Invalid limit, expected a non-negative integer"))
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.CourseUsecase.Search(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *Handler) GetCourseDetail(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid course ID"))
+		return
+	}
+
+	// Get user ID if authenticated
+	var userIDPtr *uint
+	if userID, err := getUserID(c); err == nil {
+		userIDPtr = &userID
+	}
+
+	detail, err := h.CourseUsecase.GetCourseDetails(c.Request.Context(), uint(id), userIDPtr)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+func (h *Handler) EnrollCourse(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idStr := c.Param("id")
+	courseID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid course ID"))
+		return
+	}
+
+	if err := h.CourseUsecase.EnrollStudent(c.Request.Context(), userID, uint(courseID)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully enrolled in course"})
+}
+
+func (h *Handler) GetMyEnrollments(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	enrollments, err := h.CourseUsecase.GetStudentEnrollments(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enrollments": enrollments,
+		"count":       len(enrollments),
+	})
+}
+
+// ========== MODULE HANDLERS ==========
+
+func (h *Handler) AddModule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	courseIDStr := c.PostForm("course_id")
+	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("course_id", "Invalid course_id"))
+		return
+	}
+
+	orderStr := c.PostForm("order")
+	order, err := strconv.Atoi(orderStr)
+	if err != nil {
+		order = 0
+	}
+
+	var module domain.Module
+	module.CourseID = uint(courseID)
+	module.Title = c.PostForm("title")
+	module.Type = domain.ModuleType(c.PostForm("type"))
+	module.Description = c.PostForm("description")
+	module.QuizLink = c.PostForm("quiz_link") // Exam link (Google Form atau link lainnya)
+	module.Order = order
+
+	if module.Title == "" {
+		respondError(c, domain.NewFieldError("title", "title_required", "Title is required"))
+		return
+	}
+
+	// Type validation - harus pdf atau ppt
+	if module.Type != "" && module.Type != domain.TypePDF && module.Type != domain.TypePPT {
+		respondError(c, domain.NewFieldError("type", "invalid_module_type", "Type must be 'pdf' or 'ppt'"))
+		return
+	}
+
+	// Handle file upload untuk PPT atau PDF
+	if module.Type == domain.TypePPT || module.Type == domain.TypePDF {
+		filePath, err := utils.HandleUpload(c, "content_url")
+		if err != nil {
+			respondError(c, fmt.Errorf("failed to upload content: %w", err))
+			return
+		}
+		if filePath != "" {
+			module.ContentURL = filePath
+		}
+	}
+
+	// Jika hanya exam link tanpa file, type bisa kosong atau kita set default
+	if module.QuizLink != "" && module.Type == "" {
+		// Jika hanya ada exam link, kita bisa set type sebagai "exam" atau biarkan kosong
+		// Untuk sekarang, kita akan require type tetap
+	}
+
+	if err := h.CourseUsecase.AddModule(c.Request.Context(), &module, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, module)
+}
+
+func (h *Handler) UpdateModule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	moduleID := c.Param("id")
+	if moduleID == "" {
+		respondError(c, invalidParam("id", "Module ID is required"))
+		return
+	}
+
+	// Get existing module
+	existing, err := h.CourseUsecase.GetModuleByID(c.Request.Context(), moduleID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var module domain.Module
+	module.ID = moduleID
+	module.CourseID = existing.CourseID
+	module.Title = c.PostForm("title")
+	module.Type = domain.ModuleType(c.PostForm("type"))
+	module.Description = c.PostForm("description")
+	module.QuizLink = c.PostForm("quiz_link")
+
+	orderStr := c.PostForm("order")
+	if orderStr != "" {
+		order, err := strconv.Atoi(orderStr)
+		if err == nil {
+			module.Order = order
+		} else {
+			module.Order = existing.Order
+		}
+	} else {
+		module.Order = existing.Order
+	}
+
+	if module.Title == "" {
+		module.Title = existing.Title
+	}
+	if module.Type == "" {
+		module.Type = existing.Type
+	}
+
+	// Handle content upload if provided
+	filePath, err := utils.HandleUpload(c, "content_url")
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to upload content: %w", err))
+		return
+	}
+	if filePath != "" {
+		module.ContentURL = filePath
+	} else {
+		module.ContentURL = existing.ContentURL
+	}
+
+	if err := h.CourseUsecase.UpdateModule(c.Request.Context(), &module, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Module updated successfully", "module": module})
+}
+
+func (h *Handler) DeleteModule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	moduleID := c.Param("id")
+	if moduleID == "" {
+		respondError(c, invalidParam("id", "Module ID is required"))
+		return
+	}
+
+	// Verify module exists
+	if _, err := h.CourseUsecase.GetModuleByID(c.Request.Context(), moduleID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.CourseUsecase.DeleteModule(c.Request.Context(), moduleID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Module deleted successfully"})
+}
+
+func (h *Handler) GetModulesWithProgress(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	courseIDStr := c.Param("id")
+	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid course ID"))
+		return
+	}
+
+	modules, err := h.CourseUsecase.GetModulesWithProgress(c.Request.Context(), userID, uint(courseID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"modules": modules,
+		"count":   len(modules),
+	})
+}
+
+func (h *Handler) MarkModuleComplete(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req struct {
+		ModuleID string `json:"module_id" binding:"required"`
+		CourseID uint   `json:"course_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	if err := h.CourseUsecase.MarkModuleComplete(c.Request.Context(), userID, req.ModuleID, req.CourseID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Module marked as complete"})
+}
+
+func (h *Handler) SavePPTProgress(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req struct {
+		ModuleID    string `json:"module_id" binding:"required"`
+		CourseID    uint   `json:"course_id" binding:"required"`
+		SlideNumber int    `json:"slide_number" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	if err := h.CourseUsecase.SavePPTProgress(c.Request.Context(), userID, req.ModuleID, req.CourseID, req.SlideNumber); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "PPT progress saved"})
+}
+
+func (h *Handler) GetPPTProgress(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	moduleID := c.Query("module_id")
+	if moduleID == "" {
+		respondError(c, domain.NewFieldError("module_id", "missing_parameter", "module_id is required"))
+		return
+	}
+
+	lastSlide, err := h.CourseUsecase.GetPPTProgress(c.Request.Context(), userID, moduleID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"last_slide": lastSlide})
+}
+
+// ========== ASSIGNMENT HANDLERS ==========
+
+func (h *Handler) SubmitAssignment(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	moduleID := c.PostForm("module_id")
+	courseIDStr := c.PostForm("course_id")
+	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("course_id", "Invalid course_id"))
+		return
+	}
+
+	// Handle file upload (optional for quiz completion - HandleUpload returns empty string if no file)
+	filePath, err := utils.HandleUpload(c, "file")
+	if err != nil {
+		// HandleUpload returns nil error if file is missing, so any error here is real
+		respondError(c, fmt.Errorf("failed to upload file: %w", err))
+		return
+	}
+	// filePath can be empty for quiz completion without file submission
+
+	assignment := &domain.Assignment{
+		UserID:   userID,
+		ModuleID: moduleID,
+		CourseID: uint(courseID),
+		FileURL:  filePath,
+	}
+
+	if err := h.CourseUsecase.SubmitAssignment(c.Request.Context(), assignment); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Assignment submitted successfully"})
+}
+
+func (h *Handler) GradeAssignment(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req struct {
+		AssignmentID uint    `json:"assignment_id" binding:"required"`
+		Grade        float64 `json:"grade" binding:"required"`
+		Feedback     string  `json:"feedback"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	if err := h.CourseUsecase.GradeAssignment(c.Request.Context(), req.AssignmentID, req.Grade, req.Feedback, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Assignment graded successfully"})
+}
+
+// ========== LAB HANDLERS ==========
+
+func (h *Handler) CreateLab(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var lab domain.Lab
+	if err := c.ShouldBindJSON(&lab); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	if err := h.LabUsecase.CreateLab(c.Request.Context(), &lab, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, lab)
+}
+
+func (h *Handler) GetAllLabs(c *gin.Context) {
+	q, err := parseListQuery(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	labs, page, err := h.LabUsecase.ListLabs(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"labs":       labs,
+		"count":      len(labs),
+		"pagination": page,
+	})
+}
+
+func (h *Handler) GetLabByID(c *gin.Context) {
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	lab, err := h.LabUsecase.GetLabByID(c.Request.Context(), uint(labID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lab)
+}
+
+func (h *Handler) UpdateLab(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	// Get existing lab
+	existing, err := h.LabUsecase.GetLabByID(c.Request.Context(), uint(labID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var lab domain.Lab
+	if err := c.ShouldBindJSON(&lab); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	lab.ID = uint(labID)
+
+	// If status not provided, keep existing
+	if lab.Status == "" {
+		lab.Status = existing.Status
+	}
+
+	if err := h.LabUsecase.UpdateLab(c.Request.Context(), &lab, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lab updated successfully", "lab": lab})
+}
+
+func (h *Handler) DeleteLab(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	// Verify lab exists
+	_, err = h.LabUsecase.GetLabByID(c.Request.Context(), uint(labID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.LabUsecase.DeleteLab(c.Request.Context(), uint(labID), userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lab deleted successfully"})
+}
+
+func (h *Handler) UpdateLabStatus(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	if err := h.LabUsecase.UpdateLabStatus(c.Request.Context(), uint(labID), req.Status, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lab status updated successfully"})
+}
+
+func (h *Handler) StudentEnrollInLab(c *gin.Context) {
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.LabUsecase.StudentEnroll(c.Request.Context(), userID, uint(labID)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully enrolled in lab"})
+}
+
+func (h *Handler) SubmitLabGrade(c *gin.Context) {
+	instructorID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req struct {
+		LabID     uint     `json:"lab_id" binding:"required"`
+		StudentID uint     `json:"student_id" binding:"required"`
+		Grade     *float64 `json:"grade"`
+		Feedback  string   `json:"feedback"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+		respondError(c, bindError(err))
 		return
 	}
 
-	if err := h.CourseUsecase.GradeAssignment(c.Request.Context(), req.AssignmentID, req.Grade, req.Feedback, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	err = h.LabUsecase.SubmitGrade(c.Request.Context(), instructorID, req.StudentID, req.LabID, req.Grade, req.Feedback)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Assignment graded successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Grade submitted successfully"})
 }
 
-// ========== LAB HANDLERS ==========
+func (h *Handler) GetUngradedStudents(c *gin.Context) {
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	students, err := h.LabUsecase.GetUngradedStudents(c.Request.Context(), uint(labID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"students": students,
+		"count":    len(students),
+	})
+}
+
+func (h *Handler) GetLabStudents(c *gin.Context) {
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	grades, err := h.LabUsecase.GetLabStudents(c.Request.Context(), uint(labID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"students": grades,
+		"count":    len(grades),
+	})
+}
+
+func (h *Handler) AddStudentToLab(c *gin.Context) {
+	actorID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	if err := h.LabUsecase.AddStudentToLab(c.Request.Context(), req.UserID, uint(labID), actorID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Student added to lab successfully"})
+}
+
+func (h *Handler) RemoveStudentFromLab(c *gin.Context) {
+	actorID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idStr := c.Param("id")
+	labID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid lab ID"))
+		return
+	}
+
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("user_id", "Invalid user ID"))
+		return
+	}
+
+	if err := h.LabUsecase.RemoveStudentFromLab(c.Request.Context(), uint(userID), uint(labID), actorID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Student removed from lab successfully"})
+}
+
+func (h *Handler) GetCourseStudents(c *gin.Context) {
+	idStr := c.Param("id")
+	courseID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid course ID"))
+		return
+	}
+
+	students, err := h.CourseUsecase.GetCourseStudents(c.Request.Context(), uint(courseID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"students": students,
+		"count":    len(students),
+	})
+}
+
+func (h *Handler) GetModuleStudents(c *gin.Context) {
+	moduleID := c.Param("id")
+	courseIDStr := c.Query("course_id")
+	
+	if courseIDStr == "" {
+		respondError(c, domain.NewFieldError("course_id", "missing_parameter", "course_id is required"))
+		return
+	}
+	
+	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("course_id", "invalid course_id"))
+		return
+	}
+	
+	students, err := h.CourseUsecase.GetModuleStudents(c.Request.Context(), moduleID, uint(courseID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"students": students,
+		"count":    len(students),
+	})
+}
+
+func (h *Handler) SearchAllStudents(c *gin.Context) {
+	searchTerm := c.Query("q")
+	if searchTerm == "" {
+		searchTerm = ""
+	}
+
+	students, err := h.LabUsecase.SearchAllStudents(c.Request.Context(), searchTerm)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"students": students,
+		"count":    len(students),
+	})
+}
+
+// ========== CERTIFICATE HANDLERS ==========
+
+func (h *Handler) GetUserCertificates(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	certs, err := h.CertUsecase.GetUserCertificates(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"certificates": certs,
+		"count":        len(certs),
+	})
+}
+
+func (h *Handler) GetPendingCertificates(c *gin.Context) {
+	q, err := parseListQuery(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	certs, page, err := h.CertUsecase.ListPendingCertificates(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"certificates": certs,
+		"count":        len(certs),
+		"pagination":   page,
+	})
+}
+
+func (h *Handler) ApproveCertificate(c *gin.Context) {
+	approverID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idStr := c.Param("id")
+	certID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid certificate ID"))
+		return
+	}
+
+	if err := h.CertUsecase.ApproveCertificate(c.Request.Context(), uint(certID), approverID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Certificate approved successfully"})
+}
+
+func (h *Handler) RejectCertificate(c *gin.Context) {
+	approverID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idStr := c.Param("id")
+	certID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid certificate ID"))
+		return
+	}
+
+	if err := h.CertUsecase.RejectCertificate(c.Request.Context(), uint(certID), approverID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Certificate rejected successfully"})
+}
+
+// ========== USER MANAGEMENT (ADMIN) ==========
+
+func (h *Handler) GetAllUsers(c *gin.Context) {
+	q, err := parseListQuery(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	users, page, err := h.UserUsecase.ListUsers(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":      users,
+		"count":      len(users),
+		"pagination": page,
+	})
+}
+
+func (h *Handler) CreateUser(c *gin.Context) {
+	adminID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-func (h *Handler) CreateLab(c *gin.Context) {
-	var lab domain.Lab
-	if err := c.ShouldBindJSON(&lab); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+	var user domain.User
+	if err := c.ShouldBindJSON(&user); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	if err := h.LabUsecase.CreateLab(c.Request.Context(), &lab); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.UserUsecase.CreateUser(c.Request.Context(), &user, adminID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, lab)
+	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "user": user})
 }
 
-func (h *Handler) GetAllLabs(c *gin.Context) {
-	labs, err := h.LabUsecase.GetAllLabs(c.Request.Context())
+// maxImportFileSize membatasi ukuran file CSV/XLSX untuk import user
+const maxImportFileSize = 10 * 1024 * 1024
+
+// ImportUsers - Import user massal dari file CSV/XLSX (field "file").
+// dry_run=true hanya mengembalikan laporan validasi per baris tanpa menyimpan apa pun;
+// send_invites=true mengirim undangan mendaftar alih-alih langsung membuat akun.
+func (h *Handler) ImportUsers(c *gin.Context) {
+	adminID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"labs":  labs,
-		"count": len(labs),
-	})
-}
-
-func (h *Handler) GetLabByID(c *gin.Context) {
-	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
+	header, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
+		respondError(c, domain.NewFieldError("file", "file_required", "File is required"))
 		return
 	}
-
-	lab, err := h.LabUsecase.GetLabByID(c.Request.Context(), uint(labID))
+	if header.Size > maxImportFileSize {
+		respondError(c, domain.NewFieldError("file", "file_too_large", fmt.Sprintf("File too large, maximum is %dMB", maxImportFileSize/(1024*1024))))
+		return
+	}
+	file, err := header.Open()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		respondError(c, domain.NewFieldError("file", "invalid_file", "Failed to read file"))
 		return
 	}
-
-	c.JSON(http.StatusOK, lab)
-}
-
-func (h *Handler) UpdateLab(c *gin.Context) {
-	_, err := getUserID(c)
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, domain.NewFieldError("file", "invalid_file", "Failed to read file"))
 		return
 	}
 
-	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
+	records, err := spreadsheet.Read(header.Filename, data)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
+		// Semua error parser berasal dari isi file yang dikirim klien
+		respondError(c, domain.NewFieldError("file", "invalid_spreadsheet", err.Error()))
 		return
 	}
 
-	// Get existing lab
-	existing, err := h.LabUsecase.GetLabByID(c.Request.Context(), uint(labID))
+	courseIDs, err := parseIDList(c.PostForm("course_ids"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+		respondError(c, invalidParam("course_ids", "Invalid course_ids"))
 		return
 	}
-
-	var lab domain.Lab
-	if err := c.ShouldBindJSON(&lab); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+	labIDs, err := parseIDList(c.PostForm("lab_ids"))
+	if err != nil {
+		respondError(c, invalidParam("lab_ids", "Invalid lab_ids"))
 		return
 	}
 
-	lab.ID = uint(labID)
-
-	// If status not provided, keep existing
-	if lab.Status == "" {
-		lab.Status = existing.Status
+	opts := domain.UserImportOptions{
+		DryRun:      c.PostForm("dry_run") == "true",
+		DefaultRole: domain.Role(c.PostForm("default_role")),
+		CourseIDs:   courseIDs,
+		LabIDs:      labIDs,
+		SendInvites: c.PostForm("send_invites") == "true",
 	}
 
-	if err := h.LabUsecase.UpdateLab(c.Request.Context(), &lab); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	report, err := h.UserUsecase.ImportUsers(c.Request.Context(), records, opts, adminID)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Lab updated successfully", "lab": lab})
+	c.JSON(http.StatusOK, gin.H{"report": report})
 }
 
-func (h *Handler) DeleteLab(c *gin.Context) {
-	_, err := getUserID(c)
+// parseIDList membaca daftar ID dipisah koma, mis. "1,2,3"
+func parseIDList(value string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+func (h *Handler) UpdateUser(c *gin.Context) {
+	adminID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
+	userID, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
+		respondError(c, invalidParam("id", "Invalid user ID"))
 		return
 	}
 
-	// Verify lab exists
-	_, err = h.LabUsecase.GetLabByID(c.Request.Context(), uint(labID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lab not found"})
+	var req struct {
+		Name           string      `json:"name"`
+		Email          string      `json:"email" binding:"omitempty,email"`
+		Role           domain.Role `json:"role" binding:"omitempty,oneof=student instructor admin"`
+		ProfilePicture string      `json:"profile_picture"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	if err := h.LabUsecase.DeleteLab(c.Request.Context(), uint(labID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	user := domain.User{
+		ID:             uint(userID),
+		Name:           req.Name,
+		Email:          req.Email,
+		Role:           req.Role,
+		ProfilePicture: req.ProfilePicture,
+	}
+	if err := h.UserUsecase.UpdateUser(c.Request.Context(), &user, adminID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Lab deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
 
-func (h *Handler) UpdateLabStatus(c *gin.Context) {
-	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
+func (h *Handler) DeleteUser(c *gin.Context) {
+	adminID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
+		respondError(c, err)
 		return
 	}
 
-	var req struct {
-		Status string `json:"status" binding:"required"`
+	idStr := c.Param("id")
+	userID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid user ID"))
+		return
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+
+	if uint(userID) == adminID {
+		respondError(c, domain.NewValidation("cannot_delete_self", "Cannot delete your own account"))
 		return
 	}
 
-	if err := h.LabUsecase.UpdateLabStatus(c.Request.Context(), uint(labID), req.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.UserUsecase.DeleteUser(c.Request.Context(), uint(userID), adminID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Lab status updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
-func (h *Handler) StudentEnrollInLab(c *gin.Context) {
+func (h *Handler) RevokeUserSessions(c *gin.Context) {
 	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
-		return
-	}
-
-	userID, err := getUserID(c)
+	userID, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, invalidParam("id", "Invalid user ID"))
 		return
 	}
 
-	if err := h.LabUsecase.StudentEnroll(c.Request.Context(), userID, uint(labID)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.AuthUsecase.RevokeAllSessions(c.Request.Context(), uint(userID)); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Successfully enrolled in lab"})
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked successfully"})
 }
 
-func (h *Handler) SubmitLabGrade(c *gin.Context) {
-	instructorID, err := getUserID(c)
+func (h *Handler) UnlockUser(c *gin.Context) {
+	idStr := c.Param("id")
+	userID, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, invalidParam("id", "Invalid user ID"))
 		return
 	}
 
-	var req struct {
-		LabID     uint     `json:"lab_id" binding:"required"`
-		StudentID uint     `json:"student_id" binding:"required"`
-		Grade     *float64 `json:"grade"`
-		Feedback  string   `json:"feedback"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+	adminID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	err = h.LabUsecase.SubmitGrade(c.Request.Context(), instructorID, req.StudentID, req.LabID, req.Grade, req.Feedback)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.AuthUsecase.UnlockAccount(c.Request.Context(), uint(userID), adminID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Grade submitted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked successfully"})
 }
 
-func (h *Handler) GetUngradedStudents(c *gin.Context) {
-	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
-		return
-	}
+func (h *Handler) GetLockoutEvents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
 
-	students, err := h.LabUsecase.GetUngradedStudents(c.Request.Context(), uint(labID))
+	events, err := h.AuthUsecase.GetLockoutEvents(c.Request.Context(), limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"students": students,
-		"count":    len(students),
+		"events": events,
+		"count":  len(events),
 	})
 }
 
-func (h *Handler) GetLabStudents(c *gin.Context) {
+// ImpersonateUser - Token "view as" berlaku singkat, tanpa refresh token
+func (h *Handler) ImpersonateUser(c *gin.Context) {
+	adminID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
 	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
+	targetID, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
+		respondError(c, invalidParam("id", "Invalid user ID"))
 		return
 	}
 
-	grades, err := h.LabUsecase.GetLabStudents(c.Request.Context(), uint(labID))
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	result, err := h.AuthUsecase.Impersonate(c.Request.Context(), adminID, uint(targetID), req.Reason, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"students": grades,
-		"count":    len(grades),
+		"access_token": result.Tokens.AccessToken,
+		"token_type":   result.Tokens.TokenType,
+		"expires_in":   result.Tokens.ExpiresIn,
+		"expires_at":   result.ExpiresAt,
+		"user":         result.User,
 	})
 }
 
-func (h *Handler) AddStudentToLab(c *gin.Context) {
-	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
+func (h *Handler) StopImpersonation(c *gin.Context) {
+	sessionID, _ := c.Get("session_id")
+	sid, _ := sessionID.(uint)
+
+	if err := h.AuthUsecase.StopImpersonation(c.Request.Context(), sid); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	var req struct {
-		UserID uint `json:"user_id" binding:"required"`
-	}
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation ended"})
+}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+func (h *Handler) ResetUserMFA(c *gin.Context) {
+	idStr := c.Param("id")
+	userID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid user ID"))
 		return
 	}
 
-	if err := h.LabUsecase.AddStudentToLab(c.Request.Context(), req.UserID, uint(labID)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.AuthUsecase.ResetMFA(c.Request.Context(), uint(userID)); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Student added to lab successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset successfully"})
 }
 
-func (h *Handler) RemoveStudentFromLab(c *gin.Context) {
-	idStr := c.Param("id")
-	labID, err := strconv.ParseUint(idStr, 10, 32)
+func (h *Handler) GetMFAPolicies(c *gin.Context) {
+	policies, err := h.AuthUsecase.GetMFAPolicies(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab ID"})
+		respondError(c, err)
 		return
 	}
 
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+func (h *Handler) UpdateMFAPolicy(c *gin.Context) {
+	adminID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		respondError(c, err)
 		return
 	}
 
-	if err := h.LabUsecase.RemoveStudentFromLab(c.Request.Context(), uint(userID), uint(labID)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Student removed from lab successfully"})
+	role := domain.Role(c.Param("role"))
+	if err := h.AuthUsecase.SetMFAPolicy(c.Request.Context(), role, *req.Required, adminID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "MFA policy updated successfully"})
 }
 
-func (h *Handler) GetCourseStudents(c *gin.Context) {
-	idStr := c.Param("id")
-	courseID, err := strconv.ParseUint(idStr, 10, 32)
+// GetAuditLogs - Filter opsional: actor_id, impersonator_id, action, target_type,
+// target_id, from/to (RFC3339), limit, offset
+func (h *Handler) GetAuditLogs(c *gin.Context) {
+	filter := domain.AuditLogFilter{
+		Action:     domain.AuditAction(c.Query("action")),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+
+	for param, dst := range map[string]**uint{"actor_id": &filter.ActorID, "impersonator_id": &filter.ImpersonatorID} {
+		if s := c.Query(param); s != "" {
+			id, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				respondError(c, invalidParam(param, "Invalid "+param))
+				return
+			}
+			uid := uint(id)
+			*dst = &uid
+		}
+	}
+	for param, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if s := c.Query(param); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				respondError(c, invalidParam(param, "Invalid "+param+", expected RFC3339 timestamp"))
+				return
+			}
+			*dst = &t
+		}
+	}
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	entries, total, err := h.AuditUsecase.GetLogs(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		respondError(c, err)
 		return
 	}
 
-	students, err := h.CourseUsecase.GetCourseStudents(c.Request.Context(), uint(courseID))
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+		"total":   total,
+	})
+}
+
+func (h *Handler) GetAllStudents(c *gin.Context) {
+	students, err := h.UserUsecase.GetUsersByRole(c.Request.Context(), domain.RoleStudent)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -1116,148 +2313,194 @@ func (h *Handler) GetCourseStudents(c *gin.Context) {
 	})
 }
 
-func (h *Handler) GetModuleStudents(c *gin.Context) {
-	moduleID := c.Param("id")
-	courseIDStr := c.Query("course_id")
-	
-	if courseIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "course_id is required"})
+// ========== ORGANIZATIONS ==========
+
+// GetMyOrganization - Organisasi milik admin yang sedang login
+func (h *Handler) GetMyOrganization(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-	
-	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
+
+	org, err := h.OrgUsecase.GetCurrentOrganization(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid course_id"})
+		respondError(c, err)
 		return
 	}
-	
-	students, err := h.CourseUsecase.GetModuleStudents(c.Request.Context(), moduleID, uint(courseID))
+
+	c.JSON(http.StatusOK, gin.H{"organization": org})
+}
+
+func (h *Handler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.OrgUsecase.ListOrganizations(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"students": students,
-		"count":    len(students),
+		"organizations": orgs,
+		"count":         len(orgs),
 	})
 }
 
-func (h *Handler) SearchAllStudents(c *gin.Context) {
-	searchTerm := c.Query("q")
-	if searchTerm == "" {
-		searchTerm = ""
-	}
+type organizationRequest struct {
+	Name       string `json:"name" binding:"required"`
+	Slug       string `json:"slug"`
+	OpenSignup *bool  `json:"open_signup"` // nil = tidak diubah
+}
 
-	students, err := h.LabUsecase.SearchAllStudents(c.Request.Context(), searchTerm)
+func (h *Handler) CreateOrganization(c *gin.Context) {
+	adminID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"students": students,
-		"count":    len(students),
-	})
-}
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
 
-// ========== CERTIFICATE HANDLERS ==========
+	org := domain.Organization{Name: req.Name, Slug: req.Slug, OpenSignup: req.OpenSignup != nil && *req.OpenSignup}
+	if err := h.OrgUsecase.CreateOrganization(c.Request.Context(), &org, adminID); err != nil {
+		respondError(c, err)
+		return
+	}
 
-func (h *Handler) GetUserCertificates(c *gin.Context) {
-	userID, err := getUserID(c)
+	c.JSON(http.StatusCreated, gin.H{"message": "Organization created successfully", "organization": org})
+}
+
+func (h *Handler) GetOrganization(c *gin.Context) {
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, invalidParam("id", "Invalid organization ID"))
 		return
 	}
 
-	certs, err := h.CertUsecase.GetUserCertificates(c.Request.Context(), userID)
+	org, err := h.OrgUsecase.GetOrganization(c.Request.Context(), uint(orgID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"certificates": certs,
-		"count":        len(certs),
-	})
+	c.JSON(http.StatusOK, gin.H{"organization": org})
 }
 
-func (h *Handler) GetPendingCertificates(c *gin.Context) {
-	certs, err := h.CertUsecase.GetPendingCertificates(c.Request.Context())
+func (h *Handler) UpdateOrganization(c *gin.Context) {
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, invalidParam("id", "Invalid organization ID"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"certificates": certs,
-		"count":        len(certs),
-	})
+	adminID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	org := domain.Organization{ID: uint(orgID), Name: req.Name, Slug: req.Slug}
+	if req.OpenSignup != nil {
+		org.OpenSignup = *req.OpenSignup
+	} else {
+		existing, err := h.OrgUsecase.GetOrganization(c.Request.Context(), org.ID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		org.OpenSignup = existing.OpenSignup
+	}
+	if err := h.OrgUsecase.UpdateOrganization(c.Request.Context(), &org, adminID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization updated successfully", "organization": org})
 }
 
-func (h *Handler) ApproveCertificate(c *gin.Context) {
-	approverID, err := getUserID(c)
+func (h *Handler) DeleteOrganization(c *gin.Context) {
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, invalidParam("id", "Invalid organization ID"))
 		return
 	}
 
-	idStr := c.Param("id")
-	certID, err := strconv.ParseUint(idStr, 10, 32)
+	adminID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid certificate ID"})
+		respondError(c, err)
 		return
 	}
 
-	if err := h.CertUsecase.ApproveCertificate(c.Request.Context(), uint(certID), approverID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.OrgUsecase.DeleteOrganization(c.Request.Context(), uint(orgID), adminID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Certificate approved successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
 }
 
-// ========== USER MANAGEMENT (ADMIN) ==========
+func (h *Handler) ListOrganizationMembers(c *gin.Context) {
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid organization ID"))
+		return
+	}
 
-func (h *Handler) GetAllUsers(c *gin.Context) {
-	users, err := h.UserUsecase.GetAllUsers(c.Request.Context())
+	members, err := h.OrgUsecase.ListMembers(c.Request.Context(), uint(orgID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"users": users,
-		"count": len(users),
+		"members": members,
+		"count":   len(members),
 	})
 }
 
-func (h *Handler) CreateUser(c *gin.Context) {
-	var user domain.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, formatValidationErrors(err))
+// SetOrganizationMember - Pindahkan user ke organisasi; role "admin" menjadikannya admin organisasi
+func (h *Handler) SetOrganizationMember(c *gin.Context) {
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("id", "Invalid organization ID"))
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		respondError(c, invalidParam("userId", "Invalid user ID"))
 		return
 	}
 
-	if err := h.UserUsecase.CreateUser(c.Request.Context(), &user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	adminID, err := getUserID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "user": user})
-}
+	var req struct {
+		Role domain.Role `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
 
-func (h *Handler) GetAllStudents(c *gin.Context) {
-	students, err := h.UserUsecase.GetUsersByRole(c.Request.Context(), domain.RoleStudent)
+	user, err := h.OrgUsecase.SetMember(c.Request.Context(), uint(orgID), uint(userID), req.Role, adminID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"students": students,
-		"count":    len(students),
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Organization member updated successfully", "user": user})
 }
 
 // ========== REPORTS ==========
@@ -1265,13 +2508,13 @@ func (h *Handler) GetAllStudents(c *gin.Context) {
 func (h *Handler) GetStudentPerformance(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	performance, err := h.ReportUsecase.GetStudentPerformance(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -1279,14 +2522,21 @@ func (h *Handler) GetStudentPerformance(c *gin.Context) {
 }
 
 func (h *Handler) GetAllStudentsPerformance(c *gin.Context) {
-	performances, err := h.ReportUsecase.GetAllStudentsPerformance(c.Request.Context())
+	q, err := parseListQuery(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	performances, page, err := h.ReportUsecase.ListStudentsPerformance(c.Request.Context(), q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"performances": performances,
 		"count":        len(performances),
+		"pagination":   page,
 	})
 }
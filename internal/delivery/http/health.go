@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck - Dependency yang harus bisa dijangkau agar instance siap menerima traffic
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// dependencyStatus - Detail error hanya dicatat di log karena /readyz tanpa autentikasi
+type dependencyStatus struct {
+	Status    string `json:"status"` // "up" atau "down"
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+type HealthHandler struct {
+	checks   []HealthCheck
+	timeout  time.Duration
+	logger   *slog.Logger
+	draining atomic.Bool
+}
+
+// NewHealthHandler - timeout berlaku untuk tiap dependency, dicek secara paralel
+func NewHealthHandler(timeout time.Duration, logger *slog.Logger, checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks, timeout: timeout, logger: logger}
+}
+
+// SetDraining membuat /readyz gagal selama shutdown agar orchestrator berhenti
+// mengirim request baru, sementara request yang sedang berjalan diselesaikan
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness - Proses masih hidup; sengaja tidak mengecek dependency agar gangguan
+// database tidak membuat semua pod di-restart
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness - Ping semua dependency; 503 jika ada yang gagal atau sedang shutdown
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	results := make(map[string]dependencyStatus, len(h.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			status := dependencyStatus{Status: "up", LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				status.Status = "down"
+				h.logger.WarnContext(ctx, "Readiness check failed", "dependency", check.Name, "error", err)
+			}
+
+			mu.Lock()
+			results[check.Name] = status
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	code, overall := http.StatusOK, "ok"
+	for _, status := range results {
+		if status.Status != "up" {
+			code, overall = http.StatusServiceUnavailable, "unavailable"
+		}
+	}
+	if h.draining.Load() {
+		code, overall = http.StatusServiceUnavailable, "draining"
+	}
+	c.JSON(code, gin.H{
+		"status": overall,
+		"checks": results,
+	})
+}
+
+// InitHealthRouter mendaftarkan /healthz dan /readyz (tanpa autentikasi)
+func InitHealthRouter(r *gin.Engine, h *HealthHandler) {
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+}
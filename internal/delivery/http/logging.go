@@ -0,0 +1,102 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"onlearn-backend/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID - ID dari proxy/klien dipakai ulang hanya jika aman ditulis ke log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,128}$`)
+
+// quietRoutes - Probe dan scrape dicatat di level debug agar tidak membanjiri log
+var quietRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// RequestIDMiddleware memberi setiap request ID korelasi (header X-Request-ID). ID ikut
+// di context sehingga semua log dari handler, usecase dan repository bisa dikaitkan.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Header(requestIDHeader, requestID)
+		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(domain.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+// RequestLogger mencatat satu baris log per request. user_id dan role berasal dari
+// context yang diisi middleware autentikasi, jadi dibaca setelah c.Next().
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case quietRoutes[route]:
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypeAny); len(errs) > 0 {
+			attrs = append(attrs, slog.String("error", errs.String()))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery mengganti recovery bawaan gin: panic dicatat sebagai log terstruktur
+// (beserta request ID) lalu klien menerima 500
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		logger.ErrorContext(c.Request.Context(), "Panic recovered",
+			"panic", recovered,
+			"stack", string(debug.Stack()),
+		)
+		respondError(c, fmt.Errorf("panic: %v", recovered))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"onlearn-backend/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onlearn_http_requests_total",
+		Help: "HTTP requests by route template and status code.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "onlearn_http_request_duration_seconds",
+		Help:    "HTTP request latency by route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// MetricsMiddleware mencatat jumlah dan latency request per route. Label route memakai
+// template gin (mis. /api/v1/courses/:id), bukan path asli, agar jumlah series terbatas.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequestsTotal.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// InitMetricsRouter mendaftarkan GET /metrics (format Prometheus) dari registry default,
+// termasuk metric runtime Go dan proses. Jika token diisi, scraper harus mengirim
+// "Authorization: Bearer <token>".
+func InitMetricsRouter(r *gin.Engine, token string) {
+	handler := promhttp.Handler()
+	r.GET("/metrics", func(c *gin.Context) {
+		if token != "" {
+			provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				respondError(c, domain.NewUnauthorized("invalid_metrics_token", "Invalid metrics token"))
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	})
+}
@@ -1,94 +1,141 @@
 package http
 
 import (
-	"fmt"
+	"context"
 	"net/http"
-	"os"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/utils"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware untuk API (menggunakan Header Authorization)
-func AuthMiddleware(roles ...string) gin.HandlerFunc {
+const (
+	accessTokenCookie  = "token"
+	refreshTokenCookie = "refresh_token"
+	mfaTokenCookie     = "mfa_token"
+	oidcStateCookie    = "oidc_state"
+	impersonatorCookie = "impersonator_refresh_token" // Refresh token admin selama "view as"
+
+	apiKeyHeader = "X-API-Key"
+)
+
+// AuthMiddleware untuk API (menggunakan Header Authorization).
+// Selain JWT, menerima API key lewat header X-API-Key atau "Bearer olk_...".
+// Jika perms diisi, role user harus memiliki semua permission tersebut.
+func AuthMiddleware(auth domain.AuthUsecase, perms ...domain.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader(apiKeyHeader); apiKey != "" {
+			validateAPIKeyAndSetContext(c, auth, apiKey, perms)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			respondError(c, domain.NewUnauthorized("authorization_required", "Authorization header required"))
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth header format"})
+			respondError(c, domain.NewUnauthorized("invalid_authorization_header", "Invalid auth header format"))
 			return
 		}
 		tokenString := parts[1]
 
-		validateTokenAndSetContext(c, tokenString, roles, true)
+		if strings.HasPrefix(tokenString, domain.APIKeyPrefix) {
+			validateAPIKeyAndSetContext(c, auth, tokenString, perms)
+			return
+		}
+
+		validateTokenAndSetContext(c, auth, tokenString, perms, true)
 	}
 }
 
 // WebAuthMiddleware untuk Website (menggunakan Cookie)
-func WebAuthMiddleware(roles ...string) gin.HandlerFunc {
+func WebAuthMiddleware(auth domain.AuthUsecase, perms ...domain.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString, err := c.Cookie("token")
-		if err != nil {
-			// Jika tidak ada cookie, redirect ke halaman login
-			c.Redirect(http.StatusFound, "/?error=Unauthorized")
-			c.Abort()
-			return
+		tokenString, err := c.Cookie(accessTokenCookie)
+		if err != nil || tokenString == "" || !isAccessTokenUsable(c, auth, tokenString) {
+			// Access token habis/dicabut, coba perpanjang dengan refresh token
+			tokenString = refreshWebSession(c, auth)
+			if tokenString == "" && restoreImpersonator(c) {
+				// Impersonasi habis: kembalikan admin ke session miliknya
+				c.Redirect(http.StatusFound, "/admin/dashboard?error=Impersonation+ended")
+				c.Abort()
+				return
+			}
+			if tokenString == "" {
+				// Jika tidak ada cookie, redirect ke halaman login
+				clearAuthCookies(c)
+				c.Redirect(http.StatusFound, "/?error=Unauthorized")
+				c.Abort()
+				return
+			}
 		}
 
-		validateTokenAndSetContext(c, tokenString, roles, false)
+		validateTokenAndSetContext(c, auth, tokenString, perms, false)
 	}
 }
 
-// Helper function untuk validasi token
-func validateTokenAndSetContext(c *gin.Context, tokenString string, roles []string, isAPI bool) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method")
-		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+// isAccessTokenUsable mengecek signature, masa berlaku, dan status session dari access token
+func isAccessTokenUsable(c *gin.Context, auth domain.AuthUsecase, tokenString string) bool {
+	claims, err := utils.ValidateJWT(tokenString)
+	if err != nil {
+		return false
+	}
+	return auth.ValidateSession(c.Request.Context(), claims.SessionID) == nil
+}
+
+// refreshWebSession merotasi refresh token dari cookie dan mengembalikan access token baru
+func refreshWebSession(c *gin.Context, auth domain.AuthUsecase) string {
+	refreshToken, err := c.Cookie(refreshTokenCookie)
+	if err != nil || refreshToken == "" {
+		return ""
+	}
+
+	tokens, err := auth.RefreshToken(c.Request.Context(), refreshToken, clientInfo(c))
+	if err != nil {
+		return ""
+	}
 
-	if err != nil || !token.Valid {
+	setAuthCookies(c, tokens)
+	return tokens.AccessToken
+}
+
+// Helper function untuk validasi token
+func validateTokenAndSetContext(c *gin.Context, auth domain.AuthUsecase, tokenString string, perms []domain.Permission, isAPI bool) {
+	claims, err := utils.ValidateJWT(tokenString)
+	if err != nil {
 		if isAPI {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			respondError(c, domain.NewUnauthorized("invalid_token", "Invalid token"))
 		} else {
+			clearAuthCookies(c)
 			c.Redirect(http.StatusFound, "/?error=Invalid+token")
 			c.Abort()
 		}
 		return
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	// Revocation check: session di server harus masih aktif
+	if err := auth.ValidateSession(c.Request.Context(), claims.SessionID); err != nil {
 		if isAPI {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid claims"})
+			respondError(c, domain.NewUnauthorized("session_expired", "Session expired or revoked"))
 		} else {
-			c.Redirect(http.StatusFound, "/?error=Invalid+claims")
+			clearAuthCookies(c)
+			c.Redirect(http.StatusFound, "/?error=Session+expired")
 			c.Abort()
 		}
 		return
 	}
 
-	userRole := claims["role"].(string)
+	userRole := claims.Role
 
-	// Role Validation
-	if len(roles) > 0 {
-		roleAllowed := false
-		for _, r := range roles {
-			if r == userRole {
-				roleAllowed = true
-				break
-			}
-		}
-		if !roleAllowed {
+	// Permission Validation
+	for _, perm := range perms {
+		if !domain.Role(userRole).Can(perm) {
 			if isAPI {
-				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden access"})
+				respondError(c, &domain.ForbiddenError{Permission: perm})
 			} else {
 				// Redirect ke dashboard masing-masing jika salah role, atau logout
 				c.Redirect(http.StatusFound, "/?error=Forbidden")
@@ -98,9 +145,172 @@ func validateTokenAndSetContext(c *gin.Context, tokenString string, roles []stri
 		}
 	}
 
-	// Simpan user_id dan role ke context
-	userIDFloat := claims["user_id"].(float64)
-	c.Set("user_id", uint(userIDFloat))
+	// Simpan user_id, role, session_id dan status verifikasi ke context
+	c.Set("user_id", claims.UserID)
 	c.Set("role", userRole)
+	c.Set("session_id", claims.SessionID)
+	c.Set("is_verified", claims.Verified)
+
+	// Info klien ikut di context request agar usecase bisa mencatatnya (audit log)
+	ctx := domain.WithClientInfo(c.Request.Context(), clientInfo(c))
+	ctx = domain.WithUser(ctx, claims.UserID, domain.Role(userRole))
+	ctx = withTenant(ctx, c, domain.Role(userRole), claims.OrganizationID)
+	if claims.ImpersonatorID != 0 {
+		c.Set("impersonator_id", claims.ImpersonatorID)
+		ctx = domain.WithImpersonator(ctx, claims.ImpersonatorID)
+	}
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// validateAPIKeyAndSetContext mengisi context dari API key. Permission efektif adalah
+// irisan permission role pemilik dan scope kunci.
+func validateAPIKeyAndSetContext(c *gin.Context, auth domain.AuthUsecase, rawKey string, perms []domain.Permission) {
+	key, err := auth.AuthenticateAPIKey(c.Request.Context(), rawKey, clientInfo(c))
+	if err != nil {
+		respondError(c, domain.NewUnauthorized("invalid_api_key", "Invalid API key"))
+		return
+	}
+
+	for _, perm := range perms {
+		if !key.User.Role.Can(perm) || !key.Scopes.Allows(perm) {
+			respondError(c, &domain.ForbiddenError{Permission: perm})
+			return
+		}
+	}
+
+	c.Set("user_id", key.User.ID)
+	c.Set("role", string(key.User.Role))
+	c.Set("is_verified", key.User.IsVerified)
+	c.Set("api_key_id", key.ID)
+	c.Set("api_key_scopes", key.Scopes)
+
+	ctx := domain.WithClientInfo(c.Request.Context(), clientInfo(c))
+	ctx = domain.WithUser(ctx, key.User.ID, key.User.Role)
+	ctx = withTenant(ctx, c, key.User.Role, key.User.OrgID())
+	c.Request = c.Request.WithContext(domain.WithAPIKeyScopes(ctx, key.Scopes))
 	c.Next()
 }
+
+// withTenant membatasi query repository selama request ke organisasi user.
+// Super admin tidak dibatasi; user lain tanpa organisasi tidak melihat data apa pun.
+func withTenant(ctx context.Context, c *gin.Context, role domain.Role, orgID uint) context.Context {
+	if role == domain.RoleSuperAdmin {
+		return ctx
+	}
+	c.Set("organization_id", orgID)
+	return domain.WithOrganization(ctx, orgID)
+}
+
+// RequirePermission membatasi route untuk role yang memiliki permission tertentu.
+// Untuk request lewat API key, permission juga harus termasuk scope kunci.
+// Kepemilikan resource tetap dicek di usecase lewat domain.Policy.
+func RequirePermission(perm domain.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		roleStr, _ := role.(string)
+		allowed := domain.Role(roleStr).Can(perm)
+		if scopes, ok := domain.APIKeyScopesFromContext(c.Request.Context()); ok && !scopes.Allows(perm) {
+			allowed = false
+		}
+		if !allowed {
+			respondError(c, &domain.ForbiddenError{Permission: perm})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession menolak request lewat API key atau impersonasi, untuk route sensitif
+// yang hanya boleh diakses dari sesi login pemilik akun (mis. membuat API key, 2FA)
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, viaAPIKey := c.Get("api_key_id"); viaAPIKey {
+			respondError(c, domain.NewForbidden("session_required", "This action requires a login session, not an API key"))
+			return
+		}
+		if _, impersonating := c.Get("impersonator_id"); impersonating {
+			respondError(c, domain.NewForbidden("not_allowed_while_impersonating", "This action is not allowed while impersonating"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuditImpersonation mencatat setiap request yang mengubah data selama impersonasi,
+// termasuk aksi yang biasanya tidak masuk audit log (mis. enroll, submit tugas)
+func AuditImpersonation(audit domain.AuditUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if _, ok := domain.ImpersonatorFromContext(c.Request.Context()); !ok {
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+
+		userID, _ := getUserID(c)
+		audit.Record(c.Request.Context(), userID, domain.AuditImpersonationRequest, domain.AuditTargetRequest, c.Request.Method+" "+c.FullPath(), nil, map[string]interface{}{
+			"path":   c.Request.URL.Path,
+			"status": c.Writer.Status(),
+		})
+	}
+}
+
+// RequireVerified membatasi route tertentu hanya untuk akun yang emailnya sudah terverifikasi
+// (berlaku saat policy login akun belum terverifikasi adalah "limited")
+func RequireVerified() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verified, _ := c.Get("is_verified"); verified != true {
+			respondError(c, domain.NewForbidden(domain.ErrEmailNotVerified.Code, "Email not verified. Please verify your email to access this feature."))
+			return
+		}
+		c.Next()
+	}
+}
+
+// setAuthCookies menyimpan access token dan refresh token ke cookie
+func setAuthCookies(c *gin.Context, tokens *domain.AuthTokens) {
+	// Access token tetap bisa dibaca JS karena dipakai untuk header Authorization di template
+	c.SetCookie(accessTokenCookie, tokens.AccessToken, int(utils.RefreshTokenTTL.Seconds()), "/", "", false, false)
+	// RefreshToken kosong: request paralel sudah merotasi token, cookie-nya jangan ditimpa
+	if tokens.RefreshToken != "" {
+		c.SetCookie(refreshTokenCookie, tokens.RefreshToken, int(utils.RefreshTokenTTL.Seconds()), "/", "", false, true)
+	}
+}
+
+// restoreImpersonator mengembalikan refresh token admin yang disimpan saat impersonasi
+// dimulai dari web; false jika tidak sedang impersonasi
+func restoreImpersonator(c *gin.Context) bool {
+	refreshToken, err := c.Cookie(impersonatorCookie)
+	if err != nil || refreshToken == "" {
+		return false
+	}
+	c.SetCookie(accessTokenCookie, "", -1, "/", "", false, false)
+	c.SetCookie(refreshTokenCookie, refreshToken, int(utils.RefreshTokenTTL.Seconds()), "/", "", false, true)
+	c.SetCookie(impersonatorCookie, "", -1, "/", "", false, true)
+	return true
+}
+
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie(accessTokenCookie, "", -1, "/", "", false, false)
+	c.SetCookie(refreshTokenCookie, "", -1, "/", "", false, true)
+}
+
+// setMFACookie menyimpan token challenge 2FA selama login dua langkah berlangsung
+func setMFACookie(c *gin.Context, token string, maxAge int) {
+	c.SetCookie(mfaTokenCookie, token, maxAge, "/", "", false, true)
+}
+
+func clearMFACookie(c *gin.Context) {
+	c.SetCookie(mfaTokenCookie, "", -1, "/", "", false, true)
+}
+
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
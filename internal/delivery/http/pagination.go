@@ -0,0 +1,73 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"onlearn-backend/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseListQuery membaca query param endpoint daftar:
+//
+//	limit, offset, cursor  paginasi; cursor dari pagination.next_cursor menggantikan offset
+//	sort                   nama field, awali "-" untuk descending (mis. sort=-created_at)
+//	from, to               rentang waktu RFC3339
+//	role, published, status, instructor_id  filter; yang tidak relevan untuk endpoint diabaikan
+func parseListQuery(c *gin.Context) (domain.ListQuery, error) {
+	q := domain.ListQuery{
+		Cursor: c.Query("cursor"),
+		Status: c.Query("status"),
+	}
+
+	for param, dst := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		if s := c.Query(param); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return q, invalidParam(param, "Invalid "+param+", expected a non-negative integer")
+			}
+			*dst = n
+		}
+	}
+
+	if s := c.Query("sort"); s != "" {
+		q.Desc = strings.HasPrefix(s, "-")
+		q.Sort = strings.TrimPrefix(s, "-")
+	}
+
+	for param, dst := range map[string]**time.Time{"from": &q.From, "to": &q.To} {
+		if s := c.Query(param); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return q, invalidParam(param, "Invalid "+param+", expected RFC3339 timestamp")
+			}
+			*dst = &t
+		}
+	}
+
+	if s := c.Query("role"); s != "" {
+		q.Role = domain.Role(s)
+		if len(q.Role.Permissions()) == 0 {
+			return q, invalidParam("role", "Invalid role")
+		}
+	}
+	if s := c.Query("published"); s != "" {
+		published, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, invalidParam("published", "Invalid published, expected true or false")
+		}
+		q.Published = &published
+	}
+	if s := c.Query("instructor_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return q, invalidParam("instructor_id", "Invalid instructor_id")
+		}
+		instructorID := uint(id)
+		q.InstructorID = &instructorID
+	}
+
+	return q, nil
+}
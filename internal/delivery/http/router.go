@@ -1,17 +1,32 @@
 package http
 
 import (
+	"onlearn-backend/internal/domain"
+
 	"github.com/gin-gonic/gin"
 )
 
 func InitRouter(handler *Handler) *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
+
+	// Request ID, tracing, access log dan metrics per route; dipasang paling awal agar
+	// request yang ditolak middleware lain ikut tercatat. Tracing di luar access log karena
+	// otelgin mengembalikan context request semula setelah selesai; Recovery paling dalam
+	// supaya panic tercatat sebagai 500 oleh middleware di luarnya.
+	r.Use(
+		RequestIDMiddleware(),
+		TracingMiddleware(),
+		TraceUserMiddleware(),
+		RequestLogger(handler.Logger),
+		MetricsMiddleware(),
+		Recovery(handler.Logger),
+	)
 
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -22,6 +37,9 @@ func InitRouter(handler *Handler) *gin.Engine {
 		c.Next()
 	})
 
+	// Semua request yang mengubah data saat impersonasi ikut dicatat (termasuk route web & file)
+	r.Use(AuditImpersonation(handler.AuditUsecase))
+
 	// Serve static files
 	r.Static("/uploads", "./uploads")
 
@@ -33,23 +51,93 @@ func InitRouter(handler *Handler) *gin.Engine {
 		{
 			auth.POST("/register", handler.Register)
 			auth.POST("/login", handler.Login)
+			auth.POST("/refresh", handler.RefreshToken)
+			auth.POST("/logout", handler.Logout)
 			auth.POST("/forgot-password", handler.ForgotPassword)
+			auth.POST("/reset-password", handler.ResetPassword)
+			auth.POST("/verify", handler.VerifyEmail)
+			auth.POST("/verify/resend", handler.ResendVerification)
+
+			// Login dua langkah (2FA)
+			auth.POST("/mfa/verify", handler.VerifyMFA)
+			auth.POST("/mfa/challenge/setup", handler.SetupMFAChallenge)
+
+			// OpenID Connect (authorization code + PKCE)
+			auth.GET("/oidc/authorize", handler.OIDCAuthorize)
+			auth.POST("/oidc/callback", handler.OIDCCallback)
+
+			// Undangan pendaftaran (token dari link email)
+			auth.GET("/invitations/:token", handler.GetInvitation)
+			auth.POST("/invitations/accept", handler.AcceptInvitation)
+		}
+
+		// ========== TWO-FACTOR SELF-SERVICE ==========
+		mfa := api.Group("/auth/mfa")
+		mfa.Use(AuthMiddleware(handler.AuthUsecase), RequireSession())
+		{
+			mfa.POST("/setup", handler.SetupMFA)
+			mfa.POST("/confirm", handler.ConfirmMFA)
+			mfa.POST("/disable", handler.DisableMFA)
+			mfa.POST("/recovery-codes", handler.RegenerateRecoveryCodes)
+		}
+
+		// ========== IMPERSONATION ==========
+		// Dipanggil dengan token impersonasi untuk mengakhiri "view as" lebih awal
+		impersonation := api.Group("/auth/impersonation")
+		impersonation.Use(AuthMiddleware(handler.AuthUsecase))
+		{
+			impersonation.POST("/stop", handler.StopImpersonation)
+		}
+
+		// ========== PERSONAL API KEYS ==========
+		// Kunci dipakai lewat header X-API-Key atau "Authorization: Bearer olk_..."
+		apiKeys := api.Group("/auth/api-keys")
+		apiKeys.Use(AuthMiddleware(handler.AuthUsecase))
+		{
+			apiKeys.GET("", handler.ListAPIKeys)
+			apiKeys.POST("", RequireSession(), handler.CreateAPIKey)
+			apiKeys.DELETE("/:id", RequireSession(), handler.RevokeAPIKey)
+		}
+
+		// ========== INVITATIONS ==========
+		// Admin mengundang semua role; instructor hanya student (opsional ke course miliknya)
+		invitations := api.Group("/invitations")
+		invitations.Use(AuthMiddleware(handler.AuthUsecase, domain.PermInvitationCreate))
+		{
+			invitations.GET("", handler.ListInvitations)
+			invitations.POST("", handler.CreateInvitation)
+			invitations.DELETE("/:id", handler.RevokeInvitation)
 		}
 
+		// ========== ACCOUNT DATA (EXPORT & DELETION) ==========
+		// Hanya dengan login biasa: API key dan impersonasi tidak boleh mengunduh atau menghapus akun
+		account := api.Group("/account")
+		account.Use(AuthMiddleware(handler.AuthUsecase), RequireSession())
+		{
+			account.GET("/export", handler.ExportAccount)
+			account.GET("/deletion", handler.GetAccountDeletion)
+			account.POST("/deletion", handler.RequestAccountDeletion)
+			account.DELETE("/deletion", handler.CancelAccountDeletion)
+		}
+
+		// ========== SEARCH ==========
+		// Katalog course published dan module-nya untuk semua user yang login
+		api.GET("/search", AuthMiddleware(handler.AuthUsecase), handler.Search)
+
 		// ========== STUDENT ROUTES ==========
 		student := api.Group("/student")
-		student.Use(AuthMiddleware("student"))
+		student.Use(AuthMiddleware(handler.AuthUsecase, domain.PermLearningAccess))
 		{
 			// Dashboard
 			student.GET("/dashboard", handler.GetStudentDashboard)
 
 			// Profile
-			student.PUT("/profile", handler.UpdateProfile)
+			student.PUT("/profile", RequireSession(), handler.UpdateProfile)
 
 			// Courses (Browse & Enroll)
 			student.GET("/courses", handler.GetAllCourses)
 			student.GET("/courses/:id", handler.GetCourseDetail)
-			student.POST("/courses/:id/enroll", handler.EnrollCourse)
+			student.POST("/courses/:id/enroll", RequireVerified(), handler.EnrollCourse)
 
 			// Enrollments (Jalur Pembelajaran)
 			student.GET("/enrollments", handler.GetMyEnrollments)
@@ -59,11 +147,11 @@ func InitRouter(handler *Handler) *gin.Engine {
 			student.GET("/modules/ppt/progress", handler.GetPPTProgress)
 
 			// Assignments
-			student.POST("/assignments/submit", handler.SubmitAssignment)
+			student.POST("/assignments/submit", RequireVerified(), handler.SubmitAssignment)
 
 			// Labs
 			student.GET("/labs", handler.GetAllLabs)
-			student.POST("/labs/:id/enroll", handler.StudentEnrollInLab)
+			student.POST("/labs/:id/enroll", RequireVerified(), handler.StudentEnrollInLab)
 
 			// Certificates
 			student.GET("/certificates", handler.GetUserCertificates)
@@ -74,109 +162,121 @@ func InitRouter(handler *Handler) *gin.Engine {
 
 		// ========== INSTRUCTOR ROUTES ==========
 		instructor := api.Group("/instructor")
-		instructor.Use(AuthMiddleware("instructor", "admin"))
+		instructor.Use(AuthMiddleware(handler.AuthUsecase, domain.PermTeachingAccess))
 		{
 			// Dashboard
 			instructor.GET("/dashboard", handler.GetInstructorDashboard)
 
 			// Profile
-			instructor.PUT("/profile", handler.UpdateProfile)
-
-			// Courses Management
-			instructor.POST("/courses", handler.CreateCourse)
-			instructor.GET("/courses", handler.GetAllCourses)
-			instructor.GET("/courses/:id", handler.GetCourseDetail)
-			instructor.PUT("/courses/:id", handler.UpdateCourse)
-			instructor.DELETE("/courses/:id", handler.DeleteCourse)
-			instructor.POST("/courses/:id/publish", handler.PublishCourse)
-			instructor.POST("/courses/:id/unpublish", handler.UnpublishCourse)
-			
-			// Modules Management
-			instructor.POST("/modules", handler.AddModule)
-			instructor.PUT("/modules/:id", handler.UpdateModule)
-			instructor.DELETE("/modules/:id", handler.DeleteModule)
-			instructor.GET("/courses/:id/students", handler.GetCourseStudents)
-			instructor.GET("/modules/:id/students", handler.GetModuleStudents)
-
-			// Grading
-			instructor.POST("/assignments/grade", handler.GradeAssignment)
-
-			// Labs Management
-			instructor.POST("/labs", handler.CreateLab)
-			instructor.GET("/labs", handler.GetAllLabs)
-			instructor.GET("/labs/:id", handler.GetLabByID)
-			instructor.PUT("/labs/:id", handler.UpdateLab)
-			instructor.PATCH("/labs/:id/status", handler.UpdateLabStatus)
-			instructor.DELETE("/labs/:id", handler.DeleteLab)
-			instructor.POST("/labs/grade", handler.SubmitLabGrade)
-			instructor.GET("/labs/:id/ungraded", handler.GetUngradedStudents)
-			instructor.GET("/labs/:id/students", handler.GetLabStudents)
-			instructor.POST("/labs/:id/students", handler.AddStudentToLab)
-			instructor.DELETE("/labs/:id/students/:user_id", handler.RemoveStudentFromLab)
-
-			// Certificates
-			instructor.GET("/certificates/pending", handler.GetPendingCertificates)
-			instructor.POST("/certificates/:id/approve", handler.ApproveCertificate)
+			instructor.PUT("/profile", RequireSession(), handler.UpdateProfile)
 
 			// Reports
-			instructor.GET("/students/performance", handler.GetAllStudentsPerformance)
-			instructor.GET("/students/search", handler.SearchAllStudents)
+			instructor.GET("/students/performance", RequirePermission(domain.PermStudentView), handler.GetAllStudentsPerformance)
+			instructor.GET("/students/search", RequirePermission(domain.PermStudentView), handler.SearchAllStudents)
 		}
+		registerTeachingRoutes(instructor, handler)
 
 		// ========== ADMIN ROUTES ==========
 		admin := api.Group("/admin")
-		admin.Use(AuthMiddleware("admin"))
+		admin.Use(AuthMiddleware(handler.AuthUsecase, domain.PermAdminAccess))
 		{
 			// Dashboard
 			admin.GET("/dashboard", handler.GetAdminDashboard)
 
 			// User Management (Pendaftaran)
-			admin.GET("/users", handler.GetAllUsers)
-			admin.POST("/users", handler.CreateUser)
-
-			// Inherits all instructor routes
-			admin.POST("/courses", handler.CreateCourse)
-			admin.GET("/courses", handler.GetAllCourses)
-			admin.GET("/courses/:id", handler.GetCourseDetail)
-			admin.PUT("/courses/:id", handler.UpdateCourse)
-			admin.DELETE("/courses/:id", handler.DeleteCourse)
-			admin.POST("/courses/:id/publish", handler.PublishCourse)
-			admin.POST("/courses/:id/unpublish", handler.UnpublishCourse)
-			admin.POST("/modules", handler.AddModule)
-			admin.PUT("/modules/:id", handler.UpdateModule)
-			admin.DELETE("/modules/:id", handler.DeleteModule)
-			admin.POST("/labs", handler.CreateLab)
-			admin.GET("/labs", handler.GetAllLabs)
-			admin.GET("/labs/:id", handler.GetLabByID)
-			admin.PUT("/labs/:id", handler.UpdateLab)
-			admin.PATCH("/labs/:id/status", handler.UpdateLabStatus)
-			admin.DELETE("/labs/:id", handler.DeleteLab)
-			admin.POST("/labs/grade", handler.SubmitLabGrade)
-			admin.GET("/labs/:id/ungraded", handler.GetUngradedStudents)
-			admin.GET("/labs/:id/students", handler.GetLabStudents)
-			admin.POST("/labs/:id/students", handler.AddStudentToLab)
-			admin.DELETE("/labs/:id/students/:user_id", handler.RemoveStudentFromLab)
-			admin.GET("/certificates/pending", handler.GetPendingCertificates)
-			admin.POST("/certificates/:id/approve", handler.ApproveCertificate)
+			admin.GET("/users", RequirePermission(domain.PermUserManage), handler.GetAllUsers)
+			admin.POST("/users", RequirePermission(domain.PermUserManage), handler.CreateUser)
+			admin.POST("/users/import", RequirePermission(domain.PermUserManage), handler.ImportUsers)
+			admin.PUT("/users/:id", RequirePermission(domain.PermUserManage), handler.UpdateUser)
+			admin.DELETE("/users/:id", RequirePermission(domain.PermUserManage), handler.DeleteUser)
+			admin.POST("/users/:id/impersonate", RequirePermission(domain.PermUserImpersonate), RequireSession(), handler.ImpersonateUser)
+
+			// Audit log aksi privileged
+			admin.GET("/audit", RequirePermission(domain.PermAuditView), handler.GetAuditLogs)
+
+			// Organisasi (tenant). Admin organisasi hanya melihat organisasinya sendiri.
+			admin.GET("/organization", handler.GetMyOrganization)
+			orgs := admin.Group("/organizations", RequirePermission(domain.PermOrganizationManage))
+			orgs.GET("", handler.ListOrganizations)
+			orgs.POST("", handler.CreateOrganization)
+			orgs.GET("/:id", handler.GetOrganization)
+			orgs.PUT("/:id", handler.UpdateOrganization)
+			orgs.DELETE("/:id", handler.DeleteOrganization)
+			orgs.GET("/:id/members", handler.ListOrganizationMembers)
+			orgs.PUT("/:id/members/:userId", handler.SetOrganizationMember)
+
+			// Keamanan akun
+			security := admin.Group("", RequirePermission(domain.PermSecurityManage))
+			security.POST("/users/:id/revoke-sessions", handler.RevokeUserSessions)
+			security.POST("/users/:id/unlock", handler.UnlockUser)
+			security.GET("/lockouts", handler.GetLockoutEvents)
+			security.DELETE("/users/:id/mfa", handler.ResetUserMFA)
+
+			// 2FA Policy (wajib per role)
+			security.GET("/mfa/policies", handler.GetMFAPolicies)
+			security.PUT("/mfa/policies/:role", handler.UpdateMFAPolicy)
 		}
+		// Admin memakai route pengelolaan yang sama dengan instructor
+		registerTeachingRoutes(admin, handler)
 	}
 
 	return r
 }
 
+// registerTeachingRoutes mendaftarkan route pengelolaan course, lab, dan sertifikat.
+// Permission di sini hanya gerbang awal; kepemilikan resource dicek di usecase.
+func registerTeachingRoutes(rg *gin.RouterGroup, handler *Handler) {
+	// Courses Management
+	rg.POST("/courses", RequirePermission(domain.PermCourseCreate), handler.CreateCourse)
+	rg.GET("/courses", handler.GetAllCourses)
+	rg.GET("/courses/:id", handler.GetCourseDetail)
+	rg.PUT("/courses/:id", RequirePermission(domain.PermCourseUpdate), handler.UpdateCourse)
+	rg.DELETE("/courses/:id", RequirePermission(domain.PermCourseDelete), handler.DeleteCourse)
+	rg.POST("/courses/:id/publish", RequirePermission(domain.PermCoursePublish), handler.PublishCourse)
+	rg.POST("/courses/:id/unpublish", RequirePermission(domain.PermCoursePublish), handler.UnpublishCourse)
+	rg.GET("/courses/:id/students", RequirePermission(domain.PermCourseViewStudents), handler.GetCourseStudents)
+
+	// Modules Management
+	rg.POST("/modules", RequirePermission(domain.PermModuleManage), handler.AddModule)
+	rg.PUT("/modules/:id", RequirePermission(domain.PermModuleManage), handler.UpdateModule)
+	rg.DELETE("/modules/:id", RequirePermission(domain.PermModuleManage), handler.DeleteModule)
+	rg.GET("/modules/:id/students", RequirePermission(domain.PermCourseViewStudents), handler.GetModuleStudents)
+
+	// Grading
+	rg.POST("/assignments/grade", RequirePermission(domain.PermAssignmentGrade), handler.GradeAssignment)
+
+	// Labs Management
+	rg.POST("/labs", RequirePermission(domain.PermLabCreate), handler.CreateLab)
+	rg.GET("/labs", handler.GetAllLabs)
+	rg.GET("/labs/:id", handler.GetLabByID)
+	rg.PUT("/labs/:id", RequirePermission(domain.PermLabUpdate), handler.UpdateLab)
+	rg.PATCH("/labs/:id/status", RequirePermission(domain.PermLabUpdate), handler.UpdateLabStatus)
+	rg.DELETE("/labs/:id", RequirePermission(domain.PermLabDelete), handler.DeleteLab)
+	rg.POST("/labs/grade", RequirePermission(domain.PermLabGrade), handler.SubmitLabGrade)
+	rg.GET("/labs/:id/ungraded", RequirePermission(domain.PermLabGrade), handler.GetUngradedStudents)
+	rg.GET("/labs/:id/students", RequirePermission(domain.PermLabManageStudents), handler.GetLabStudents)
+	rg.POST("/labs/:id/students", RequirePermission(domain.PermLabManageStudents), handler.AddStudentToLab)
+	rg.DELETE("/labs/:id/students/:user_id", RequirePermission(domain.PermLabManageStudents), handler.RemoveStudentFromLab)
+
+	// Certificates
+	rg.GET("/certificates/pending", RequirePermission(domain.PermCertificateApprove), handler.GetPendingCertificates)
+	rg.POST("/certificates/:id/approve", RequirePermission(domain.PermCertificateApprove), handler.ApproveCertificate)
+	rg.POST("/certificates/:id/reject", RequirePermission(domain.PermCertificateApprove), handler.RejectCertificate)
+}
+
 // InitFileRouter initializes file-related routes for GridFS
-func InitFileRouter(r *gin.Engine, fileHandler *FileHandler) {
+func InitFileRouter(r *gin.Engine, fileHandler *FileHandler, auth domain.AuthUsecase) {
 	// Protected file streaming with enrollment verification
 	api := r.Group("/api/v1")
 	{
 		files := api.Group("/files")
-		files.Use(AuthMiddleware("student", "instructor", "admin"))
+		files.Use(AuthMiddleware(auth))
 		{
 			// Protected file streaming (requires auth and enrollment check)
 			files.GET("/:id/stream", fileHandler.StreamFileProtected)
 			files.GET("/:id/info", fileHandler.GetFileInfo)
-			files.POST("/upload", fileHandler.UploadFile)
-			files.DELETE("/:id", fileHandler.DeleteFile)
+			files.POST("/upload", RequirePermission(domain.PermModuleManage), fileHandler.UploadFile)
+			files.DELETE("/:id", RequirePermission(domain.PermModuleManage), fileHandler.DeleteFile)
 		}
 	}
 
@@ -0,0 +1,30 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware membuat span server per request (otelgin) dan meneruskannya lewat
+// c.Request.Context() ke usecase dan repository. Header traceparent dari pemanggil
+// (gateway/frontend) dihormati sehingga trace tersambung lintas layanan; server.address
+// diambil dari Host header.
+func TracingMiddleware() gin.HandlerFunc {
+	return otelgin.Middleware("")
+}
+
+// TraceUserMiddleware menambahkan enduser.id ke span request setelah middleware auth
+// mengisi user_id. Dipasang tepat setelah TracingMiddleware.
+func TraceUserMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if userID, ok := c.Get("user_id"); ok {
+			if id, ok := userID.(uint); ok {
+				trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.Int64("enduser.id", int64(id)))
+			}
+		}
+	}
+}
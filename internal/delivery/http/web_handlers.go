@@ -1,7 +1,12 @@
 package http
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"onlearn-backend/internal/domain"
 	"onlearn-backend/pkg/utils"
 	"strconv"
@@ -11,11 +16,15 @@ import (
 )
 
 type WebHandler struct {
-	AuthUsecase      domain.AuthUsecase
-	CourseUsecase    domain.CourseUsecase
-	LabUsecase       domain.LabUsecase
-	CertUsecase      domain.CertificateUsecase
-	DashboardUsecase domain.DashboardUsecase
+	AuthUsecase       domain.AuthUsecase
+	CourseUsecase     domain.CourseUsecase
+	LabUsecase        domain.LabUsecase
+	CertUsecase       domain.CertificateUsecase
+	DashboardUsecase  domain.DashboardUsecase
+	OIDCUsecase       domain.OIDCUsecase
+	AccountUsecase    domain.AccountUsecase
+	InvitationUsecase domain.InvitationUsecase
+	Logger            *slog.Logger
 }
 
 func NewWebHandler(
@@ -24,46 +33,52 @@ func NewWebHandler(
 	lu domain.LabUsecase,
 	certu domain.CertificateUsecase,
 	du domain.DashboardUsecase,
+	ou domain.OIDCUsecase,
+	accu domain.AccountUsecase,
+	invu domain.InvitationUsecase,
+	logger *slog.Logger,
 ) *WebHandler {
 	return &WebHandler{
-		AuthUsecase:      au,
-		CourseUsecase:    cu,
-		LabUsecase:       lu,
-		CertUsecase:      certu,
-		DashboardUsecase: du,
+		AuthUsecase:       au,
+		CourseUsecase:     cu,
+		LabUsecase:        lu,
+		CertUsecase:       certu,
+		DashboardUsecase:  du,
+		OIDCUsecase:       ou,
+		AccountUsecase:    accu,
+		InvitationUsecase: invu,
+		Logger:            logger,
 	}
 }
 
 // ========== AUTH PAGES ==========
 
 func (h *WebHandler) ShowLoginPage(c *gin.Context) {
-	token, err := c.Cookie("token")
+	token, err := c.Cookie(accessTokenCookie)
 	if err == nil && token != "" {
 		claims, parseErr := utils.ValidateJWT(token)
-		if parseErr == nil {
-			switch claims.Role {
-			case "instructor":
-				c.Redirect(http.StatusFound, "/instructor/dashboard")
-				return
-			case "admin":
-				c.Redirect(http.StatusFound, "/admin/dashboard")
-				return
-			default:
-				c.Redirect(http.StatusFound, "/student/dashboard")
-				return
-			}
+		if parseErr == nil && h.AuthUsecase.ValidateSession(c.Request.Context(), claims.SessionID) == nil {
+			c.Redirect(http.StatusFound, dashboardPath(claims.Role))
+			return
 		}
 	}
 
 	data := gin.H{
-		"title": "Login | OnLearn",
+		"title":       "Login | OnLearn",
+		"oidcEnabled": h.OIDCUsecase.Enabled(),
 	}
 
 	if err := c.Query("error"); err != "" {
 		data["error"] = err
 	}
 	if reg := c.Query("registered"); reg == "true" {
-		data["success"] = "Akun berhasil dibuat. Silakan login."
+		data["success"] = "Akun berhasil dibuat. Kode verifikasi telah dikirim ke email Anda."
+	}
+	if c.Query("verified") == "true" {
+		data["success"] = "Email berhasil diverifikasi. Silakan login."
+	}
+	if reset := c.Query("reset"); reset == "true" {
+		data["success"] = "Password berhasil diubah. Silakan login dengan password baru."
 	}
 
 	c.HTML(http.StatusOK, "auth/login.html", data)
@@ -83,93 +98,440 @@ func (h *WebHandler) LoginWeb(c *gin.Context) {
 	}
 
 	// Call Usecase
-	token, err := h.AuthUsecase.Login(c.Request.Context(), email, password)
+	result, err := h.AuthUsecase.Login(c.Request.Context(), email, password, clientInfo(c))
 	if err != nil {
+		errMsg := "Email atau password salah."
+		var locked *domain.LoginLockedError
+		if errors.As(err, &locked) {
+			errMsg = fmt.Sprintf("Terlalu banyak percobaan login gagal. Coba lagi dalam %d detik.", locked.RetryAfterSeconds())
+		} else if errors.Is(err, domain.ErrEmailNotVerified) {
+			c.Redirect(http.StatusFound, "/verify-email?email="+url.QueryEscape(email))
+			return
+		}
 		c.HTML(http.StatusOK, "auth/login.html", gin.H{
-			"error": "Email atau password salah.",
+			"error": errMsg,
 			"email": email,
 		})
 		return
 	}
 
+	// 2FA aktif/wajib: lanjut ke halaman kode verifikasi
+	if result.MFARequired {
+		setMFACookie(c, result.MFAToken, result.MFAExpiresIn)
+		c.Redirect(http.StatusFound, "/login/mfa")
+		return
+	}
+
+	h.redirectAfterLogin(c, result.Tokens)
+}
+
+// OIDCLoginWeb mengarahkan browser ke halaman login identity provider (SSO)
+func (h *WebHandler) OIDCLoginWeb(c *gin.Context) {
+	if !h.OIDCUsecase.Enabled() {
+		c.Redirect(http.StatusFound, "/?error=Login+SSO+tidak+tersedia")
+		return
+	}
+
+	req, err := h.OIDCUsecase.BeginLogin(c.Request.Context())
+	if err != nil {
+		c.Redirect(http.StatusFound, "/?error=Login+SSO+sedang+tidak+tersedia")
+		return
+	}
+
+	// State juga disimpan di cookie untuk memastikan callback berasal dari browser yang sama
+	c.SetCookie(oidcStateCookie, req.State, req.ExpiresIn, "/", "", false, true)
+	c.Redirect(http.StatusFound, req.AuthorizationURL)
+}
+
+func (h *WebHandler) OIDCCallbackWeb(c *gin.Context) {
+	expectedState, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/", "", false, true)
+
+	if c.Query("error") != "" {
+		c.Redirect(http.StatusFound, "/?error=Login+SSO+dibatalkan")
+		return
+	}
+
+	state := c.Query("state")
+	if state == "" || expectedState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 {
+		c.Redirect(http.StatusFound, "/?error=Sesi+login+SSO+tidak+valid")
+		return
+	}
+
+	result, err := h.OIDCUsecase.CompleteLogin(c.Request.Context(), state, c.Query("code"), clientInfo(c))
+	if err != nil {
+		errMsg := "Login SSO gagal."
+		var locked *domain.LoginLockedError
+		if errors.As(err, &locked) {
+			errMsg = fmt.Sprintf("Terlalu banyak percobaan login gagal. Coba lagi dalam %d detik.", locked.RetryAfterSeconds())
+		} else if errors.Is(err, domain.ErrNoOIDCAccount) {
+			errMsg = "Belum ada akun OnLearn untuk email ini."
+		} else if errors.Is(err, domain.ErrEmailNotVerified) {
+			errMsg = "Email belum diverifikasi. Silakan cek kode verifikasi di email Anda."
+		} else if errorCode(err) == "registration_invite_only" {
+			errMsg = "Pendaftaran hanya lewat undangan. Hubungi admin untuk mendapatkan undangan."
+		} else if errorCode(err) == "email_domain_not_allowed" {
+			errMsg = "Domain email Anda tidak diizinkan untuk mendaftar."
+		}
+		c.Redirect(http.StatusFound, "/?error="+url.QueryEscape(errMsg))
+		return
+	}
+
+	if result.MFARequired {
+		setMFACookie(c, result.MFAToken, result.MFAExpiresIn)
+		c.Redirect(http.StatusFound, "/login/mfa")
+		return
+	}
+
+	h.redirectAfterLogin(c, result.Tokens)
+}
+
+// redirectAfterLogin menyimpan cookie token lalu redirect ke dashboard sesuai role
+func (h *WebHandler) redirectAfterLogin(c *gin.Context, tokens *domain.AuthTokens) {
 	// Parse token to get user role
-	claims, err := utils.ValidateJWT(token)
+	claims, err := utils.ValidateJWT(tokens.AccessToken)
 	if err != nil {
 		c.HTML(http.StatusOK, "auth/login.html", gin.H{
 			"error": "Gagal memproses token.",
-			"email": email,
 		})
 		return
 	}
 
-	// Set Cookie (MaxAge 24 jam)
-	c.SetCookie("token", token, 86400, "/", "", false, false)
+	// Set Cookie access token + refresh token
+	setAuthCookies(c, tokens)
+
+	c.Redirect(http.StatusFound, dashboardPath(claims.Role))
+}
 
-	// Redirect based on role
-	switch claims.Role {
+func dashboardPath(role string) string {
+	switch role {
 	case "instructor":
-		c.Redirect(http.StatusFound, "/instructor/dashboard")
-	case "admin":
-		c.Redirect(http.StatusFound, "/admin/dashboard")
+		return "/instructor/dashboard"
+	case "admin", "super_admin":
+		return "/admin/dashboard"
 	default:
-		c.Redirect(http.StatusFound, "/student/dashboard")
+		return "/student/dashboard"
+	}
+}
+
+func (h *WebHandler) ShowMFAPage(c *gin.Context) {
+	mfaToken, err := c.Cookie(mfaTokenCookie)
+	if err != nil || mfaToken == "" {
+		c.Redirect(http.StatusFound, "/?error=Sesi+login+telah+berakhir")
+		return
+	}
+
+	// Jika 2FA wajib tapi belum enroll, tampilkan QR code untuk didaftarkan
+	setup, err := h.AuthUsecase.SetupMFAForChallenge(c.Request.Context(), mfaToken)
+	if err != nil {
+		clearMFACookie(c)
+		c.Redirect(http.StatusFound, "/?error=Sesi+login+telah+berakhir")
+		return
 	}
+
+	c.HTML(http.StatusOK, "auth/mfa.html", gin.H{
+		"title": "Verifikasi 2 Langkah | OnLearn",
+		"setup": setup,
+		"error": c.Query("error"),
+	})
+}
+
+func (h *WebHandler) VerifyMFAWeb(c *gin.Context) {
+	mfaToken, err := c.Cookie(mfaTokenCookie)
+	if err != nil || mfaToken == "" {
+		c.Redirect(http.StatusFound, "/?error=Sesi+login+telah+berakhir")
+		return
+	}
+
+	result, err := h.AuthUsecase.VerifyMFA(c.Request.Context(), mfaToken, c.PostForm("code"), clientInfo(c))
+	if err != nil {
+		var locked *domain.LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			clearMFACookie(c)
+			c.Redirect(http.StatusFound, "/?error="+url.QueryEscape(fmt.Sprintf("Terlalu banyak percobaan gagal. Coba lagi dalam %d detik.", locked.RetryAfterSeconds())))
+		case errors.Is(err, domain.ErrInvalidMFACode):
+			c.Redirect(http.StatusFound, "/login/mfa?error="+url.QueryEscape("Kode verifikasi salah."))
+		default:
+			clearMFACookie(c)
+			c.Redirect(http.StatusFound, "/?error=Sesi+login+telah+berakhir")
+		}
+		return
+	}
+
+	clearMFACookie(c)
+
+	// Enrollment baru selesai: tampilkan recovery codes sekali sebelum ke dashboard
+	if len(result.RecoveryCodes) > 0 {
+		claims, err := utils.ValidateJWT(result.Tokens.AccessToken)
+		if err != nil {
+			c.Redirect(http.StatusFound, "/?error=Invalid+token")
+			return
+		}
+		setAuthCookies(c, result.Tokens)
+		c.HTML(http.StatusOK, "auth/recovery_codes.html", gin.H{
+			"title":         "Recovery Codes | OnLearn",
+			"recoveryCodes": result.RecoveryCodes,
+			"nextURL":       dashboardPath(claims.Role),
+		})
+		return
+	}
+
+	h.redirectAfterLogin(c, result.Tokens)
 }
 
 func (h *WebHandler) ShowRegisterPage(c *gin.Context) {
-	if _, err := c.Cookie("token"); err == nil {
+	if _, err := c.Cookie(accessTokenCookie); err == nil {
 		c.Redirect(http.StatusFound, "/student/dashboard")
 		return
 	}
-	c.HTML(http.StatusOK, "auth/register.html", gin.H{"title": "Register | OnLearn"})
+	data := h.registerPageData(c, c.Query("invite"))
+	data["organization"] = c.Query("org")
+	c.HTML(http.StatusOK, "auth/register.html", data)
+}
+
+// registerPageData menyiapkan form daftar: dari undangan (email dikunci) atau pendaftaran publik
+func (h *WebHandler) registerPageData(c *gin.Context, inviteToken string) gin.H {
+	data := gin.H{"title": "Register | OnLearn"}
+	if inviteToken == "" {
+		data["closed"] = h.InvitationUsecase.SignupPolicy().InviteOnly
+		return data
+	}
+
+	invitation, err := h.InvitationUsecase.GetInvitation(c.Request.Context(), inviteToken)
+	if err != nil {
+		data["error"] = "Link undangan tidak valid atau sudah kedaluwarsa."
+		data["closed"] = h.InvitationUsecase.SignupPolicy().InviteOnly
+		return data
+	}
+	data["invite"] = invitation
+	data["inviteToken"] = inviteToken
+	data["email"] = invitation.Email
+	data["name"] = invitation.Name
+	return data
 }
 
 func (h *WebHandler) RegisterWeb(c *gin.Context) {
 	name := c.PostForm("name")
 	email := c.PostForm("email")
 	password := c.PostForm("password")
+	inviteToken := c.PostForm("invite_token")
+	organization := c.PostForm("organization")
 
-	if name == "" || email == "" || password == "" {
-		c.HTML(http.StatusOK, "auth/register.html", gin.H{
-			"error": "Semua kolom wajib diisi.",
-			"title": "Register | OnLearn",
-			"name":  name,
-			"email": email,
-		})
-		return
+	renderError := func(msg string) {
+		data := h.registerPageData(c, inviteToken)
+		if data["error"] == nil {
+			data["error"] = msg
+		}
+		data["name"] = name
+		data["organization"] = organization
+		if data["invite"] == nil {
+			data["email"] = email
+		}
+		c.HTML(http.StatusOK, "auth/register.html", data)
 	}
 
-	user := &domain.User{
-		Name:     name,
-		Email:    email,
-		Password: password,
-		Role:     domain.RoleStudent,
+	if name == "" || password == "" || (inviteToken == "" && email == "") {
+		renderError("Semua kolom wajib diisi.")
+		return
 	}
 
 	// Call Usecase
-	if err := h.AuthUsecase.Register(c.Request.Context(), user); err != nil {
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "duplicate") {
+	var err error
+	if inviteToken != "" {
+		_, err = h.InvitationUsecase.AcceptInvitation(c.Request.Context(), inviteToken, name, password)
+	} else {
+		err = h.InvitationUsecase.SignUp(c.Request.Context(), &domain.User{
+			Name:     name,
+			Email:    email,
+			Password: password,
+		}, organization)
+	}
+	if err != nil {
+		errMsg := errorMessage(err)
+		switch errorCode(err) {
+		case domain.ErrEmailExists.Code:
 			errMsg = "Email sudah terdaftar."
+		case "organization_signup_closed":
+			errMsg = "Organisasi ini hanya bisa diikuti lewat undangan."
 		}
-		c.HTML(http.StatusOK, "auth/register.html", gin.H{
-			"error": errMsg,
-			"title": "Register | OnLearn",
-			"name":  name,
-			"email": email,
-		})
+		renderError(errMsg)
 		return
 	}
 
+	// Pendaftaran mandiri belum terverifikasi; undangan sudah membuktikan kepemilikan email
+	if inviteToken == "" {
+		c.Redirect(http.StatusFound, "/verify-email?registered=true&email="+url.QueryEscape(email))
+		return
+	}
 	c.Redirect(http.StatusFound, "/?registered=true")
 }
 
 func (h *WebHandler) LogoutWeb(c *gin.Context) {
+	// Cabut session di server
+	if refreshToken, err := c.Cookie(refreshTokenCookie); err == nil && refreshToken != "" {
+		_ = h.AuthUsecase.Logout(c.Request.Context(), refreshToken)
+	}
+
+	// Logout saat impersonasi ikut mengakhiri session admin di baliknya
+	if token, err := c.Cookie(accessTokenCookie); err == nil && token != "" {
+		if claims, err := utils.ValidateJWT(token); err == nil && claims.ImpersonatorID != 0 {
+			_ = h.AuthUsecase.StopImpersonation(c.Request.Context(), claims.SessionID)
+		}
+	}
+	if refreshToken, err := c.Cookie(impersonatorCookie); err == nil && refreshToken != "" {
+		_ = h.AuthUsecase.Logout(c.Request.Context(), refreshToken)
+		c.SetCookie(impersonatorCookie, "", -1, "/", "", false, true)
+	}
+
 	// Hapus cookie
-	c.SetCookie("token", "", -1, "/", "", false, false)
+	clearAuthCookies(c)
 	c.Redirect(http.StatusFound, "/")
 }
 
+func (h *WebHandler) ShowForgotPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "auth/forgot_password.html", gin.H{"title": "Lupa Password | OnLearn"})
+}
+
+func (h *WebHandler) ForgotPasswordWeb(c *gin.Context) {
+	email := c.PostForm("email")
+	if email == "" {
+		c.HTML(http.StatusOK, "auth/forgot_password.html", gin.H{
+			"error": "Email wajib diisi.",
+			"title": "Lupa Password | OnLearn",
+		})
+		return
+	}
+
+	h.AuthUsecase.ForgotPassword(c.Request.Context(), email)
+
+	c.HTML(http.StatusOK, "auth/forgot_password.html", gin.H{
+		"success": "Jika email terdaftar, link reset password telah dikirim.",
+		"title":   "Lupa Password | OnLearn",
+	})
+}
+
+func (h *WebHandler) ShowResetPasswordPage(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.Redirect(http.StatusFound, "/forgot-password")
+		return
+	}
+
+	c.HTML(http.StatusOK, "auth/reset_password.html", gin.H{
+		"title": "Reset Password | OnLearn",
+		"token": token,
+	})
+}
+
+func (h *WebHandler) ResetPasswordWeb(c *gin.Context) {
+	token := c.PostForm("token")
+	password := c.PostForm("password")
+	confirm := c.PostForm("password_confirmation")
+
+	data := gin.H{
+		"title": "Reset Password | OnLearn",
+		"token": token,
+	}
+
+	if password == "" || password != confirm {
+		data["error"] = "Password dan konfirmasi password harus sama."
+		c.HTML(http.StatusOK, "auth/reset_password.html", data)
+		return
+	}
+
+	if err := h.AuthUsecase.ResetPassword(c.Request.Context(), token, password); err != nil {
+		data["error"] = "Link reset password tidak valid atau sudah kedaluwarsa."
+		if errorCode(err) == "password_too_short" {
+			data["error"] = "Password minimal 6 karakter."
+		}
+		c.HTML(http.StatusOK, "auth/reset_password.html", data)
+		return
+	}
+
+	clearAuthCookies(c)
+	c.Redirect(http.StatusFound, "/?reset=true")
+}
+
+func (h *WebHandler) ShowVerifyEmailPage(c *gin.Context) {
+	data := gin.H{
+		"title": "Verifikasi Email | OnLearn",
+		"email": c.Query("email"),
+	}
+	if c.Query("registered") == "true" {
+		data["success"] = "Akun berhasil dibuat. Kode verifikasi telah dikirim ke email Anda."
+	}
+	c.HTML(http.StatusOK, "auth/verify_email.html", data)
+}
+
+func (h *WebHandler) VerifyEmailWeb(c *gin.Context) {
+	email := c.PostForm("email")
+	code := strings.TrimSpace(c.PostForm("code"))
+
+	data := gin.H{
+		"title": "Verifikasi Email | OnLearn",
+		"email": email,
+	}
+
+	if email == "" || code == "" {
+		data["error"] = "Email dan kode verifikasi wajib diisi."
+		c.HTML(http.StatusOK, "auth/verify_email.html", data)
+		return
+	}
+
+	if err := h.AuthUsecase.VerifyEmail(c.Request.Context(), email, code); err != nil {
+		data["error"] = verificationErrorMessage(err)
+		c.HTML(http.StatusOK, "auth/verify_email.html", data)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/?verified=true")
+}
+
+func (h *WebHandler) ResendVerificationWeb(c *gin.Context) {
+	email := c.PostForm("email")
+
+	data := gin.H{
+		"title": "Verifikasi Email | OnLearn",
+		"email": email,
+	}
+
+	if email == "" {
+		data["error"] = "Email wajib diisi."
+		c.HTML(http.StatusOK, "auth/verify_email.html", data)
+		return
+	}
+
+	if err := h.AuthUsecase.ResendVerification(c.Request.Context(), email); err != nil {
+		data["error"] = verificationErrorMessage(err)
+		c.HTML(http.StatusOK, "auth/verify_email.html", data)
+		return
+	}
+
+	data["success"] = "Jika email terdaftar dan belum diverifikasi, kode baru telah dikirim."
+	c.HTML(http.StatusOK, "auth/verify_email.html", data)
+}
+
+// verificationErrorMessage menerjemahkan error verifikasi email untuk halaman web
+func verificationErrorMessage(err error) string {
+	switch errorCode(err) {
+	case "verification_code_expired":
+		return "Kode verifikasi sudah kedaluwarsa. Silakan kirim ulang kode."
+	case "verification_attempts_exceeded":
+		return "Terlalu banyak percobaan. Silakan kirim ulang kode."
+	case "verification_locked":
+		return "Terlalu banyak kode salah. Coba lagi nanti."
+	case "verification_resend_too_soon":
+		return "Tunggu sebentar sebelum meminta kode baru."
+	case "verification_email_failed":
+		return "Gagal mengirim kode verifikasi. Coba lagi nanti."
+	}
+	if errors.Is(err, domain.ErrValidation) {
+		return "Kode verifikasi salah."
+	}
+	return "Terjadi kesalahan. Coba lagi nanti."
+}
+
 // ========== STUDENT HANDLERS ==========
 
 func (h *WebHandler) StudentDashboard(c *gin.Context) {
@@ -418,7 +780,8 @@ func (h *WebHandler) InstructorDashboard(c *gin.Context) {
 
 	dashboardData, err := h.DashboardUsecase.GetInstructorDashboard(c.Request.Context(), userID)
 	if err != nil {
-		c.HTML(http.StatusOK, "instructor/dashboard.html", gin.H{"error": err.Error(), "User": user})
+		c.Error(err)
+		c.HTML(http.StatusOK, "instructor/dashboard.html", gin.H{"error": errorMessage(err), "User": user})
 		return
 	}
 
@@ -702,12 +1065,57 @@ func (h *WebHandler) InstructorStudents(c *gin.Context) {
 func (h *WebHandler) AdminDashboard(c *gin.Context) {
 	dashboardData, err := h.DashboardUsecase.GetAdminDashboard(c.Request.Context())
 	if err != nil {
-		c.HTML(http.StatusOK, "admin_dashboard.html", gin.H{"error": err.Error()})
+		c.Error(err)
+		c.HTML(http.StatusOK, "admin_dashboard.html", gin.H{"error": errorMessage(err)})
 		return
 	}
 
 	c.HTML(http.StatusOK, "admin_dashboard.html", dashboardData)
 }
+
+// ImpersonateWeb memulai "view as" dari web. Refresh token admin disimpan di cookie
+// terpisah agar bisa kembali saat impersonasi dihentikan atau habis.
+func (h *WebHandler) ImpersonateWeb(c *gin.Context) {
+	adminIDVal, _ := c.Get("user_id")
+	adminID, _ := adminIDVal.(uint)
+
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/admin/dashboard?error=Invalid+user+ID")
+		return
+	}
+
+	result, err := h.AuthUsecase.Impersonate(c.Request.Context(), adminID, uint(targetID), c.PostForm("reason"), clientInfo(c))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/admin/dashboard?error="+url.QueryEscape(errorMessage(err)))
+		return
+	}
+
+	if refreshToken, err := c.Cookie(refreshTokenCookie); err == nil && refreshToken != "" {
+		c.SetCookie(impersonatorCookie, refreshToken, int(utils.RefreshTokenTTL.Seconds()), "/", "", false, true)
+	}
+	c.SetCookie(accessTokenCookie, result.Tokens.AccessToken, result.Tokens.ExpiresIn, "/", "", false, false)
+	c.SetCookie(refreshTokenCookie, "", -1, "/", "", false, true)
+
+	c.Redirect(http.StatusFound, dashboardPath(string(result.User.Role)))
+}
+
+// StopImpersonationWeb mengakhiri "view as" dan mengembalikan admin ke session miliknya
+func (h *WebHandler) StopImpersonationWeb(c *gin.Context) {
+	sessionID, _ := c.Get("session_id")
+	sid, _ := sessionID.(uint)
+	if err := h.AuthUsecase.StopImpersonation(c.Request.Context(), sid); err != nil {
+		c.Redirect(http.StatusFound, "/?error="+url.QueryEscape(errorMessage(err)))
+		return
+	}
+
+	if !restoreImpersonator(c) {
+		clearAuthCookies(c)
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/dashboard")
+}
 func (h *WebHandler) StudentBrowseCourses(c *gin.Context) {
 	userIDVal, exists := c.Get("user_id")
 	if !exists {
@@ -746,11 +1154,23 @@ func (h *WebHandler) StudentBrowseCourses(c *gin.Context) {
 	data := gin.H{
 		"User":       dashboardData.User,
 		"Courses":    availableCourses,
+		"Enrolled":   enrolledCourseIDs,
 		"ActiveMenu": "browse",
 		"Title":      "Browse Kursus",
 		"PageTitle":  "Browse Kursus",
 	}
 
+	// Pencarian katalog (?q=); hasil ditampilkan menggantikan daftar course
+	if query := strings.TrimSpace(c.Query("q")); query != "" {
+		data["Query"] = query
+		results, err := h.CourseUsecase.Search(c.Request.Context(), query, 0)
+		if err != nil {
+			data["SearchError"] = errorMessage(err)
+		} else {
+			data["Search"] = results
+		}
+	}
+
 	c.HTML(http.StatusOK, "student/browse_courses.html", data)
 }
 
@@ -868,11 +1288,13 @@ func (h *WebHandler) StudentProfile(c *gin.Context) {
 	}
 
 	completedLabGrades, _ := h.LabUsecase.GetCompletedLabsByUserID(c.Request.Context(), userID)
+	deletion, _ := h.AccountUsecase.GetDeletion(c.Request.Context(), userID)
 
 	data := gin.H{
 		"User":          dashboardData.User,
 		"Enrollments":   enrollments,
 		"CompletedLabs": completedLabGrades,
+		"Deletion":      deletion,
 		"Title":         "Profile",
 		"PageTitle":     "Profile",
 		"ActiveMenu":    "profile",
@@ -906,10 +1328,17 @@ func (h *WebHandler) StudentProfileEdit(c *gin.Context) {
 		name := c.PostForm("name")
 		password := c.PostForm("password")
 
+		if password != "" {
+			if err := h.AuthUsecase.ChangePassword(c.Request.Context(), userID, c.PostForm("current_password"), password); err != nil {
+				data["error"] = errorMessage(err)
+				c.HTML(http.StatusOK, "student/profile_edit.html", data)
+				return
+			}
+		}
+
 		user := &domain.User{
-			ID:       userID,
-			Name:     name,
-			Password: password,
+			ID:   userID,
+			Name: name,
 		}
 
 		filePath, err := utils.HandleUpload(c, "profile_picture")
@@ -918,7 +1347,7 @@ func (h *WebHandler) StudentProfileEdit(c *gin.Context) {
 		}
 
 		if err := h.AuthUsecase.UpdateUser(c.Request.Context(), user); err != nil {
-			data["error"] = err.Error()
+			data["error"] = errorMessage(err)
 			c.HTML(http.StatusOK, "student/profile_edit.html", data)
 			return
 		}
@@ -929,3 +1358,69 @@ func (h *WebHandler) StudentProfileEdit(c *gin.Context) {
 
 	c.HTML(http.StatusOK, "student/profile_edit.html", data)
 }
+
+// ========== ACCOUNT DATA (EXPORT & DELETION) ==========
+
+// webAccountUserID mengembalikan user yang boleh mengelola data akunnya sendiri;
+// admin yang sedang impersonasi tidak boleh mengunduh atau menghapus akun
+func webAccountUserID(c *gin.Context) (uint, bool) {
+	if _, impersonating := c.Get("impersonator_id"); impersonating {
+		c.Redirect(http.StatusFound, "/student/profile?error="+url.QueryEscape("This action is not allowed while impersonating"))
+		return 0, false
+	}
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.Redirect(http.StatusFound, "/?error=Unauthorized")
+		return 0, false
+	}
+	return userIDVal.(uint), true
+}
+
+func (h *WebHandler) ExportAccountWeb(c *gin.Context) {
+	userID, ok := webAccountUserID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.AccountUsecase.GetExport(c.Request.Context(), userID)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/student/profile?error="+url.QueryEscape(errorMessage(err)))
+		return
+	}
+
+	writeAccountExport(c, h.AccountUsecase, h.Logger, export)
+}
+
+func (h *WebHandler) RequestAccountDeletionWeb(c *gin.Context) {
+	userID, ok := webAccountUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.AuthUsecase.GetUserByID(c.Request.Context(), userID)
+	if err != nil || !strings.EqualFold(user.Email, c.PostForm("confirm_email")) {
+		c.Redirect(http.StatusFound, "/student/profile?error="+url.QueryEscape("Email konfirmasi tidak cocok"))
+		return
+	}
+
+	if _, err := h.AccountUsecase.RequestDeletion(c.Request.Context(), userID, c.PostForm("reason")); err != nil {
+		c.Redirect(http.StatusFound, "/student/profile?error="+url.QueryEscape(errorMessage(err)))
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/student/profile?success="+url.QueryEscape("Penghapusan akun dijadwalkan"))
+}
+
+func (h *WebHandler) CancelAccountDeletionWeb(c *gin.Context) {
+	userID, ok := webAccountUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.AccountUsecase.CancelDeletion(c.Request.Context(), userID); err != nil {
+		c.Redirect(http.StatusFound, "/student/profile?error="+url.QueryEscape(errorMessage(err)))
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/student/profile?success="+url.QueryEscape("Penghapusan akun dibatalkan"))
+}
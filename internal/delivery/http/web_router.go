@@ -198,6 +198,10 @@ func InitWebRouter(router *gin.Engine, webHandler *WebHandler) {
 		"hasPrefix": func(s, prefix string) bool {
 			return strings.HasPrefix(s, prefix)
 		},
+		// HTML hasil search.Highlighter: sudah di-escape, hanya berisi tag <mark>
+		"highlight": func(s string) template.HTML {
+			return template.HTML(s)
+		},
 		"thumbnailURL": func(thumb string) string {
 			if thumb == "" {
 				return ""
@@ -220,15 +224,28 @@ func InitWebRouter(router *gin.Engine, webHandler *WebHandler) {
 		// Public Routes (Login/Register)
 		web.GET("/", webHandler.ShowLoginPage)
 		web.POST("/login", webHandler.LoginWeb)
+		web.GET("/login/mfa", webHandler.ShowMFAPage)
+		web.POST("/login/mfa", webHandler.VerifyMFAWeb)
+		web.GET("/auth/oidc/login", webHandler.OIDCLoginWeb)
+		web.GET("/auth/oidc/callback", webHandler.OIDCCallbackWeb)
 
 		web.GET("/register", webHandler.ShowRegisterPage)
 		web.POST("/register", webHandler.RegisterWeb)
 
 		web.GET("/logout", webHandler.LogoutWeb)
+		web.POST("/impersonation/stop", WebAuthMiddleware(webHandler.AuthUsecase), webHandler.StopImpersonationWeb)
+
+		web.GET("/forgot-password", webHandler.ShowForgotPasswordPage)
+		web.POST("/forgot-password", webHandler.ForgotPasswordWeb)
+		web.GET("/reset-password", webHandler.ShowResetPasswordPage)
+		web.POST("/reset-password", webHandler.ResetPasswordWeb)
+		web.GET("/verify-email", webHandler.ShowVerifyEmailPage)
+		web.POST("/verify-email", webHandler.VerifyEmailWeb)
+		web.POST("/verify-email/resend", webHandler.ResendVerificationWeb)
 
 		// Student Routes
 		student := web.Group("/student")
-		student.Use(WebAuthMiddleware(string(domain.RoleStudent)))
+		student.Use(WebAuthMiddleware(webHandler.AuthUsecase, domain.PermLearningAccess))
 		{
 			student.GET("/dashboard", webHandler.StudentDashboard)
 			student.GET("/courses", webHandler.StudentCourses)
@@ -240,11 +257,14 @@ func InitWebRouter(router *gin.Engine, webHandler *WebHandler) {
 			student.GET("/profile", webHandler.StudentProfile)
 			student.GET("/profile/edit", webHandler.StudentProfileEdit)
 			student.POST("/profile/edit", webHandler.StudentProfileEdit)
+			student.GET("/account/export", webHandler.ExportAccountWeb)
+			student.POST("/account/deletion", webHandler.RequestAccountDeletionWeb)
+			student.POST("/account/deletion/cancel", webHandler.CancelAccountDeletionWeb)
 		}
 
 		// Instructor Routes
 		instructor := web.Group("/instructor")
-		instructor.Use(WebAuthMiddleware(string(domain.RoleInstructor)))
+		instructor.Use(WebAuthMiddleware(webHandler.AuthUsecase, domain.PermTeachingAccess))
 		{
 			instructor.GET("/dashboard", webHandler.InstructorDashboard)
 			instructor.GET("/courses", webHandler.InstructorAllCourses)
@@ -256,9 +276,10 @@ func InitWebRouter(router *gin.Engine, webHandler *WebHandler) {
 
 		// Admin Routes
 		admin := web.Group("/admin")
-		admin.Use(WebAuthMiddleware(string(domain.RoleAdmin)))
+		admin.Use(WebAuthMiddleware(webHandler.AuthUsecase, domain.PermAdminAccess))
 		{
 			admin.GET("/dashboard", webHandler.AdminDashboard)
+			admin.POST("/impersonate/:id", RequirePermission(domain.PermUserImpersonate), webHandler.ImpersonateWeb)
 		}
 	}
 }
@@ -0,0 +1,90 @@
+package domain
+
+import "context"
+
+type contextKey int
+
+const (
+	clientInfoKey contextKey = iota
+	apiKeyScopesKey
+	impersonatorKey
+	organizationKey
+	requestIDKey
+	userKey
+)
+
+// WithClientInfo menyimpan info klien (IP, user agent) ke context request
+// agar usecase bisa mencatatnya tanpa bergantung pada layer HTTP
+func WithClientInfo(ctx context.Context, client ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey, client)
+}
+
+// ClientInfoFromContext mengembalikan info klien; kosong jika tidak ada
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	client, _ := ctx.Value(clientInfoKey).(ClientInfo)
+	return client
+}
+
+// WithAPIKeyScopes menandai request yang diautentikasi dengan API key; policy
+// hanya mengizinkan permission yang termasuk scope kunci
+func WithAPIKeyScopes(ctx context.Context, scopes APIKeyScopes) context.Context {
+	return context.WithValue(ctx, apiKeyScopesKey, scopes)
+}
+
+// APIKeyScopesFromContext mengembalikan scope API key; ok false jika request memakai JWT
+func APIKeyScopesFromContext(ctx context.Context) (APIKeyScopes, bool) {
+	scopes, ok := ctx.Value(apiKeyScopesKey).(APIKeyScopes)
+	return scopes, ok
+}
+
+// WithImpersonator menandai request yang dilakukan admin atas nama user lain
+func WithImpersonator(ctx context.Context, adminID uint) context.Context {
+	return context.WithValue(ctx, impersonatorKey, adminID)
+}
+
+// ImpersonatorFromContext mengembalikan ID admin yang sedang impersonasi; ok false jika tidak
+func ImpersonatorFromContext(ctx context.Context) (uint, bool) {
+	adminID, ok := ctx.Value(impersonatorKey).(uint)
+	return adminID, ok && adminID != 0
+}
+
+// WithOrganization membatasi semua query repository ke satu organisasi (tenant).
+// Request user biasa selalu dibatasi; super admin dan job sistem berjalan tanpa batas.
+func WithOrganization(ctx context.Context, orgID uint) context.Context {
+	return context.WithValue(ctx, organizationKey, orgID)
+}
+
+// OrganizationFromContext mengembalikan organisasi pembatas query; ok false jika tidak dibatasi
+func OrganizationFromContext(ctx context.Context) (uint, bool) {
+	orgID, ok := ctx.Value(organizationKey).(uint)
+	return orgID, ok
+}
+
+// WithRequestID menyimpan ID korelasi request (header X-Request-ID) untuk log
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext mengembalikan ID request; kosong di luar request HTTP
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// RequestUser - User yang terautentikasi pada request ini
+type RequestUser struct {
+	ID   uint
+	Role Role
+}
+
+// WithUser menyimpan user yang terautentikasi agar log dari usecase/repository
+// bisa dikaitkan ke user tanpa meneruskan ID secara eksplisit
+func WithUser(ctx context.Context, userID uint, role Role) context.Context {
+	return context.WithValue(ctx, userKey, RequestUser{ID: userID, Role: role})
+}
+
+// UserFromContext mengembalikan user request; ok false jika request tidak terautentikasi
+func UserFromContext(ctx context.Context) (RequestUser, bool) {
+	user, ok := ctx.Value(userKey).(RequestUser)
+	return user, ok
+}
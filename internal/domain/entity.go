@@ -1,6 +1,10 @@
 package domain
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 )
 
@@ -9,7 +13,37 @@ type Role string
 const (
 	RoleStudent    Role = "student"
 	RoleInstructor Role = "instructor"
-	RoleAdmin      Role = "admin"
+	RoleAdmin      Role = "admin"       // Admin satu organisasi
+	RoleSuperAdmin Role = "super_admin" // Admin platform, lintas organisasi
+)
+
+// IsAdmin - Admin organisasi atau super admin
+func (r Role) IsAdmin() bool {
+	return r == RoleAdmin || r == RoleSuperAdmin
+}
+
+// DefaultOrganizationSlug - Organisasi untuk data lama dan pendaftaran publik tanpa organisasi
+const DefaultOrganizationSlug = "default"
+
+// Organization - Sekolah/tenant. Setiap user (kecuali super admin), course, lab dan
+// module milik tepat satu organisasi; keanggotaan user disimpan di User.OrganizationID.
+type Organization struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	Slug      string    `json:"slug" gorm:"type:varchar(64);uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Pendaftaran publik boleh masuk ke organisasi ini; jika tidak, hanya lewat undangan
+	OpenSignup bool `json:"open_signup" gorm:"not null;default:false"`
+}
+
+// UnverifiedLoginPolicy - Perlakuan login untuk akun yang emailnya belum diverifikasi
+type UnverifiedLoginPolicy string
+
+const (
+	UnverifiedLoginBlock   UnverifiedLoginPolicy = "block"   // Login ditolak
+	UnverifiedLoginLimited UnverifiedLoginPolicy = "limited" // Login boleh, tapi akses terbatas
 )
 
 type User struct {
@@ -20,8 +54,20 @@ type User struct {
 	Role           Role      `json:"role" gorm:"type:varchar(20);default:'student'"`
 	IsVerified     bool      `json:"is_verified" gorm:"default:false"`
 	ProfilePicture string    `json:"profile_picture"`
+	Locale         string    `json:"locale" gorm:"type:varchar(5);default:'id'"` // Bahasa email: "id" atau "en"
 	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Organisasi user; nil hanya untuk super admin
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+}
+
+// OrgID mengembalikan ID organisasi user, 0 jika tidak punya (super admin)
+func (u *User) OrgID() uint {
+	if u.OrganizationID == nil {
+		return 0
+	}
+	return *u.OrganizationID
 }
 
 type Course struct {
@@ -34,6 +80,8 @@ type Course struct {
 	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
 	// Relations
 	Instructor User `json:"instructor,omitempty" gorm:"foreignKey:InstructorID"`
 }
@@ -47,6 +95,11 @@ type Lab struct {
 	Status      string    `json:"status" gorm:"type:varchar(20);default:'scheduled'"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Pemilik lab. Lab lama diisi migrasi 0008; lab tanpa pemilik hanya bisa dikelola admin.
+	InstructorID *uint `json:"instructor_id" gorm:"index"`
+
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
 }
 
 // Enrollment - Student mendaftar ke Course
@@ -128,6 +181,426 @@ type Certificate struct {
 	Approver *User   `json:"approver,omitempty" gorm:"foreignKey:ApprovedBy"`
 }
 
+// Session - Sesi login yang menyimpan refresh token (hash) di server
+type Session struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	RefreshTokenHash  string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	PreviousTokenHash string     `json:"-" gorm:"type:varchar(64);index"` // Untuk deteksi refresh token reuse
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `json:"ip_address" gorm:"type:varchar(45)"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ImpersonatorID    *uint      `json:"impersonator_id,omitempty" gorm:"index"` // Diisi jika session dibuat admin untuk "view as"
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// EmailVerification - Kode verifikasi email (disimpan dalam bentuk hash)
+type EmailVerification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"type:varchar(64);not null"`
+	Attempts  int        `json:"attempts" gorm:"default:0"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// PasswordReset - Token reset password sekali pakai (disimpan dalam bentuk hash)
+type PasswordReset struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// LockoutScope - Cakupan penghitung login gagal
+type LockoutScope string
+
+const (
+	LockoutScopeAccount LockoutScope = "account" // Per email yang dicoba
+	LockoutScopeIP      LockoutScope = "ip"      // Per alamat IP
+
+	// LockoutScopeVerification - Salah kode verifikasi email per user (identifier = ID user);
+	// tidak direset saat kode dikirim ulang
+	LockoutScopeVerification LockoutScope = "verification"
+)
+
+// LoginThrottle - Penghitung percobaan login gagal per akun atau per IP
+type LoginThrottle struct {
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	Scope        LockoutScope `json:"scope" gorm:"type:varchar(20);not null;uniqueIndex:idx_login_throttle_identifier"`
+	Identifier   string       `json:"identifier" gorm:"not null;uniqueIndex:idx_login_throttle_identifier"`
+	FailedCount  int          `json:"failed_count" gorm:"default:0"`
+	LastFailedAt time.Time    `json:"last_failed_at"`
+	LockedUntil  *time.Time   `json:"locked_until,omitempty"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// LockoutEvent - Catatan penguncian login dan pembukaan kunci oleh admin
+type LockoutEvent struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Action      string       `json:"action" gorm:"type:varchar(20);not null;index"` // "locked" atau "unlocked"
+	Scope       LockoutScope `json:"scope" gorm:"type:varchar(20);not null"`
+	Identifier  string       `json:"identifier" gorm:"not null;index"`
+	UserID      *uint        `json:"user_id,omitempty" gorm:"index"`
+	IPAddress   string       `json:"ip_address"`
+	UserAgent   string       `json:"user_agent"`
+	FailedCount int          `json:"failed_count"`
+	LockedUntil *time.Time   `json:"locked_until,omitempty"`
+	ActorID     *uint        `json:"actor_id,omitempty"` // Admin yang membuka kunci
+	CreatedAt   time.Time    `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+const (
+	LockoutActionLocked   = "locked"
+	LockoutActionUnlocked = "unlocked"
+)
+
+// UserMFA - Konfigurasi TOTP (2FA) milik user
+type UserMFA struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"uniqueIndex;not null"`
+	Secret       string     `json:"-" gorm:"type:varchar(64);not null"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"` // nil = enrollment belum dikonfirmasi
+	LastUsedStep int64      `json:"-"`                      // Mencegah kode TOTP yang sama dipakai ulang
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// MFARecoveryCode - Kode cadangan sekali pakai jika perangkat authenticator hilang
+type MFARecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// MFAChallengePurpose - Tujuan challenge MFA setelah password benar
+type MFAChallengePurpose string
+
+const (
+	MFAChallengeVerify MFAChallengePurpose = "verify" // User sudah enroll, cukup masukkan kode
+	MFAChallengeEnroll MFAChallengePurpose = "enroll" // 2FA wajib untuk role ini tapi user belum enroll
+)
+
+// MFAChallenge - Login yang menunggu kode 2FA (token disimpan dalam bentuk hash)
+type MFAChallenge struct {
+	ID        uint                `json:"id" gorm:"primaryKey"`
+	UserID    uint                `json:"user_id" gorm:"not null;index"`
+	TokenHash string              `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Purpose   MFAChallengePurpose `json:"purpose" gorm:"type:varchar(20);not null"`
+	Attempts  int                 `json:"attempts" gorm:"default:0"`
+	ExpiresAt time.Time           `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time          `json:"used_at,omitempty"`
+	CreatedAt time.Time           `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// MFAPolicy - Pengaturan wajib 2FA per role
+type MFAPolicy struct {
+	Role      Role      `json:"role" gorm:"type:varchar(20);primaryKey"`
+	Required  bool      `json:"required" gorm:"default:false"`
+	UpdatedBy uint      `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// UserIdentity - Akun eksternal (OpenID Connect) yang terhubung ke user
+type UserIdentity struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	Provider    string    `json:"provider" gorm:"not null;uniqueIndex:idx_identity_provider_subject"` // Issuer URL
+	Subject     string    `json:"subject" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`  // Claim "sub"
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// OIDCLoginState - State login OIDC yang sedang berjalan (authorization code + PKCE)
+type OIDCLoginState struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	StateHash    string    `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Nonce        string    `json:"-" gorm:"not null"`
+	CodeVerifier string    `json:"-" gorm:"not null"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// APIKeyPrefix menandai token sebagai API key (bukan JWT) di header Authorization
+const APIKeyPrefix = "olk_"
+
+// APIKey - Kunci API personal untuk integrasi/script. Hanya hash yang disimpan;
+// prefix boleh ditampilkan ulang untuk mengenali kunci.
+type APIKey struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	UserID     uint         `json:"user_id" gorm:"not null;index"`
+	Name       string       `json:"name" gorm:"not null"`
+	Prefix     string       `json:"prefix" gorm:"type:varchar(16);not null"`
+	KeyHash    string       `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Scopes     APIKeyScopes `json:"scopes" gorm:"type:jsonb"`
+	ExpiresAt  *time.Time   `json:"expires_at,omitempty"` // nil = tidak kedaluwarsa
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	LastUsedIP string       `json:"last_used_ip,omitempty" gorm:"type:varchar(45)"`
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relations
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// IsActive - Kunci belum dicabut dan belum kedaluwarsa
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyScopes - Daftar permission yang boleh dipakai kunci, disimpan sebagai jsonb
+type APIKeyScopes []Permission
+
+// Allows mengecek apakah permission termasuk scope kunci
+func (s APIKeyScopes) Allows(p Permission) bool {
+	for _, scope := range s {
+		if scope == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (s APIKeyScopes) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+func (s *APIKeyScopes) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("unsupported type for APIKeyScopes")
+	}
+	return json.Unmarshal(b, s)
+}
+
+// Invitation - Undangan mendaftar yang terikat ke email dan role, opsional langsung
+// enroll ke course. Token hanya disimpan dalam bentuk hash.
+type Invitation struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Email          string     `json:"email" gorm:"not null;index"`
+	Name           string     `json:"name,omitempty"` // Isian awal form pendaftaran (mis. dari file import)
+	Role           Role       `json:"role" gorm:"type:varchar(20);not null"`
+	CourseID       *uint      `json:"course_id,omitempty" gorm:"index"`
+	TokenHash      string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	InvitedBy      uint       `json:"invited_by" gorm:"not null;index"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedUserID *uint      `json:"accepted_user_id,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	// Organisasi tempat akun baru dibuat (organisasi pengundang atau course)
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"index"`
+
+	// Course dan lab tambahan dari import massal; student langsung didaftarkan saat menerima undangan
+	CourseIDs IDList `json:"course_ids,omitempty" gorm:"type:jsonb"`
+	LabIDs    IDList `json:"lab_ids,omitempty" gorm:"type:jsonb"`
+
+	// Relations
+	Course  *Course `json:"course,omitempty" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE"`
+	Inviter *User   `json:"inviter,omitempty" gorm:"foreignKey:InvitedBy;constraint:OnDelete:CASCADE"`
+}
+
+// IsPending - Undangan belum diterima, belum dicabut dan belum kedaluwarsa
+func (i *Invitation) IsPending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// IDList - Daftar ID, disimpan sebagai jsonb
+type IDList []uint
+
+func (l IDList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+func (l *IDList) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("unsupported type for IDList")
+	}
+	return json.Unmarshal(b, l)
+}
+
+// SignupPolicy - Aturan pendaftaran publik (tanpa undangan). Pendaftaran publik
+// selalu menghasilkan akun student; role lain hanya lewat undangan.
+type SignupPolicy struct {
+	InviteOnly     bool     // Pendaftaran publik ditutup
+	AllowedDomains []string // Domain email yang boleh mendaftar; kosong = semua
+}
+
+// AllowsEmail mengecek domain email terhadap AllowedDomains (termasuk subdomain)
+func (p SignupPolicy) AllowsEmail(email string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	host := strings.ToLower(email[at+1:])
+	for _, allowed := range p.AllowedDomains {
+		allowed = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(allowed), "@"))
+		if allowed != "" && (host == allowed || strings.HasSuffix(host, "."+allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountDeletion - Permintaan hapus akun oleh user sendiri. Data baru dihapus setelah
+// ScheduledFor; selama masa tenggang user masih bisa login dan membatalkan.
+type AccountDeletion struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"uniqueIndex;not null"`
+	Reason       string    `json:"reason,omitempty" gorm:"type:text"`
+	ScheduledFor time.Time `json:"scheduled_for" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// AuditAction - Jenis aksi yang dicatat di audit log, format "target.aksi"
+type AuditAction string
+
+const (
+	AuditCourseCreate    AuditAction = "course.create"
+	AuditCourseUpdate    AuditAction = "course.update"
+	AuditCourseDelete    AuditAction = "course.delete"
+	AuditCoursePublish   AuditAction = "course.publish"
+	AuditCourseUnpublish AuditAction = "course.unpublish"
+	AuditModuleCreate    AuditAction = "module.create"
+	AuditModuleUpdate    AuditAction = "module.update"
+	AuditModuleDelete    AuditAction = "module.delete"
+	AuditAssignmentGrade AuditAction = "assignment.grade"
+
+	AuditLabCreate        AuditAction = "lab.create"
+	AuditLabUpdate        AuditAction = "lab.update"
+	AuditLabDelete        AuditAction = "lab.delete"
+	AuditLabGrade         AuditAction = "lab.grade"
+	AuditLabStudentAdd    AuditAction = "lab.student_add"
+	AuditLabStudentRemove AuditAction = "lab.student_remove"
+
+	AuditCertificateApprove    AuditAction = "certificate.approve"
+	AuditCertificateReject     AuditAction = "certificate.reject"
+	AuditCertificateRegenerate AuditAction = "certificate.regenerate"
+
+	AuditUserCreate        AuditAction = "user.create"
+	AuditUserUpdate        AuditAction = "user.update"
+	AuditUserDelete        AuditAction = "user.delete"
+	AuditUserPasswordReset AuditAction = "user.password_reset"
+
+	AuditAccountExport           AuditAction = "account.export"
+	AuditAccountDeletionSchedule AuditAction = "account.deletion_schedule"
+	AuditAccountDeletionCancel   AuditAction = "account.deletion_cancel"
+
+	AuditInvitationCreate AuditAction = "invitation.create"
+	AuditInvitationRevoke AuditAction = "invitation.revoke"
+	AuditInvitationAccept AuditAction = "invitation.accept"
+
+	AuditOrganizationCreate    AuditAction = "organization.create"
+	AuditOrganizationUpdate    AuditAction = "organization.update"
+	AuditOrganizationDelete    AuditAction = "organization.delete"
+	AuditOrganizationMemberSet AuditAction = "organization.member_set"
+
+	AuditImpersonationStart   AuditAction = "impersonation.start"
+	AuditImpersonationStop    AuditAction = "impersonation.stop"
+	AuditImpersonationRequest AuditAction = "impersonation.request" // Request yang mengubah data selama impersonasi
+)
+
+// Jenis entity target audit log
+const (
+	AuditTargetCourse       = "course"
+	AuditTargetModule       = "module"
+	AuditTargetAssignment   = "assignment"
+	AuditTargetLab          = "lab"
+	AuditTargetLabGrade     = "lab_grade"
+	AuditTargetCertificate  = "certificate"
+	AuditTargetUser         = "user"
+	AuditTargetRequest      = "request"
+	AuditTargetInvitation   = "invitation"
+	AuditTargetOrganization = "organization"
+)
+
+// AuditLog - Jejak aksi privileged (nilai, sertifikat, user, course, lab)
+type AuditLog struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	ActorID        *uint        `json:"actor_id" gorm:"index"`                  // nil untuk aksi sistem
+	ImpersonatorID *uint        `json:"impersonator_id,omitempty" gorm:"index"` // Admin di balik aksi saat impersonasi
+	Action         AuditAction  `json:"action" gorm:"type:varchar(64);not null;index"`
+	TargetType     string       `json:"target_type" gorm:"type:varchar(32);not null;index:idx_audit_target"`
+	TargetID       string       `json:"target_id" gorm:"type:varchar(64);not null;index:idx_audit_target"` // String karena module memakai ObjectID
+	Changes        AuditChanges `json:"changes" gorm:"type:jsonb"`
+	IPAddress      string       `json:"ip_address"`
+	UserAgent      string       `json:"user_agent"`
+	CreatedAt      time.Time    `json:"created_at" gorm:"autoCreateTime;index"`
+
+	// Relations
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID;constraint:OnDelete:SET NULL"`
+}
+
+// AuditChange - Nilai sebuah field sebelum dan sesudah perubahan
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges - Diff per field, disimpan sebagai jsonb
+type AuditChanges map[string]AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("unsupported type for AuditChanges")
+	}
+	return json.Unmarshal(b, c)
+}
+
 // ========== MONGODB MODELS ==========
 
 type ModuleType string
@@ -149,10 +622,249 @@ type Module struct {
 	Description string     `json:"description" bson:"description"`
 	Order       int        `json:"order" bson:"order"`
 	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
+
+	// Sama dengan organisasi dan status publish course-nya
+	OrganizationID  uint `json:"organization_id,omitempty" bson:"organization_id,omitempty"`
+	CoursePublished bool `json:"-" bson:"course_published"`
+}
+
+// StoredFile - File GridFS yang diupload user (untuk export dan penghapusan akun)
+type StoredFile struct {
+	ID           string    `json:"id"`
+	OriginalName string    `json:"original_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	UploadDate   time.Time `json:"upload_date"`
+	CourseID     uint      `json:"course_id,omitempty"` // Diisi untuk materi course
+	ModuleID     string    `json:"module_id,omitempty"`
+}
+
+// ========== EMAIL ==========
+
+type EmailTemplate string
+
+const (
+	EmailTemplateVerification  EmailTemplate = "verification"
+	EmailTemplatePasswordReset EmailTemplate = "password_reset"
+	EmailTemplateSignupInvite  EmailTemplate = "signup_invitation" // Undangan untuk mendaftar sendiri
+)
+
+// Email - Permintaan pengiriman email berbasis template (HTML + plain text)
+type Email struct {
+	To       string
+	Template EmailTemplate
+	Locale   string // "id" atau "en", kosong = bahasa default mailer
+	Data     map[string]interface{}
+}
+
+// ========== QUERY FILTERS ==========
+
+// AuditLogFilter - Filter untuk membaca audit log; field kosong diabaikan
+type AuditLogFilter struct {
+	ActorID        *uint
+	ImpersonatorID *uint
+	Action         AuditAction
+	TargetType     string
+	TargetID       string
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
+
+// Batas ukuran halaman untuk ListQuery
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// ListQuery - Paginasi, urutan dan filter untuk endpoint daftar. Filter kosong diabaikan,
+// begitu juga filter yang tidak berlaku untuk resource yang di-list. Jika Cursor diisi,
+// Offset diabaikan dan urutan mengikuti cursor.
+type ListQuery struct {
+	Limit  int // 0 = DefaultPageLimit, dibatasi MaxPageLimit
+	Offset int
+	Cursor string // PageInfo.NextCursor dari halaman sebelumnya
+	Sort   string // Nama field, mis. "created_at"; kosong = urutan default resource
+	Desc   bool
+
+	Role         Role   // User
+	Published    *bool  // Course
+	InstructorID *uint  // Course dan Lab
+	Status       string // Lab dan Certificate
+	From         *time.Time
+	To           *time.Time // From/To: created_at; start_time untuk lab, issue_date untuk sertifikat
+}
+
+// PageInfo - Metadata paginasi yang dikirim bersama hasil ListQuery
+type PageInfo struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Sort       string `json:"sort"`
+	Desc       bool   `json:"desc"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// UserImportOptions - Opsi import user massal dari CSV/XLSX
+type UserImportOptions struct {
+	DryRun      bool   // Hanya validasi, tidak ada yang disimpan
+	DefaultRole Role   // Dipakai jika kolom role kosong
+	CourseIDs   []uint // Student hasil import langsung didaftarkan ke course ini
+	LabIDs      []uint // ... dan ke lab ini
+	SendInvites bool   // Kirim undangan mendaftar alih-alih langsung membuat akun
 }
 
 // ========== RESPONSE DTOs ==========
 
+// SearchResults - Hasil pencarian katalog: course published dan module di dalamnya,
+// masing-masing diurutkan dari yang paling relevan
+type SearchResults struct {
+	Query   string            `json:"query"`
+	Courses []CourseSearchHit `json:"courses"`
+	Modules []ModuleSearchHit `json:"modules"`
+}
+
+// CourseSearchHit - Course yang cocok di judul, deskripsi atau nama instructor.
+// Field *Highlight berisi HTML yang sudah di-escape dengan kata yang cocok dibungkus <mark>.
+type CourseSearchHit struct {
+	ID             uint    `json:"id"`
+	Title          string  `json:"title"`
+	Description    string  `json:"description"`
+	Thumbnail      string  `json:"thumbnail"`
+	InstructorID   uint    `json:"instructor_id"`
+	InstructorName string  `json:"instructor_name"`
+	Rank           float64 `json:"rank"`
+
+	TitleHighlight       string `json:"title_highlight" gorm:"-"`
+	DescriptionHighlight string `json:"description_highlight" gorm:"-"`
+	InstructorHighlight  string `json:"instructor_highlight" gorm:"-"`
+}
+
+// ModuleSearchHit - Module yang cocok di judul atau deskripsi
+type ModuleSearchHit struct {
+	ID          string     `json:"id"`
+	CourseID    uint       `json:"course_id"`
+	CourseTitle string     `json:"course_title"`
+	Title       string     `json:"title"`
+	Type        ModuleType `json:"type"`
+	Description string     `json:"description"`
+	Score       float64    `json:"score"`
+
+	TitleHighlight       string `json:"title_highlight"`
+	DescriptionHighlight string `json:"description_highlight"`
+}
+
+// ClientInfo - Informasi client yang melakukan request (untuk session)
+type ClientInfo struct {
+	IPAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+}
+
+// AuthTokens - Pasangan access token dan refresh token hasil login/refresh
+type AuthTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"` // Kosong jika refresh token lama tetap berlaku
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Detik
+}
+
+// LoginResult - Hasil login: token langsung, atau challenge 2FA yang harus diselesaikan
+type LoginResult struct {
+	Tokens        *AuthTokens         `json:"tokens,omitempty"`
+	MFARequired   bool                `json:"mfa_required"`
+	MFAToken      string              `json:"mfa_token,omitempty"`
+	MFAPurpose    MFAChallengePurpose `json:"mfa_purpose,omitempty"`
+	MFAExpiresIn  int                 `json:"mfa_expires_in,omitempty"`
+	RecoveryCodes []string            `json:"recovery_codes,omitempty"` // Hanya diisi saat enrollment selesai
+}
+
+// OIDCAuthRequest - URL redirect ke identity provider beserta state-nya
+type OIDCAuthRequest struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int    `json:"expires_in"`
+}
+
+// NewAPIKey - Kunci API yang baru dibuat; Key hanya dikembalikan sekali ini
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// NewInvitation - Undangan yang baru dibuat; Link hanya dikembalikan sekali ini
+type NewInvitation struct {
+	Invitation
+	Link string `json:"link"`
+}
+
+// ImpersonationResult - Access token untuk "view as" user lain. Tidak ada refresh
+// token; sesi berakhir saat ExpiresAt atau dihentikan lebih awal.
+type ImpersonationResult struct {
+	Tokens    *AuthTokens `json:"tokens"`
+	User      *User       `json:"user"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// AccountExport - Seluruh data milik user dari Postgres dan Mongo, ditulis sebagai
+// account.json di dalam arsip export
+type AccountExport struct {
+	ExportedAt     time.Time        `json:"exported_at"`
+	User           User             `json:"user"`
+	Enrollments    []Enrollment     `json:"enrollments"`
+	ModuleProgress []ModuleProgress `json:"module_progress"`
+	Assignments    []Assignment     `json:"assignments"`
+	LabGrades      []LabGrade       `json:"lab_grades"`
+	Certificates   []Certificate    `json:"certificates"`
+	Sessions       []Session        `json:"sessions"`
+	APIKeys        []APIKey         `json:"api_keys"`
+	Identities     []UserIdentity   `json:"identities"`
+	MFA            *UserMFA         `json:"mfa,omitempty"`
+	AuditLog       []AuditLog       `json:"audit_log"` // Aksi yang dilakukan user ini
+	Deletion       *AccountDeletion `json:"deletion,omitempty"`
+	Files          []StoredFile     `json:"files"`
+}
+
+// Status baris hasil import user
+const (
+	UserImportValid   = "valid"   // Lolos validasi (dry-run)
+	UserImportCreated = "created" // User berhasil dibuat
+	UserImportInvited = "invited" // Undangan mendaftar dikirim; akun dibuat saat undangan diterima
+	UserImportError   = "error"   // Baris dilewati, lihat Errors
+)
+
+// UserImportRow - Hasil per baris file import; Row mengikuti nomor baris di file (header = 1)
+type UserImportRow struct {
+	Row          int      `json:"row"`
+	Name         string   `json:"name"`
+	Email        string   `json:"email"`
+	Role         Role     `json:"role"`
+	Status       string   `json:"status"`
+	UserID       uint     `json:"user_id,omitempty"`
+	InvitationID uint     `json:"invitation_id,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+	Warnings     []string `json:"warnings,omitempty"` // Mis. gagal enroll atau kirim undangan; baris tetap diproses
+}
+
+// UserImportReport - Ringkasan import user. Baris yang valid tetap dibuat walaupun
+// ada baris lain yang error; pakai dry-run untuk memeriksa file lebih dulu.
+type UserImportReport struct {
+	DryRun  bool            `json:"dry_run"`
+	Total   int             `json:"total"`
+	Valid   int             `json:"valid"`
+	Created int             `json:"created"`
+	Invited int             `json:"invited"`
+	Failed  int             `json:"failed"`
+	Rows    []UserImportRow `json:"rows"`
+}
+
+// MFASetup - Data untuk mendaftarkan aplikasi authenticator
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI, tampilkan sebagai QR code
+}
+
 type StudentDashboardData struct {
 	User               *User                  `json:"user"`
 	TotalEnrollments   int                    `json:"total_enrollments"`
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kategori error domain. Cek dengan errors.Is, mis. errors.Is(err, domain.ErrNotFound);
+// delivery layer memetakan kategori ke status HTTP.
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrForbidden       = errors.New("forbidden")
+	ErrValidation      = errors.New("validation failed")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrUnavailable     = errors.New("unavailable")
+)
+
+// Error - Error domain dengan kode stabil untuk klien (mis. "course_not_found") dan pesan untuk manusia
+type Error struct {
+	Kind    error  // Salah satu kategori Err* di atas
+	Code    string // snake_case, tidak berubah walau pesan berubah
+	Message string
+	Fields  map[string]string // Pesan per field untuk ErrValidation
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is membuat errors.Is(err, ErrNotFound) dan sejenisnya bernilai true sesuai Kind
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// NewNotFound membuat error untuk resource yang tidak ada
+func NewNotFound(code, message string) *Error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: message}
+}
+
+// NewConflict membuat error untuk aksi yang bentrok dengan state saat ini (duplikat, masih dipakai, dll.)
+func NewConflict(code, message string) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message}
+}
+
+// NewForbidden membuat error untuk aksi yang tidak boleh dilakukan user ini
+func NewForbidden(code, message string) *Error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: message}
+}
+
+// NewValidation membuat error untuk input yang tidak valid
+func NewValidation(code, message string) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message}
+}
+
+// NewFieldError membuat error validasi untuk satu field input
+func NewFieldError(field, code, message string) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message, Fields: map[string]string{field: message}}
+}
+
+// NewUnauthorized membuat error untuk kredensial, token atau sesi yang tidak valid
+func NewUnauthorized(code, message string) *Error {
+	return &Error{Kind: ErrUnauthorized, Code: code, Message: message}
+}
+
+// NewTooManyRequests membuat error untuk permintaan yang dibatasi (cooldown, batas percobaan)
+func NewTooManyRequests(code, message string) *Error {
+	return &Error{Kind: ErrTooManyRequests, Code: code, Message: message}
+}
+
+// NewUnavailable membuat error untuk fitur yang tidak dikonfigurasi atau layanan eksternal yang gagal
+func NewUnavailable(code, message string) *Error {
+	return &Error{Kind: ErrUnavailable, Code: code, Message: message}
+}
+
+// Error yang dipakai di banyak tempat; bandingkan dengan errors.Is, bukan dengan err.Error()
+var (
+	ErrUserNotFound         = NewNotFound("user_not_found", "user not found")
+	ErrCourseNotFound       = NewNotFound("course_not_found", "course not found")
+	ErrLabNotFound          = NewNotFound("lab_not_found", "lab not found")
+	ErrModuleNotFound       = NewNotFound("module_not_found", "module not found")
+	ErrAssignmentNotFound   = NewNotFound("assignment_not_found", "assignment not found")
+	ErrCertificateNotFound  = NewNotFound("certificate_not_found", "certificate not found")
+	ErrInvitationNotFound   = NewNotFound("invitation_not_found", "invitation not found")
+	ErrOrganizationNotFound = NewNotFound("organization_not_found", "organization not found")
+	ErrAPIKeyNotFound       = NewNotFound("api_key_not_found", "api key not found")
+	ErrFileNotFound         = NewNotFound("file_not_found", "file not found")
+
+	ErrEmailExists    = NewConflict("email_already_exists", "email already exists")
+	ErrAccountHasLabs = NewConflict("account_has_labs", "reassign or delete your labs before deleting the account")
+
+	ErrInvalidCredentials = NewUnauthorized("invalid_credentials", "invalid credentials")
+	ErrEmailNotVerified   = NewForbidden("email_not_verified", "email not verified")
+	ErrInvalidMFACode     = NewUnauthorized("invalid_mfa_code", "invalid mfa code")
+	ErrNoOIDCAccount      = NewForbidden("oidc_account_not_found", "no OnLearn account is registered for this email")
+	ErrOIDCNotConfigured  = NewUnavailable("oidc_not_configured", "oidc login is not configured")
+)
+
+// LoginLockedError dikembalikan saat login ditolak karena akun atau IP sedang dikunci
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %d seconds", e.RetryAfterSeconds())
+}
+
+// Is - LoginLockedError termasuk kategori ErrTooManyRequests
+func (e *LoginLockedError) Is(target error) bool {
+	return target == ErrTooManyRequests
+}
+
+// RetryAfterSeconds membulatkan sisa waktu kunci ke atas dalam detik
+func (e *LoginLockedError) RetryAfterSeconds() int {
+	secs := int(e.RetryAfter / time.Second)
+	if e.RetryAfter%time.Second != 0 {
+		secs++
+	}
+	return secs
+}
+
+// ForbiddenError dikembalikan policy saat user tidak punya permission atas sebuah resource
+type ForbiddenError struct {
+	Permission Permission
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("forbidden: missing permission %s", e.Permission)
+}
+
+// Is - ForbiddenError termasuk kategori ErrForbidden
+func (e *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden
+}
@@ -1,12 +1,16 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // ========== REPOSITORIES ==========
 
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
-	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error) // Tidak dibatasi organisasi: email unik di seluruh platform
 	GetByID(ctx context.Context, id uint) (*User, error)
 	Update(ctx context.Context, user *User) error
 	UpdateVerified(ctx context.Context, email string) error
@@ -14,6 +18,7 @@ type UserRepository interface {
 	GetByIDs(ctx context.Context, ids []uint) ([]User, error)
 	GetByRole(ctx context.Context, role Role) ([]User, error)
 	GetAll(ctx context.Context) ([]User, error)
+	List(ctx context.Context, q ListQuery) ([]User, *PageInfo, error) // Sort: created_at, name, email
 	SearchStudents(ctx context.Context, searchTerm string) ([]User, error)
 	Delete(ctx context.Context, id uint) error
 	CountByRole(ctx context.Context, role Role) (int64, error)
@@ -23,7 +28,10 @@ type CourseRepository interface {
 	Create(ctx context.Context, course *Course) error
 	GetAll(ctx context.Context) ([]Course, error)
 	GetPublished(ctx context.Context) ([]Course, error) // Get only published courses
+	List(ctx context.Context, q ListQuery) ([]Course, *PageInfo, error) // Sort: created_at, title
+	SearchPublished(ctx context.Context, query string, limit int) ([]CourseSearchHit, error)
 	GetByID(ctx context.Context, id uint) (*Course, error)
+	GetByIDs(ctx context.Context, ids []uint) ([]Course, error)
 	GetByInstructorID(ctx context.Context, instructorID uint) ([]Course, error)
 	Update(ctx context.Context, course *Course) error
 	Delete(ctx context.Context, id uint) error
@@ -36,6 +44,8 @@ type ModuleRepository interface {
 	GetByID(ctx context.Context, id string) (*Module, error)
 	Update(ctx context.Context, module *Module) error
 	Delete(ctx context.Context, id string) error
+	Search(ctx context.Context, query string, limit int) ([]ModuleSearchHit, error) // Hanya module dari course published
+	SetCoursePublished(ctx context.Context, courseID uint, published bool) error
 }
 
 type EnrollmentRepository interface {
@@ -78,8 +88,10 @@ type LabRepository interface {
 	GetByID(ctx context.Context, id uint) (*Lab, error)
 	GetAll(ctx context.Context) ([]Lab, error)
 	GetUpcoming(ctx context.Context) ([]Lab, error)
+	List(ctx context.Context, q ListQuery) ([]Lab, *PageInfo, error) // Sort: start_time, created_at, title
 	Delete(ctx context.Context, id uint) error
 	Count(ctx context.Context) (int64, error)
+	CountByInstructorID(ctx context.Context, instructorID uint) (int64, error)
 
 	// Lab Grades
 	CreateGrade(ctx context.Context, grade *LabGrade) error
@@ -95,44 +107,252 @@ type CertificateRepository interface {
 	Create(ctx context.Context, cert *Certificate) error
 	GetByUserID(ctx context.Context, userID uint) ([]Certificate, error)
 	GetByID(ctx context.Context, id uint) (*Certificate, error)
+	GetAll(ctx context.Context) ([]Certificate, error)
 	GetPending(ctx context.Context) ([]Certificate, error)
+	List(ctx context.Context, q ListQuery) ([]Certificate, *PageInfo, error) // Sort: issue_date, title
 	GetRecentByUserID(ctx context.Context, userID uint, limit int) ([]Certificate, error)
 	Update(ctx context.Context, cert *Certificate) error
 	Count(ctx context.Context) (int64, error)
 	CountByStatus(ctx context.Context, status string) (int64, error)
 }
 
+type SessionRepository interface {
+	Create(ctx context.Context, session *Session) error
+	GetByID(ctx context.Context, id uint) (*Session, error)
+	GetByRefreshTokenHash(ctx context.Context, hash string) (*Session, error)
+	GetByPreviousTokenHash(ctx context.Context, hash string) (*Session, error)
+	Update(ctx context.Context, session *Session) error
+	// Rotate menyimpan token baru hanya jika refresh token session masih currentHash;
+	// false berarti request lain sudah merotasinya lebih dulu
+	Rotate(ctx context.Context, session *Session, currentHash string) (bool, error)
+	Revoke(ctx context.Context, id uint) error
+	RevokeAllByUserID(ctx context.Context, userID uint) error
+}
+
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, verification *EmailVerification) error
+	GetLatestByUserID(ctx context.Context, userID uint) (*EmailVerification, error)
+	Update(ctx context.Context, verification *EmailVerification) error
+	// MarkUsed menandai kode terpakai; false jika sudah dipakai request lain
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *PasswordReset) error
+	GetByTokenHash(ctx context.Context, hash string) (*PasswordReset, error)
+	Update(ctx context.Context, reset *PasswordReset) error
+	// MarkUsed menandai token terpakai; false jika sudah dipakai request lain
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	DeleteUnusedByUserID(ctx context.Context, userID uint) error
+}
+
+type LoginThrottleRepository interface {
+	Get(ctx context.Context, scope LockoutScope, identifier string) (*LoginThrottle, error)
+	RegisterFailure(ctx context.Context, scope LockoutScope, identifier string, window time.Duration) (*LoginThrottle, error)
+	Lock(ctx context.Context, id uint, until time.Time) error
+	Reset(ctx context.Context, scope LockoutScope, identifier string) error
+}
+
+type LockoutEventRepository interface {
+	Create(ctx context.Context, event *LockoutEvent) error
+	GetRecent(ctx context.Context, limit int) ([]LockoutEvent, error)
+}
+
+type MFARepository interface {
+	GetByUserID(ctx context.Context, userID uint) (*UserMFA, error)
+	Save(ctx context.Context, mfa *UserMFA) error
+	DeleteByUserID(ctx context.Context, userID uint) error
+	// Confirm mengaktifkan enrollment yang belum dikonfirmasi; UseStep mencatat step TOTP
+	// yang dipakai. Keduanya false jika request lain lebih dulu (kode tidak bisa di-replay).
+	Confirm(ctx context.Context, userID uint, step int64) (bool, error)
+	UseStep(ctx context.Context, userID uint, step int64) (bool, error)
+
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []MFARecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+
+	CreateChallenge(ctx context.Context, challenge *MFAChallenge) error
+	GetChallengeByTokenHash(ctx context.Context, hash string) (*MFAChallenge, error)
+	UpdateChallenge(ctx context.Context, challenge *MFAChallenge) error
+	UseChallenge(ctx context.Context, id uint) (bool, error)
+
+	GetPolicy(ctx context.Context, role Role) (*MFAPolicy, error)
+	GetPolicies(ctx context.Context) ([]MFAPolicy, error)
+	SavePolicy(ctx context.Context, policy *MFAPolicy) error
+}
+
+type OIDCRepository interface {
+	CreateState(ctx context.Context, state *OIDCLoginState) error
+	ConsumeState(ctx context.Context, stateHash string) (*OIDCLoginState, error)
+
+	GetIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *UserIdentity) error
+	UpdateIdentity(ctx context.Context, identity *UserIdentity) error
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByID(ctx context.Context, id uint) (*APIKey, error)
+	GetByKeyHash(ctx context.Context, hash string) (*APIKey, error)
+	GetByUserID(ctx context.Context, userID uint) ([]APIKey, error)
+	Revoke(ctx context.Context, id uint) error
+	UpdateLastUsed(ctx context.Context, id uint, usedAt time.Time, ip string) error
+}
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *AuditLog) error
+	List(ctx context.Context, filter AuditLogFilter) ([]AuditLog, int64, error)
+}
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *Invitation) error
+	GetByID(ctx context.Context, id uint) (*Invitation, error)
+	GetByTokenHash(ctx context.Context, hash string) (*Invitation, error)
+	List(ctx context.Context, invitedBy *uint) ([]Invitation, error) // nil = semua undangan
+	Update(ctx context.Context, invitation *Invitation) error
+	RevokePendingByEmail(ctx context.Context, email string) error
+	GetPendingByEmail(ctx context.Context, email string) (*Invitation, error) // nil jika tidak ada
+}
+
+// OrganizationRepository - Tenant; tidak dibatasi organisasi pemanggil
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *Organization) error
+	GetByID(ctx context.Context, id uint) (*Organization, error)
+	GetBySlug(ctx context.Context, slug string) (*Organization, error)
+	GetAll(ctx context.Context) ([]Organization, error)
+	Update(ctx context.Context, org *Organization) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// AccountRepository - Data lintas tabel milik satu user (export dan penghapusan akun)
+type AccountRepository interface {
+	GetExport(ctx context.Context, userID uint) (*AccountExport, error)
+	Purge(ctx context.Context, userID uint) error
+
+	CreateDeletion(ctx context.Context, deletion *AccountDeletion) error
+	GetDeletionByUserID(ctx context.Context, userID uint) (*AccountDeletion, error)
+	DeleteDeletion(ctx context.Context, userID uint) error
+	GetDueDeletions(ctx context.Context, now time.Time) ([]AccountDeletion, error)
+}
+
+// UserFileStore - File GridFS yang diupload user
+type UserFileStore interface {
+	ListByUploader(ctx context.Context, userID uint) ([]StoredFile, error)
+	Open(ctx context.Context, fileID string) (io.ReadCloser, error)
+	Delete(ctx context.Context, fileID string) error
+	AnonymizeUploader(ctx context.Context, userID uint) error
+}
+
 // ========== USECASES ==========
 
 type AuthUsecase interface {
 	Register(ctx context.Context, user *User) error
-	Login(ctx context.Context, email, password string) (string, error)
+	Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error)
+	VerifyMFA(ctx context.Context, mfaToken, code string, client ClientInfo) (*LoginResult, error)
+	SetupMFAForChallenge(ctx context.Context, mfaToken string) (*MFASetup, error)
+	LoginWithIdentity(ctx context.Context, user *User, client ClientInfo) (*LoginResult, error)
+	RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*AuthTokens, error)
+	Logout(ctx context.Context, refreshToken string) error
+	RevokeAllSessions(ctx context.Context, userID uint) error
+	ValidateSession(ctx context.Context, sessionID uint) error
+	UnlockAccount(ctx context.Context, userID, adminID uint) error
+	GetLockoutEvents(ctx context.Context, limit int) ([]LockoutEvent, error)
 	UpdateUser(ctx context.Context, user *User) error
-	VerifyEmail(ctx context.Context, email string, token string) error
+	ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) error
+	VerifyEmail(ctx context.Context, email string, code string) error
+	ResendVerification(ctx context.Context, email string) error
 	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, newPassword string) error
+	SetPassword(ctx context.Context, userID uint, newPassword string, actorID uint) error
 	GetUserByID(ctx context.Context, id uint) (*User, error)
+
+	// Two-factor authentication (TOTP)
+	SetupMFA(ctx context.Context, userID uint) (*MFASetup, error)
+	ConfirmMFA(ctx context.Context, userID uint, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userID uint, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error)
+	ResetMFA(ctx context.Context, userID uint) error
+	GetMFAPolicies(ctx context.Context) ([]MFAPolicy, error)
+	SetMFAPolicy(ctx context.Context, role Role, required bool, adminID uint) error
+
+	// Impersonation ("view as" oleh admin)
+	Impersonate(ctx context.Context, adminID, targetID uint, reason string, client ClientInfo) (*ImpersonationResult, error)
+	StopImpersonation(ctx context.Context, sessionID uint) error
+
+	// Personal API keys
+	CreateAPIKey(ctx context.Context, userID uint, name string, scopes []Permission, ttl time.Duration) (*NewAPIKey, error)
+	ListAPIKeys(ctx context.Context, userID uint) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID uint) error
+	AuthenticateAPIKey(ctx context.Context, key string, client ClientInfo) (*APIKey, error)
+}
+
+type OIDCUsecase interface {
+	Enabled() bool
+	BeginLogin(ctx context.Context) (*OIDCAuthRequest, error)
+	CompleteLogin(ctx context.Context, state, code string, client ClientInfo) (*LoginResult, error)
 }
 
 type UserUsecase interface {
-	CreateUser(ctx context.Context, user *User) error
+	CreateUser(ctx context.Context, user *User, actorID uint) error
 	GetUserByID(ctx context.Context, id uint) (*User, error)
-	GetAllUsers(ctx context.Context) ([]User, error)
+	ListUsers(ctx context.Context, q ListQuery) ([]User, *PageInfo, error)
 	GetUsersByRole(ctx context.Context, role Role) ([]User, error)
-	UpdateUser(ctx context.Context, user *User) error
-	DeleteUser(ctx context.Context, id uint) error
+	UpdateUser(ctx context.Context, user *User, actorID uint) error
+	DeleteUser(ctx context.Context, id uint, actorID uint) error
+	ImportUsers(ctx context.Context, records [][]string, opts UserImportOptions, actorID uint) (*UserImportReport, error)
+}
+
+// InvitationUsecase - Pendaftaran publik (dengan SignupPolicy) dan lewat undangan
+type InvitationUsecase interface {
+	SignupPolicy() SignupPolicy
+	SignUp(ctx context.Context, user *User, organizationSlug string) error // Slug kosong = organisasi default
+
+	CreateInvitation(ctx context.Context, email string, role Role, courseID *uint, ttl time.Duration, actorID uint) (*NewInvitation, error)
+	CreateImportInvitation(ctx context.Context, invitation *Invitation, actorID uint) (*NewInvitation, error)
+	ListInvitations(ctx context.Context, actorID uint) ([]Invitation, error)
+	RevokeInvitation(ctx context.Context, id, actorID uint) error
+	GetInvitation(ctx context.Context, token string) (*Invitation, error)
+	AcceptInvitation(ctx context.Context, token, name, password string) (*User, error)
+	SignUpWithIdentity(ctx context.Context, user *User) error
+}
+
+// OrganizationUsecase - Pengelolaan tenant oleh super admin
+type OrganizationUsecase interface {
+	ListOrganizations(ctx context.Context) ([]Organization, error)
+	GetOrganization(ctx context.Context, id uint) (*Organization, error)
+	GetCurrentOrganization(ctx context.Context, userID uint) (*Organization, error)
+	CreateOrganization(ctx context.Context, org *Organization, actorID uint) error
+	UpdateOrganization(ctx context.Context, org *Organization, actorID uint) error
+	DeleteOrganization(ctx context.Context, id, actorID uint) error
+	ListMembers(ctx context.Context, orgID uint) ([]User, error)
+	SetMember(ctx context.Context, orgID, userID uint, role Role, actorID uint) (*User, error)
+}
+
+// AccountUsecase - Export data dan penghapusan akun oleh user sendiri (GDPR)
+type AccountUsecase interface {
+	GetExport(ctx context.Context, userID uint) (*AccountExport, error)
+	WriteExportArchive(ctx context.Context, export *AccountExport, w io.Writer) error
+
+	RequestDeletion(ctx context.Context, userID uint, reason string) (*AccountDeletion, error)
+	CancelDeletion(ctx context.Context, userID uint) error
+	GetDeletion(ctx context.Context, userID uint) (*AccountDeletion, error)
+	DeleteAccount(ctx context.Context, userID, actorID uint) error
+	PurgeDueDeletions(ctx context.Context) (int, error)
 }
 
 type CourseUsecase interface {
-	CreateCourse(ctx context.Context, course *Course) error
-	AddModule(ctx context.Context, module *Module) error
-	UpdateModule(ctx context.Context, module *Module) error
-	DeleteModule(ctx context.Context, moduleID string) error
+	CreateCourse(ctx context.Context, course *Course, actorID uint) error
+	AddModule(ctx context.Context, module *Module, actorID uint) error
+	UpdateModule(ctx context.Context, module *Module, actorID uint) error
+	DeleteModule(ctx context.Context, moduleID string, actorID uint) error
 	GetModuleByID(ctx context.Context, moduleID string) (*Module, error)
 	GetCourseDetails(ctx context.Context, courseID uint, userID *uint) (*CourseDetail, error)
 	GetAllCourses(ctx context.Context) ([]Course, error)
+	ListCourses(ctx context.Context, q ListQuery, userID uint) ([]Course, *PageInfo, error) // Default hanya course published
+	Search(ctx context.Context, query string, limit int) (*SearchResults, error)
 	GetInstructorCourses(ctx context.Context, instructorID uint) ([]Course, error)
-	UpdateCourse(ctx context.Context, course *Course) error
-	DeleteCourse(ctx context.Context, id uint) error
+	UpdateCourse(ctx context.Context, course *Course, actorID uint) error
+	DeleteCourse(ctx context.Context, id uint, actorID uint) error
 
 	// Enrollment
 	EnrollStudent(ctx context.Context, userID, courseID uint) error
@@ -157,18 +377,19 @@ type CourseUsecase interface {
 }
 
 type LabUsecase interface {
-	CreateLab(ctx context.Context, lab *Lab) error
-	UpdateLab(ctx context.Context, lab *Lab) error
-	UpdateLabStatus(ctx context.Context, labID uint, status string) error
+	CreateLab(ctx context.Context, lab *Lab, actorID uint) error
+	UpdateLab(ctx context.Context, lab *Lab, actorID uint) error
+	UpdateLabStatus(ctx context.Context, labID uint, status string, actorID uint) error
 	GetLabByID(ctx context.Context, labID uint) (*Lab, error)
 	GetAllLabs(ctx context.Context) ([]Lab, error)
+	ListLabs(ctx context.Context, q ListQuery) ([]Lab, *PageInfo, error)
 	GetUpcomingLabs(ctx context.Context) ([]Lab, error)
-	DeleteLab(ctx context.Context, labID uint) error
+	DeleteLab(ctx context.Context, labID uint, actorID uint) error
 
 	// Student Management in Labs
 	StudentEnroll(ctx context.Context, userID, labID uint) error
-	AddStudentToLab(ctx context.Context, userID, labID uint) error
-	RemoveStudentFromLab(ctx context.Context, userID, labID uint) error
+	AddStudentToLab(ctx context.Context, userID, labID uint, actorID uint) error
+	RemoveStudentFromLab(ctx context.Context, userID, labID uint, actorID uint) error
 	GetLabStudents(ctx context.Context, labID uint) ([]LabGrade, error)
 
 	// Grading
@@ -185,8 +406,17 @@ type CertificateUsecase interface {
 	GetUserCertificates(ctx context.Context, userID uint) ([]Certificate, error)
 	GetRecentCertificates(ctx context.Context, userID uint, limit int) ([]Certificate, error)
 	GetPendingCertificates(ctx context.Context) ([]Certificate, error)
+	ListPendingCertificates(ctx context.Context, q ListQuery) ([]Certificate, *PageInfo, error)
 	ApproveCertificate(ctx context.Context, certID uint, approverID uint) error
 	RejectCertificate(ctx context.Context, certID uint, approverID uint) error
+	RegenerateCertificates(ctx context.Context, ids []uint, actorID uint) (int, error)
+}
+
+// AuditUsecase - Pencatatan aksi privileged. Record tidak pernah menggagalkan aksi
+// utama; kegagalan menulis log hanya dicatat sebagai warning.
+type AuditUsecase interface {
+	Record(ctx context.Context, actorID uint, action AuditAction, targetType, targetID string, before, after interface{})
+	GetLogs(ctx context.Context, filter AuditLogFilter) ([]AuditLog, int64, error)
 }
 
 type DashboardUsecase interface {
@@ -197,6 +427,28 @@ type DashboardUsecase interface {
 
 type ReportUsecase interface {
 	GetStudentPerformance(ctx context.Context, userID uint) (*StudentPerformance, error)
-	GetAllStudentsPerformance(ctx context.Context) ([]StudentPerformance, error)
+	ListStudentsPerformance(ctx context.Context, q ListQuery) ([]StudentPerformance, *PageInfo, error) // Sort mengikuti UserRepository.List
 	GetCourseReport(ctx context.Context, courseID uint) (interface{}, error)
 }
+
+// ========== SERVICES ==========
+
+// Policy - Satu pintu pengecekan permission dan kepemilikan resource.
+// Authorize memeriksa aksi atas resource yang sudah ada; resource tanpa pemilik (ownerID nil)
+// hanya boleh disentuh dengan ScopeAny. AuthorizeAction memeriksa aksi yang tidak menyentuh
+// resource milik siapa pun, mis. membuat resource baru (cukup punya permission).
+type Policy interface {
+	Authorize(ctx context.Context, userID uint, perm Permission, ownerID *uint) error
+	AuthorizeAction(ctx context.Context, userID uint, perm Permission) error
+}
+
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// OIDCProvider - Identity provider eksternal (authorization code + PKCE)
+type OIDCProvider interface {
+	Issuer() string
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (map[string]interface{}, error)
+}
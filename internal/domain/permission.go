@@ -0,0 +1,130 @@
+package domain
+
+// Permission - Hak akses granular dengan format "resource:action"
+type Permission string
+
+const (
+	// Akses portal (group route per peran)
+	PermLearningAccess Permission = "learning:access"
+	PermTeachingAccess Permission = "teaching:access"
+	PermAdminAccess    Permission = "admin:access"
+
+	// Course & module
+	PermCourseCreate       Permission = "course:create"
+	PermCourseUpdate       Permission = "course:update"
+	PermCourseDelete       Permission = "course:delete"
+	PermCoursePublish      Permission = "course:publish"
+	PermCourseViewStudents Permission = "course:view_students"
+	PermCourseViewContent  Permission = "course:view_content" // Akses file materi tanpa harus enroll
+	PermModuleManage       Permission = "module:manage"
+	PermAssignmentGrade    Permission = "assignment:grade"
+
+	// Lab
+	PermLabCreate         Permission = "lab:create"
+	PermLabUpdate         Permission = "lab:update"
+	PermLabDelete         Permission = "lab:delete"
+	PermLabGrade          Permission = "lab:grade"
+	PermLabManageStudents Permission = "lab:manage_students"
+
+	// Sertifikat & laporan
+	PermCertificateApprove Permission = "certificate:approve"
+	PermStudentView        Permission = "student:view"
+	PermInvitationCreate   Permission = "invitation:create" // Undang student; undangan role lain butuh user:manage
+
+	// Administrasi
+	PermUserManage      Permission = "user:manage"
+	PermSecurityManage  Permission = "security:manage" // Unlock akun, reset 2FA, policy 2FA, cabut session
+	PermAuditView       Permission = "audit:view"
+	PermUserImpersonate Permission = "user:impersonate" // "View as" user lain untuk debugging
+
+	// Platform (hanya super admin)
+	PermOrganizationManage Permission = "organization:manage"
+)
+
+// PermissionScope menentukan resource mana yang boleh disentuh dengan sebuah permission
+type PermissionScope int
+
+const (
+	ScopeNone PermissionScope = iota
+	ScopeOwn                  // Hanya resource milik sendiri (mis. course yang diajar)
+	ScopeAny                  // Semua resource
+)
+
+// rolePermissions adalah satu-satunya tempat pemetaan role ke permission
+var rolePermissions = map[Role]map[Permission]PermissionScope{
+	RoleStudent: {
+		PermLearningAccess: ScopeOwn,
+	},
+	RoleInstructor: {
+		PermTeachingAccess:     ScopeOwn,
+		PermCourseCreate:       ScopeOwn,
+		PermCourseUpdate:       ScopeOwn,
+		PermCourseDelete:       ScopeOwn,
+		PermCoursePublish:      ScopeOwn,
+		PermCourseViewStudents: ScopeOwn,
+		PermCourseViewContent:  ScopeAny,
+		PermModuleManage:       ScopeOwn,
+		PermAssignmentGrade:    ScopeOwn,
+		PermLabCreate:          ScopeOwn,
+		PermLabUpdate:          ScopeOwn,
+		PermLabDelete:          ScopeOwn,
+		PermLabGrade:           ScopeOwn,
+		PermLabManageStudents:  ScopeOwn,
+		PermCertificateApprove: ScopeOwn,
+		PermStudentView:        ScopeAny,
+		PermInvitationCreate:   ScopeOwn,
+	},
+	RoleAdmin: {
+		PermTeachingAccess:     ScopeAny,
+		PermAdminAccess:        ScopeAny,
+		PermCourseCreate:       ScopeAny,
+		PermCourseUpdate:       ScopeAny,
+		PermCourseDelete:       ScopeAny,
+		PermCoursePublish:      ScopeAny,
+		PermCourseViewStudents: ScopeAny,
+		PermCourseViewContent:  ScopeAny,
+		PermModuleManage:       ScopeAny,
+		PermAssignmentGrade:    ScopeAny,
+		PermLabCreate:          ScopeAny,
+		PermLabUpdate:          ScopeAny,
+		PermLabDelete:          ScopeAny,
+		PermLabGrade:           ScopeAny,
+		PermLabManageStudents:  ScopeAny,
+		PermCertificateApprove: ScopeAny,
+		PermStudentView:        ScopeAny,
+		PermInvitationCreate:   ScopeAny,
+		PermUserManage:         ScopeAny,
+		PermSecurityManage:     ScopeAny,
+		PermAuditView:          ScopeAny,
+		PermUserImpersonate:    ScopeAny,
+	},
+}
+
+// Super admin memiliki semua permission admin (tanpa batas organisasi) ditambah
+// pengelolaan organisasi
+func init() {
+	superAdmin := map[Permission]PermissionScope{PermOrganizationManage: ScopeAny}
+	for p, scope := range rolePermissions[RoleAdmin] {
+		superAdmin[p] = scope
+	}
+	rolePermissions[RoleSuperAdmin] = superAdmin
+}
+
+// Scope mengembalikan cakupan permission untuk role ini (ScopeNone jika tidak dimiliki)
+func (r Role) Scope(p Permission) PermissionScope {
+	return rolePermissions[r][p]
+}
+
+// Can mengecek apakah role memiliki permission, tanpa melihat kepemilikan resource
+func (r Role) Can(p Permission) bool {
+	return r.Scope(p) != ScopeNone
+}
+
+// Permissions mengembalikan daftar permission yang dimiliki role
+func (r Role) Permissions() []Permission {
+	perms := make([]Permission, 0, len(rolePermissions[r]))
+	for p := range rolePermissions[r] {
+		perms = append(perms, p)
+	}
+	return perms
+}
@@ -6,15 +6,22 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"onlearn-backend/internal/domain"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -43,8 +50,28 @@ type GridFSRepository interface {
 	Download(ctx context.Context, fileID string) (io.ReadCloser, *FileInfo, error)
 	Delete(ctx context.Context, fileID string) error
 	GetFileInfo(ctx context.Context, fileID string) (*FileInfo, error)
+
+	// Dipakai export/penghapusan akun
+	ListByUploader(ctx context.Context, userID uint) ([]domain.StoredFile, error)
+	Open(ctx context.Context, fileID string) (io.ReadCloser, error)
+	AnonymizeUploader(ctx context.Context, userID uint) error
 }
 
+var errInvalidFileID = domain.NewValidation("invalid_file_id", "invalid file ID")
+
+var (
+	gridFSUploadedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onlearn_gridfs_uploaded_bytes_total",
+		Help: "Bytes stored in GridFS by file type.",
+	}, []string{"file_type"})
+	gridFSDownloadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "onlearn_gridfs_downloaded_bytes_total",
+		Help: "Bytes read from GridFS (downloads, previews and account exports).",
+	})
+)
+
+var tracer = otel.Tracer("onlearn-backend/internal/repository")
+
 type gridFSRepo struct {
 	db     *mongo.Database
 	bucket *gridfs.Bucket
@@ -62,7 +89,7 @@ func NewGridFSRepository(db *mongo.Database) (GridFSRepository, error) {
 func (r *gridFSRepo) Upload(ctx context.Context, file multipart.File, header *multipart.FileHeader, metadata FileMetadata) (*FileInfo, error) {
 	// Validasi ukuran file
 	if header.Size > MaxLargeFileSize {
-		return nil, fmt.Errorf("ukuran file terlalu besar. Maksimal %dMB", MaxLargeFileSize/(1024*1024))
+		return nil, domain.NewFieldError("file", "file_too_large", fmt.Sprintf("ukuran file terlalu besar. Maksimal %dMB", MaxLargeFileSize/(1024*1024)))
 	}
 
 	// Deteksi content type
@@ -73,7 +100,7 @@ func (r *gridFSRepo) Upload(ctx context.Context, file multipart.File, header *mu
 
 	// Validasi tipe file
 	if !isAllowedFileType(contentType, header.Filename) {
-		return nil, errors.New("tipe file tidak diizinkan. Hanya PDF, PPT, PPTX, dan gambar yang diperbolehkan")
+		return nil, domain.NewFieldError("file", "file_type_not_allowed", "tipe file tidak diizinkan. Hanya PDF, PPT, PPTX, dan gambar yang diperbolehkan")
 	}
 
 	// Generate unique filename
@@ -94,10 +121,20 @@ func (r *gridFSRepo) Upload(ctx context.Context, file multipart.File, header *mu
 	})
 
 	// Upload ke GridFS
+	_, span := tracer.Start(ctx, "gridfs upload", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "mongodb"),
+		attribute.String("gridfs.file_type", metadata.FileType),
+		attribute.Int64("gridfs.size_bytes", header.Size),
+	))
 	objectID, err := r.bucket.UploadFromStream(uniqueFilename, file, uploadOpts)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
 		return nil, fmt.Errorf("gagal upload file: %w", err)
 	}
+	span.End()
+	gridFSUploadedBytes.WithLabelValues(metadata.FileType).Add(float64(header.Size))
 
 	return &FileInfo{
 		ID:          objectID.Hex(),
@@ -112,7 +149,7 @@ func (r *gridFSRepo) Upload(ctx context.Context, file multipart.File, header *mu
 func (r *gridFSRepo) Download(ctx context.Context, fileID string) (io.ReadCloser, *FileInfo, error) {
 	objectID, err := primitive.ObjectIDFromHex(fileID)
 	if err != nil {
-		return nil, nil, errors.New("invalid file ID")
+		return nil, nil, errInvalidFileID
 	}
 
 	fileInfo, err := r.GetFileInfo(ctx, fileID)
@@ -120,9 +157,9 @@ func (r *gridFSRepo) Download(ctx context.Context, fileID string) (io.ReadCloser
 		return nil, nil, err
 	}
 
-	stream, err := r.bucket.OpenDownloadStream(objectID)
+	stream, err := r.openStream(ctx, objectID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("file tidak ditemukan: %w", err)
+		return nil, nil, fmt.Errorf("%w: %w", domain.ErrFileNotFound, err)
 	}
 
 	return stream, fileInfo, nil
@@ -131,10 +168,13 @@ func (r *gridFSRepo) Download(ctx context.Context, fileID string) (io.ReadCloser
 func (r *gridFSRepo) Delete(ctx context.Context, fileID string) error {
 	objectID, err := primitive.ObjectIDFromHex(fileID)
 	if err != nil {
-		return errors.New("invalid file ID")
+		return errInvalidFileID
 	}
 
-	err = r.bucket.Delete(objectID)
+	err = r.bucket.DeleteContext(ctx, objectID)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return domain.ErrFileNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("gagal menghapus file: %w", err)
 	}
@@ -145,28 +185,128 @@ func (r *gridFSRepo) Delete(ctx context.Context, fileID string) error {
 func (r *gridFSRepo) GetFileInfo(ctx context.Context, fileID string) (*FileInfo, error) {
 	objectID, err := primitive.ObjectIDFromHex(fileID)
 	if err != nil {
-		return nil, errors.New("invalid file ID")
+		return nil, errInvalidFileID
 	}
 
 	// Query files collection
 	collection := r.db.Collection("uploads.files")
-	
-	var result struct {
-		ID         primitive.ObjectID `bson:"_id"`
-		Filename   string             `bson:"filename"`
-		Length     int64              `bson:"length"`
-		UploadDate time.Time          `bson:"uploadDate"`
-		Metadata   bson.M             `bson:"metadata"`
-	}
 
+	var result fileDocument
 	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&result)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("file tidak ditemukan")
+			return nil, domain.ErrFileNotFound
 		}
 		return nil, err
 	}
 
+	return result.toFileInfo(), nil
+}
+
+// ListByUploader mengembalikan semua file yang diupload user
+func (r *gridFSRepo) ListByUploader(ctx context.Context, userID uint) ([]domain.StoredFile, error) {
+	cursor, err := r.db.Collection("uploads.files").Find(ctx, bson.M{"metadata.uploaded_by": int64(userID)})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var files []domain.StoredFile
+	for cursor.Next(ctx) {
+		var doc fileDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		info := doc.toFileInfo()
+		files = append(files, domain.StoredFile{
+			ID:           info.ID,
+			OriginalName: info.Metadata.OriginalName,
+			ContentType:  info.ContentType,
+			Size:         info.Size,
+			UploadDate:   info.UploadDate,
+			CourseID:     info.Metadata.CourseID,
+			ModuleID:     info.Metadata.ModuleID,
+		})
+	}
+	return files, cursor.Err()
+}
+
+// Open membuka stream isi file
+func (r *gridFSRepo) Open(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	objectID, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return nil, errInvalidFileID
+	}
+
+	stream, err := r.openStream(ctx, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrFileNotFound, err)
+	}
+	return stream, nil
+}
+
+// openStream membuka download stream; span "gridfs download" berakhir saat stream ditutup
+// sehingga durasinya mencakup seluruh waktu streaming ke client
+func (r *gridFSRepo) openStream(ctx context.Context, objectID primitive.ObjectID) (io.ReadCloser, error) {
+	_, span := tracer.Start(ctx, "gridfs download", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "mongodb"),
+		attribute.String("gridfs.file_id", objectID.Hex()),
+	))
+	stream, err := r.bucket.OpenDownloadStream(objectID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+	return &countingReader{ReadCloser: stream, counter: gridFSDownloadedBytes, span: span}, nil
+}
+
+// AnonymizeUploader menghapus jejak uploader (uploaded_by = 0) pada file yang tetap
+// disimpan setelah akun dihapus, mis. materi course
+func (r *gridFSRepo) AnonymizeUploader(ctx context.Context, userID uint) error {
+	_, err := r.db.Collection("uploads.files").UpdateMany(ctx,
+		bson.M{"metadata.uploaded_by": int64(userID)},
+		bson.M{"$set": bson.M{"metadata.uploaded_by": 0}},
+	)
+	return err
+}
+
+// countingReader menambah metric byte yang benar-benar dibaca dari stream GridFS
+type countingReader struct {
+	io.ReadCloser
+	counter prometheus.Counter
+	span    trace.Span
+	read    int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.counter.Add(float64(n))
+	r.read += int64(n)
+	if err != nil && err != io.EOF {
+		r.span.RecordError(err)
+		r.span.SetStatus(codes.Error, err.Error())
+	}
+	return n, err
+}
+
+func (r *countingReader) Close() error {
+	r.span.SetAttributes(attribute.Int64("gridfs.bytes_read", r.read))
+	r.span.End()
+	return r.ReadCloser.Close()
+}
+
+// fileDocument - Dokumen di collection uploads.files
+type fileDocument struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Filename   string             `bson:"filename"`
+	Length     int64              `bson:"length"`
+	UploadDate time.Time          `bson:"uploadDate"`
+	Metadata   bson.M             `bson:"metadata"`
+}
+
+func (result fileDocument) toFileInfo() *FileInfo {
 	// Extract metadata
 	metadata := FileMetadata{}
 	if result.Metadata != nil {
@@ -208,7 +348,7 @@ func (r *gridFSRepo) GetFileInfo(ctx context.Context, fileID string) (*FileInfo,
 		Size:        result.Length,
 		UploadDate:  result.UploadDate,
 		Metadata:    metadata,
-	}, nil
+	}
 }
 
 // Helper functions
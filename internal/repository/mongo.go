@@ -2,8 +2,9 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/search"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,6 +13,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var errInvalidModuleID = domain.NewValidation("invalid_module_id", "invalid module ID")
+
 type moduleRepo struct {
 	db *mongo.Database
 }
@@ -20,9 +23,21 @@ func NewModuleRepository(db *mongo.Database) domain.ModuleRepository {
 	return &moduleRepo{db}
 }
 
+// scopeModuleFilter membatasi filter ke organisasi pemanggil (lihat domain.WithOrganization)
+func scopeModuleFilter(ctx context.Context, filter bson.M) bson.M {
+	if orgID, ok := domain.OrganizationFromContext(ctx); ok {
+		filter["organization_id"] = orgID
+	}
+	return filter
+}
+
 func (r *moduleRepo) Create(ctx context.Context, module *domain.Module) error {
 	collection := r.db.Collection("modules")
 
+	if orgID, ok := domain.OrganizationFromContext(ctx); ok {
+		module.OrganizationID = orgID
+	}
+
 	// Set created_at if not set
 	if module.CreatedAt.IsZero() {
 		module.CreatedAt = time.Now()
@@ -44,7 +59,7 @@ func (r *moduleRepo) Create(ctx context.Context, module *domain.Module) error {
 
 func (r *moduleRepo) GetByCourseID(ctx context.Context, courseID uint) ([]domain.Module, error) {
 	collection := r.db.Collection("modules")
-	filter := bson.M{"course_id": courseID}
+	filter := scopeModuleFilter(ctx, bson.M{"course_id": courseID})
 
 	// Sort by order ascending
 	opts := options.Find().SetSort(bson.D{{Key: "order", Value: 1}})
@@ -77,16 +92,16 @@ func (r *moduleRepo) GetByID(ctx context.Context, id string) (*domain.Module, er
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid module ID")
+		return nil, errInvalidModuleID
 	}
 
-	filter := bson.M{"_id": objID}
+	filter := scopeModuleFilter(ctx, bson.M{"_id": objID})
 
 	var module domain.Module
 	err = collection.FindOne(ctx, filter).Decode(&module)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("module not found")
+			return nil, domain.ErrModuleNotFound
 		}
 		return nil, err
 	}
@@ -100,10 +115,10 @@ func (r *moduleRepo) Update(ctx context.Context, module *domain.Module) error {
 
 	objID, err := primitive.ObjectIDFromHex(module.ID)
 	if err != nil {
-		return errors.New("invalid module ID")
+		return errInvalidModuleID
 	}
 
-	filter := bson.M{"_id": objID}
+	filter := scopeModuleFilter(ctx, bson.M{"_id": objID})
 	update := bson.M{
 		"$set": bson.M{
 			"title":       module.Title,
@@ -122,7 +137,7 @@ func (r *moduleRepo) Update(ctx context.Context, module *domain.Module) error {
 	}
 
 	if result.MatchedCount == 0 {
-		return errors.New("module not found")
+		return domain.ErrModuleNotFound
 	}
 
 	return nil
@@ -133,18 +148,110 @@ func (r *moduleRepo) Delete(ctx context.Context, id string) error {
 
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid module ID")
+		return errInvalidModuleID
 	}
 
-	filter := bson.M{"_id": objID}
+	filter := scopeModuleFilter(ctx, bson.M{"_id": objID})
 	result, err := collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return err
 	}
 
 	if result.DeletedCount == 0 {
-		return errors.New("module not found")
+		return domain.ErrModuleNotFound
 	}
 
 	return nil
 }
+
+// SetCoursePublished menyalin status publish course ke semua module-nya agar pencarian
+// bisa memfilter di query MongoDB tanpa daftar course dari PostgreSQL
+func (r *moduleRepo) SetCoursePublished(ctx context.Context, courseID uint, published bool) error {
+	filter := scopeModuleFilter(ctx, bson.M{"course_id": courseID})
+	_, err := r.db.Collection("modules").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"course_published": published}})
+	return err
+}
+
+// fuzzyModuleScore - Skor untuk module yang hanya cocok lewat regex salah ketik/prefix,
+// di bawah skor $text sehingga kecocokan persis selalu di atas
+const fuzzyModuleScore = 0.25
+
+type moduleSearchDoc struct {
+	domain.Module `bson:",inline"`
+	Score         float64 `bson:"score"`
+}
+
+// Search mencari module dengan text index (title_description_text). Jika hasilnya
+// kurang dari limit, sisanya diisi module yang cocok dengan search.Pattern sehingga
+// kata yang salah ketik atau belum selesai diketik tetap ditemukan. Hanya module dari
+// course published di organisasi pemanggil (course_published, organization_id).
+func (r *moduleRepo) Search(ctx context.Context, query string, limit int) ([]domain.ModuleSearchHit, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	collection := r.db.Collection("modules")
+
+	filter := scopeModuleFilter(ctx, bson.M{
+		"course_published": true,
+		"$text":            bson.M{"$search": strings.Join(terms, " ")},
+	})
+	opts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}).
+		SetLimit(int64(limit))
+
+	var docs []moduleSearchDoc
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	if len(docs) < limit {
+		found := make([]primitive.ObjectID, 0, len(docs))
+		for _, d := range docs {
+			if id, err := primitive.ObjectIDFromHex(d.ID); err == nil {
+				found = append(found, id)
+			}
+		}
+
+		pattern := bson.M{"$regex": search.Pattern(terms), "$options": "i"}
+		filter := scopeModuleFilter(ctx, bson.M{
+			"course_published": true,
+			"_id":              bson.M{"$nin": found},
+			"$or":              bson.A{bson.M{"title": pattern}, bson.M{"description": pattern}},
+		})
+		opts := options.Find().
+			SetSort(bson.D{{Key: "course_id", Value: 1}, {Key: "order", Value: 1}}).
+			SetLimit(int64(limit - len(docs)))
+
+		var fuzzy []moduleSearchDoc
+		cursor, err := collection.Find(ctx, filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := cursor.All(ctx, &fuzzy); err != nil {
+			return nil, err
+		}
+		for i := range fuzzy {
+			fuzzy[i].Score = fuzzyModuleScore
+		}
+		docs = append(docs, fuzzy...)
+	}
+
+	hits := make([]domain.ModuleSearchHit, len(docs))
+	for i, d := range docs {
+		hits[i] = domain.ModuleSearchHit{
+			ID:          d.ID,
+			CourseID:    d.CourseID,
+			Title:       d.Title,
+			Type:        d.Type,
+			Description: d.Description,
+			Score:       d.Score,
+		}
+	}
+	return hits, nil
+}
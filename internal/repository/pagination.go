@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"onlearn-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ========== LIST PAGINATION ==========
+
+// sortField - Field yang boleh dipakai di ListQuery.Sort. value mengambil nilai kolom
+// dari item terakhir di halaman untuk dijadikan cursor.
+type sortField[T any] struct {
+	column string
+	value  func(T) any
+}
+
+// listSpec - Cara sebuah repository melayani ListQuery
+type listSpec[T any] struct {
+	sorts       map[string]sortField[T]
+	defaultSort string // Selalu descending, mis. data terbaru lebih dulu
+	id          func(T) uint
+	preloads    []string
+}
+
+// pageCursor - Isi cursor keyset: urutan yang dipakai dan posisi item terakhir.
+// Dikirim ke klien sebagai base64 (URL-safe) dari JSON, klien tidak perlu membacanya.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value any    `json:"v"`
+	Time  bool   `json:"t,omitempty"` // Value adalah timestamp RFC3339Nano
+	ID    uint   `json:"id"`
+}
+
+var errInvalidCursor = domain.NewFieldError("cursor", "invalid_cursor", "Invalid cursor")
+
+func encodeCursor(c pageCursor) string {
+	if t, ok := c.Value.(time.Time); ok {
+		c.Value = t.Format(time.RFC3339Nano)
+		c.Time = true
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.ID == 0 {
+		return c, errInvalidCursor
+	}
+	if c.Time {
+		str, _ := c.Value.(string)
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return c, errInvalidCursor
+		}
+		c.Value = t
+	}
+	return c, nil
+}
+
+// listPage menjalankan query yang sudah difilter dengan paginasi offset atau cursor.
+// Total dihitung tanpa cursor sehingga selalu jumlah semua baris yang cocok filter.
+func listPage[T any](query *gorm.DB, q domain.ListQuery, spec listSpec[T]) ([]T, *domain.PageInfo, error) {
+	if q.Limit <= 0 {
+		q.Limit = domain.DefaultPageLimit
+	}
+	if q.Limit > domain.MaxPageLimit {
+		q.Limit = domain.MaxPageLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	var cursor *pageCursor
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		if q.Sort == "" {
+			q.Sort, q.Desc = c.Sort, c.Desc
+		} else if q.Sort != c.Sort || q.Desc != c.Desc {
+			return nil, nil, domain.NewFieldError("cursor", "cursor_sort_mismatch", "Cursor was created for a different sort order")
+		}
+		cursor = &c
+		q.Offset = 0
+	}
+	if q.Sort == "" {
+		q.Sort, q.Desc = spec.defaultSort, true
+	}
+	field, ok := spec.sorts[q.Sort]
+	if !ok {
+		return nil, nil, domain.NewFieldError("sort", "invalid_sort", fmt.Sprintf("Cannot sort by %q", q.Sort))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	dir, op := "ASC", ">"
+	if q.Desc {
+		dir, op = "DESC", "<"
+	}
+	if cursor != nil {
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", field.column, op), cursor.Value, cursor.ID)
+	}
+	for _, p := range spec.preloads {
+		query = query.Preload(p)
+	}
+
+	// Ambil satu baris lebih untuk mengetahui apakah masih ada halaman berikutnya
+	var items []T
+	err := query.
+		Order(fmt.Sprintf("%s %s, id %s", field.column, dir, dir)).
+		Limit(q.Limit + 1).
+		Offset(q.Offset).
+		Find(&items).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := &domain.PageInfo{Total: total, Limit: q.Limit, Offset: q.Offset, Sort: q.Sort, Desc: q.Desc}
+	if len(items) > q.Limit {
+		items = items[:q.Limit]
+		last := items[len(items)-1]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(pageCursor{Sort: q.Sort, Desc: q.Desc, Value: field.value(last), ID: spec.id(last)})
+	}
+	return items, page, nil
+}
+
+// scopeDateRange menerapkan ListQuery.From/To ke kolom waktu resource
+func scopeDateRange(q domain.ListQuery, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if q.From != nil {
+			db = db.Where(column+" >= ?", *q.From)
+		}
+		if q.To != nil {
+			db = db.Where(column+" <= ?", *q.To)
+		}
+		return db
+	}
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"onlearn-backend/internal/domain"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		name   string
+		cursor pageCursor
+	}{
+		{
+			name:   "timestamp descending",
+			cursor: pageCursor{Sort: "created_at", Desc: true, Value: time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC), ID: 42},
+		},
+		{
+			name:   "timestamp with nanoseconds and offset",
+			cursor: pageCursor{Sort: "start_time", Value: time.Date(2024, 12, 31, 23, 59, 59, 123456789, jakarta), ID: 7},
+		},
+		{
+			name:   "string ascending",
+			cursor: pageCursor{Sort: "name", Value: "Budi Santoso", ID: 3},
+		},
+		{
+			name:   "string with quotes and unicode",
+			cursor: pageCursor{Sort: "title", Desc: true, Value: `Dasar "Go" — Ünïcode/?&=`, ID: 9},
+		},
+		{
+			name:   "empty string value",
+			cursor: pageCursor{Sort: "email", Value: "", ID: 1},
+		},
+		{
+			name:   "large id",
+			cursor: pageCursor{Sort: "title", Value: "z", ID: 1<<32 + 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := encodeCursor(tt.cursor)
+			if _, err := base64.RawURLEncoding.DecodeString(encoded); err != nil {
+				t.Fatalf("cursor %q is not URL-safe base64: %v", encoded, err)
+			}
+
+			got, err := decodeCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if got.Sort != tt.cursor.Sort || got.Desc != tt.cursor.Desc || got.ID != tt.cursor.ID {
+				t.Errorf("decodeCursor() = %+v, want %+v", got, tt.cursor)
+			}
+
+			switch want := tt.cursor.Value.(type) {
+			case time.Time:
+				value, ok := got.Value.(time.Time)
+				if !ok || !value.Equal(want) {
+					t.Errorf("Value = %#v, want time %v", got.Value, want)
+				}
+			default:
+				if got.Value != want {
+					t.Errorf("Value = %#v, want %#v", got.Value, want)
+				}
+			}
+
+			// Cursor yang sama harus menghasilkan string yang sama (stabil antar halaman)
+			if again := encodeCursor(got); again != encoded {
+				t.Errorf("re-encoded cursor = %s, want %s", again, encoded)
+			}
+		})
+	}
+}
+
+// TestCursorRoundTripListSpecs memastikan nilai cursor dari setiap sort field repository
+// kembali dengan tipe yang sama setelah decode
+func TestCursorRoundTripListSpecs(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+	user := domain.User{ID: 11, Name: "Siti", Email: "siti@example.com", CreatedAt: created}
+	lab := domain.Lab{ID: 12, Title: "Lab Jaringan", StartTime: created.Add(time.Hour), CreatedAt: created}
+
+	tests := []struct {
+		name  string
+		value any
+		id    uint
+	}{
+		{"user created_at", userListSpec.sorts["created_at"].value(user), userListSpec.id(user)},
+		{"user name", userListSpec.sorts["name"].value(user), userListSpec.id(user)},
+		{"user email", userListSpec.sorts["email"].value(user), userListSpec.id(user)},
+		{"lab start_time", labListSpec.sorts["start_time"].value(lab), labListSpec.id(lab)},
+		{"lab created_at", labListSpec.sorts["created_at"].value(lab), labListSpec.id(lab)},
+		{"lab title", labListSpec.sorts["title"].value(lab), labListSpec.id(lab)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(pageCursor{Sort: "s", Value: tt.value, ID: tt.id}))
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if got.ID != tt.id {
+				t.Errorf("ID = %d, want %d", got.ID, tt.id)
+			}
+			if want, ok := tt.value.(time.Time); ok {
+				if value, ok := got.Value.(time.Time); !ok || !value.Equal(want) {
+					t.Errorf("Value = %#v, want time %v", got.Value, want)
+				}
+				return
+			}
+			if got.Value != tt.value {
+				t.Errorf("Value = %#v, want %#v", got.Value, tt.value)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "!!not-base64!!"},
+		{"padded standard base64", base64.StdEncoding.EncodeToString([]byte(`{"s":"name","v":"ab","id":1}`))},
+		{"not json", encode("created_at:42")},
+		{"json array", encode(`["name","a",1]`)},
+		{"missing id", encode(`{"s":"name","v":"a"}`)},
+		{"zero id", encode(`{"s":"name","v":"a","id":0}`)},
+		{"negative id", encode(`{"s":"name","v":"a","id":-1}`)},
+		{"time flag with invalid timestamp", encode(`{"s":"created_at","v":"yesterday","t":true,"id":1}`)},
+		{"time flag with number", encode(`{"s":"created_at","v":1700000000,"t":true,"id":1}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.cursor)
+			var domainErr *domain.Error
+			if !errors.As(err, &domainErr) || domainErr.Code != "invalid_cursor" {
+				t.Fatalf("decodeCursor(%q) error = %v, want invalid_cursor", tt.cursor, err)
+			}
+		})
+	}
+}
@@ -4,11 +4,55 @@ import (
 	"context"
 	"errors"
 	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/search"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ========== ORGANIZATION SCOPE ==========
+
+// scopeOrg membatasi query ke organisasi pemanggil (domain.WithOrganization).
+// column adalah kolom organization_id tabel yang di-query.
+func scopeOrg(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if orgID, ok := domain.OrganizationFromContext(ctx); ok {
+			return db.Where(column+" = ?", orgID)
+		}
+		return db
+	}
+}
+
+// scopeOrgByUser untuk tabel tanpa kolom organisasi: dibatasi lewat user pemiliknya
+func scopeOrgByUser(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if orgID, ok := domain.OrganizationFromContext(ctx); ok {
+			return db.Where(column+" IN (SELECT id FROM users WHERE organization_id = ?)", orgID)
+		}
+		return db
+	}
+}
+
+// organizationForCreate menentukan organisasi record baru: organisasi pemanggil jika
+// request dibatasi (nilai dari client diabaikan), nilai yang sudah diisi usecase, atau
+// organisasi default untuk pendaftaran publik dan job sistem
+func organizationForCreate(ctx context.Context, db *gorm.DB, current *uint) (*uint, error) {
+	if orgID, ok := domain.OrganizationFromContext(ctx); ok {
+		return &orgID, nil
+	}
+	if current != nil {
+		return current, nil
+	}
+	var org domain.Organization
+	if err := db.WithContext(ctx).Where("slug = ?", domain.DefaultOrganizationSlug).First(&org).Error; err != nil {
+		return nil, errors.New("default organization not found")
+	}
+	return &org.ID, nil
+}
+
 // ========== USER REPOSITORY ==========
 
 type userRepo struct {
@@ -26,6 +70,15 @@ func (r *userRepo) UpdateLastLogin(ctx context.Context, userID uint) error {
 		Update("last_login_at", now).Error
 }
 func (r *userRepo) Create(ctx context.Context, user *domain.User) error {
+	if user.Role == domain.RoleSuperAdmin {
+		user.OrganizationID = nil
+	} else {
+		orgID, err := organizationForCreate(ctx, r.db, user.OrganizationID)
+		if err != nil {
+			return err
+		}
+		user.OrganizationID = orgID
+	}
 	return r.db.WithContext(ctx).Create(user).Error
 }
 
@@ -33,16 +86,16 @@ func (r *userRepo) GetByEmail(ctx context.Context, email string) (*domain.User,
 	var user domain.User
 	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("user not found")
+		return nil, domain.ErrUserNotFound
 	}
 	return &user, err
 }
 
 func (r *userRepo) GetByID(ctx context.Context, id uint) (*domain.User, error) {
 	var user domain.User
-	err := r.db.WithContext(ctx).First(&user, id).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).First(&user, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("user not found")
+		return nil, domain.ErrUserNotFound
 	}
 	return &user, err
 }
@@ -52,32 +105,56 @@ func (r *userRepo) GetByIDs(ctx context.Context, ids []uint) ([]domain.User, err
 	if len(ids) == 0 {
 		return users, nil
 	}
-	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Where("id IN ?", ids).Find(&users).Error
 	return users, err
 }
 
 func (r *userRepo) GetByRole(ctx context.Context, role domain.Role) ([]domain.User, error) {
 	var users []domain.User
-	err := r.db.WithContext(ctx).Where("role = ?", role).Find(&users).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Where("role = ?", role).Find(&users).Error
 	return users, err
 }
 
 func (r *userRepo) GetAll(ctx context.Context) ([]domain.User, error) {
 	var users []domain.User
-	err := r.db.WithContext(ctx).Find(&users).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Find(&users).Error
 	return users, err
 }
 
+var userListSpec = listSpec[domain.User]{
+	sorts: map[string]sortField[domain.User]{
+		"created_at": {"created_at", func(u domain.User) any { return u.CreatedAt }},
+		"name":       {"name", func(u domain.User) any { return u.Name }},
+		"email":      {"email", func(u domain.User) any { return u.Email }},
+	},
+	defaultSort: "created_at",
+	id:          func(u domain.User) uint { return u.ID },
+}
+
+func (r *userRepo) List(ctx context.Context, q domain.ListQuery) ([]domain.User, *domain.PageInfo, error) {
+	query := r.db.WithContext(ctx).Model(&domain.User{}).
+		Scopes(scopeOrg(ctx, "organization_id"), scopeDateRange(q, "created_at"))
+	if q.Role != "" {
+		query = query.Where("role = ?", q.Role)
+	}
+	return listPage(query, q, userListSpec)
+}
+
 func (r *userRepo) SearchStudents(ctx context.Context, searchTerm string) ([]domain.User, error) {
 	var users []domain.User
-	query := r.db.WithContext(ctx).Where("role = ?", domain.RoleStudent)
+	query := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Where("role = ?", domain.RoleStudent)
 	
 	if searchTerm != "" {
+		// ILIKE untuk potongan nama/email, trigram (pg_trgm) untuk nama yang salah ketik
 		searchPattern := "%" + searchTerm + "%"
-		query = query.Where("name ILIKE ? OR email ILIKE ?", searchPattern, searchPattern)
+		query = query.
+			Where("name ILIKE ? OR email ILIKE ? OR ? <% name", searchPattern, searchPattern, searchTerm).
+			Clauses(clause.OrderBy{Expression: clause.Expr{SQL: "word_similarity(?, name) DESC, name ASC", Vars: []interface{}{searchTerm}}})
+	} else {
+		query = query.Order("name ASC")
 	}
 	
-	err := query.Order("name ASC").Find(&users).Error
+	err := query.Find(&users).Error
 	return users, err
 }
 
@@ -90,12 +167,12 @@ func (r *userRepo) UpdateVerified(ctx context.Context, email string) error {
 }
 
 func (r *userRepo) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.User{}, id).Error
+	return r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Delete(&domain.User{}, id).Error
 }
 
 func (r *userRepo) CountByRole(ctx context.Context, role domain.Role) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&domain.User{}).Where("role = ?", role).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&domain.User{}).Scopes(scopeOrg(ctx, "organization_id")).Where("role = ?", role).Count(&count).Error
 	return count, err
 }
 
@@ -110,6 +187,11 @@ func NewCourseRepository(db *gorm.DB) domain.CourseRepository {
 }
 
 func (r *courseRepo) Create(ctx context.Context, course *domain.Course) error {
+	orgID, err := organizationForCreate(ctx, r.db, course.OrganizationID)
+	if err != nil {
+		return err
+	}
+	course.OrganizationID = orgID
 	return r.db.WithContext(ctx).Create(course).Error
 }
 
@@ -119,38 +201,108 @@ func (r *courseRepo) Update(ctx context.Context, course *domain.Course) error {
 
 func (r *courseRepo) GetAll(ctx context.Context) ([]domain.Course, error) {
 	var courses []domain.Course
-	err := r.db.WithContext(ctx).Preload("Instructor").Find(&courses).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Preload("Instructor").Find(&courses).Error
 	return courses, err
 }
 
 func (r *courseRepo) GetPublished(ctx context.Context) ([]domain.Course, error) {
 	var courses []domain.Course
-	err := r.db.WithContext(ctx).Where("is_published = ?", true).Preload("Instructor").Find(&courses).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Where("is_published = ?", true).Preload("Instructor").Find(&courses).Error
 	return courses, err
 }
 
+var courseListSpec = listSpec[domain.Course]{
+	sorts: map[string]sortField[domain.Course]{
+		"created_at": {"created_at", func(c domain.Course) any { return c.CreatedAt }},
+		"title":      {"title", func(c domain.Course) any { return c.Title }},
+	},
+	defaultSort: "created_at",
+	id:          func(c domain.Course) uint { return c.ID },
+	preloads:    []string{"Instructor"},
+}
+
+func (r *courseRepo) List(ctx context.Context, q domain.ListQuery) ([]domain.Course, *domain.PageInfo, error) {
+	query := r.db.WithContext(ctx).Model(&domain.Course{}).
+		Scopes(scopeOrg(ctx, "organization_id"), scopeDateRange(q, "created_at"))
+	if q.Published != nil {
+		query = query.Where("is_published = ?", *q.Published)
+	}
+	if q.InstructorID != nil {
+		query = query.Where("instructor_id = ?", *q.InstructorID)
+	}
+	return listPage(query, q, courseListSpec)
+}
+
+// SearchPublished mencari course published dengan full-text search (kolom search_vector,
+// judul berbobot lebih tinggi dari deskripsi, setiap term juga cocok sebagai prefix) dan
+// trigram untuk judul atau nama instructor yang salah ketik
+func (r *courseRepo) SearchPublished(ctx context.Context, query string, limit int) ([]domain.CourseSearchHit, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	tsquery := prefixTSQuery(terms)
+	phrase := strings.Join(terms, " ")
+
+	var hits []domain.CourseSearchHit
+	err := r.db.WithContext(ctx).
+		Table("courses").
+		Select(`courses.id, courses.title, courses.description, courses.thumbnail, courses.instructor_id,
+			users.name AS instructor_name,
+			ts_rank(courses.search_vector, to_tsquery('simple', ?)) * 2
+				+ word_similarity(?, courses.title)
+				+ word_similarity(?, users.name) * 0.5 AS rank`, tsquery, phrase, phrase).
+		Joins("JOIN users ON users.id = courses.instructor_id").
+		Scopes(scopeOrg(ctx, "courses.organization_id")).
+		Where("courses.is_published = ?", true).
+		Where("courses.search_vector @@ to_tsquery('simple', ?) OR ? <% courses.title OR ? <% users.name", tsquery, phrase, phrase).
+		Order("rank DESC, courses.id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+// prefixTSQuery menggabungkan term dengan AND dan menjadikan setiap term prefix
+// ("pyth dasar" -> "pyth:* & dasar:*"). Aman karena Terms hanya berisi huruf dan angka.
+func prefixTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
 func (r *courseRepo) GetByID(ctx context.Context, id uint) (*domain.Course, error) {
 	var course domain.Course
-	err := r.db.WithContext(ctx).First(&course, id).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).First(&course, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("course not found")
+		return nil, domain.ErrCourseNotFound
 	}
 	return &course, err
 }
 
+func (r *courseRepo) GetByIDs(ctx context.Context, ids []uint) ([]domain.Course, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var courses []domain.Course
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Where("id IN ?", ids).Find(&courses).Error
+	return courses, err
+}
+
 func (r *courseRepo) GetByInstructorID(ctx context.Context, instructorID uint) ([]domain.Course, error) {
 	var courses []domain.Course
-	err := r.db.WithContext(ctx).Where("instructor_id = ?", instructorID).Find(&courses).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Where("instructor_id = ?", instructorID).Find(&courses).Error
 	return courses, err
 }
 
 func (r *courseRepo) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Course{}, id).Error
+	return r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Delete(&domain.Course{}, id).Error
 }
 
 func (r *courseRepo) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&domain.Course{}).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&domain.Course{}).Scopes(scopeOrg(ctx, "organization_id")).Count(&count).Error
 	return count, err
 }
 
@@ -281,9 +433,9 @@ func (r *assignmentRepo) Create(ctx context.Context, assignment *domain.Assignme
 
 func (r *assignmentRepo) GetByID(ctx context.Context, id uint) (*domain.Assignment, error) {
 	var assignment domain.Assignment
-	err := r.db.WithContext(ctx).Preload("User").Preload("GradedBy").First(&assignment, id).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrgByUser(ctx, "user_id")).Preload("User").Preload("GradedBy").First(&assignment, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("assignment not found")
+		return nil, domain.ErrAssignmentNotFound
 	}
 	return &assignment, err
 }
@@ -338,6 +490,7 @@ func (r *assignmentRepo) GetRecentSubmissionsByUserID(ctx context.Context, userI
 func (r *assignmentRepo) GetRecentSubmissions(ctx context.Context, limit int) ([]domain.Assignment, error) {
 	var assignments []domain.Assignment
 	err := r.db.WithContext(ctx).
+		Scopes(scopeOrgByUser(ctx, "user_id")).
 		Preload("User").
 		Order("submitted_at DESC").
 		Limit(limit).
@@ -433,6 +586,11 @@ func NewLabRepository(db *gorm.DB) domain.LabRepository {
 }
 
 func (r *labRepo) Create(ctx context.Context, lab *domain.Lab) error {
+	orgID, err := organizationForCreate(ctx, r.db, lab.OrganizationID)
+	if err != nil {
+		return err
+	}
+	lab.OrganizationID = orgID
 	return r.db.WithContext(ctx).Create(lab).Error
 }
 
@@ -442,16 +600,16 @@ func (r *labRepo) Update(ctx context.Context, lab *domain.Lab) error {
 
 func (r *labRepo) GetByID(ctx context.Context, id uint) (*domain.Lab, error) {
 	var lab domain.Lab
-	err := r.db.WithContext(ctx).First(&lab, id).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).First(&lab, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("lab not found")
+		return nil, domain.ErrLabNotFound
 	}
 	return &lab, err
 }
 
 func (r *labRepo) GetAll(ctx context.Context) ([]domain.Lab, error) {
 	var labs []domain.Lab
-	err := r.db.WithContext(ctx).Order("start_time DESC").Find(&labs).Error
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Order("start_time DESC").Find(&labs).Error
 	return labs, err
 }
 
@@ -459,19 +617,48 @@ func (r *labRepo) GetUpcoming(ctx context.Context) ([]domain.Lab, error) {
 	var labs []domain.Lab
 	now := time.Now()
 	err := r.db.WithContext(ctx).
+		Scopes(scopeOrg(ctx, "organization_id")).
 		Where("start_time > ? OR status = ?", now, "open").
 		Order("start_time ASC").
 		Find(&labs).Error
 	return labs, err
 }
 
+var labListSpec = listSpec[domain.Lab]{
+	sorts: map[string]sortField[domain.Lab]{
+		"start_time": {"start_time", func(l domain.Lab) any { return l.StartTime }},
+		"created_at": {"created_at", func(l domain.Lab) any { return l.CreatedAt }},
+		"title":      {"title", func(l domain.Lab) any { return l.Title }},
+	},
+	defaultSort: "start_time",
+	id:          func(l domain.Lab) uint { return l.ID },
+}
+
+func (r *labRepo) List(ctx context.Context, q domain.ListQuery) ([]domain.Lab, *domain.PageInfo, error) {
+	query := r.db.WithContext(ctx).Model(&domain.Lab{}).
+		Scopes(scopeOrg(ctx, "organization_id"), scopeDateRange(q, "start_time"))
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.InstructorID != nil {
+		query = query.Where("instructor_id = ?", *q.InstructorID)
+	}
+	return listPage(query, q, labListSpec)
+}
+
 func (r *labRepo) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Lab{}, id).Error
+	return r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Delete(&domain.Lab{}, id).Error
 }
 
 func (r *labRepo) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&domain.Lab{}).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&domain.Lab{}).Scopes(scopeOrg(ctx, "organization_id")).Count(&count).Error
+	return count, err
+}
+
+func (r *labRepo) CountByInstructorID(ctx context.Context, instructorID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Lab{}).Scopes(scopeOrg(ctx, "organization_id")).Where("instructor_id = ?", instructorID).Count(&count).Error
 	return count, err
 }
 
@@ -544,19 +731,30 @@ func (r *certRepo) GetByUserID(ctx context.Context, userID uint) ([]domain.Certi
 func (r *certRepo) GetByID(ctx context.Context, id uint) (*domain.Certificate, error) {
 	var cert domain.Certificate
 	err := r.db.WithContext(ctx).
+		Scopes(scopeOrgByUser(ctx, "user_id")).
 		Preload("User").
 		Preload("Course").
 		Preload("Lab").
 		First(&cert, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("certificate not found")
+		return nil, domain.ErrCertificateNotFound
 	}
 	return &cert, err
 }
 
+func (r *certRepo) GetAll(ctx context.Context) ([]domain.Certificate, error) {
+	var certs []domain.Certificate
+	err := r.db.WithContext(ctx).
+		Scopes(scopeOrgByUser(ctx, "user_id")).
+		Order("id ASC").
+		Find(&certs).Error
+	return certs, err
+}
+
 func (r *certRepo) GetPending(ctx context.Context) ([]domain.Certificate, error) {
 	var certs []domain.Certificate
 	err := r.db.WithContext(ctx).
+		Scopes(scopeOrgByUser(ctx, "user_id")).
 		Where("status = ?", "pending").
 		Preload("User").
 		Preload("Course").
@@ -566,6 +764,25 @@ func (r *certRepo) GetPending(ctx context.Context) ([]domain.Certificate, error)
 	return certs, err
 }
 
+var certListSpec = listSpec[domain.Certificate]{
+	sorts: map[string]sortField[domain.Certificate]{
+		"issue_date": {"issue_date", func(c domain.Certificate) any { return c.IssueDate }},
+		"title":      {"title", func(c domain.Certificate) any { return c.Title }},
+	},
+	defaultSort: "issue_date",
+	id:          func(c domain.Certificate) uint { return c.ID },
+	preloads:    []string{"User", "Course", "Lab"},
+}
+
+func (r *certRepo) List(ctx context.Context, q domain.ListQuery) ([]domain.Certificate, *domain.PageInfo, error) {
+	query := r.db.WithContext(ctx).Model(&domain.Certificate{}).
+		Scopes(scopeOrgByUser(ctx, "user_id"), scopeDateRange(q, "issue_date"))
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	return listPage(query, q, certListSpec)
+}
+
 func (r *certRepo) GetRecentByUserID(ctx context.Context, userID uint, limit int) ([]domain.Certificate, error) {
 	var certs []domain.Certificate
 	err := r.db.WithContext(ctx).
@@ -584,16 +801,796 @@ func (r *certRepo) Update(ctx context.Context, cert *domain.Certificate) error {
 
 func (r *certRepo) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&domain.Certificate{}).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&domain.Certificate{}).Scopes(scopeOrgByUser(ctx, "user_id")).Count(&count).Error
 	return count, err
 }
 
 func (r *certRepo) CountByStatus(ctx context.Context, status string) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&domain.Certificate{}).Where("status = ?", status).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&domain.Certificate{}).Scopes(scopeOrgByUser(ctx, "user_id")).Where("status = ?", status).Count(&count).Error
 	return count, err
 }
 
+// ========== SESSION REPOSITORY ==========
+
+type sessionRepo struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) domain.SessionRepository {
+	return &sessionRepo{db}
+}
+
+func (r *sessionRepo) Create(ctx context.Context, session *domain.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepo) GetByID(ctx context.Context, id uint) (*domain.Session, error) {
+	var session domain.Session
+	err := r.db.WithContext(ctx).First(&session, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("session not found")
+	}
+	return &session, err
+}
+
+func (r *sessionRepo) GetByRefreshTokenHash(ctx context.Context, hash string) (*domain.Session, error) {
+	var session domain.Session
+	err := r.db.WithContext(ctx).Where("refresh_token_hash = ?", hash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &session, err
+}
+
+func (r *sessionRepo) GetByPreviousTokenHash(ctx context.Context, hash string) (*domain.Session, error) {
+	var session domain.Session
+	err := r.db.WithContext(ctx).Where("previous_token_hash = ?", hash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &session, err
+}
+
+func (r *sessionRepo) Update(ctx context.Context, session *domain.Session) error {
+	return r.db.WithContext(ctx).Save(session).Error
+}
+
+func (r *sessionRepo) Rotate(ctx context.Context, session *domain.Session, currentHash string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&domain.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, currentHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  session.RefreshTokenHash,
+			"previous_token_hash": session.PreviousTokenHash,
+			"expires_at":          session.ExpiresAt,
+			"last_used_at":        session.LastUsedAt,
+			"ip_address":          session.IPAddress,
+			"user_agent":          session.UserAgent,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *sessionRepo) Revoke(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// GetPendingByEmail mengembalikan undangan terbaru yang masih berlaku untuk email ini
+func (r *invitationRepo) GetPendingByEmail(ctx context.Context, email string) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	err := r.db.WithContext(ctx).
+		Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", email, time.Now()).
+		Order("created_at DESC").First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &invitation, err
+}
+
+func (r *sessionRepo) RevokeAllByUserID(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ========== EMAIL VERIFICATION REPOSITORY ==========
+
+type emailVerificationRepo struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) domain.EmailVerificationRepository {
+	return &emailVerificationRepo{db}
+}
+
+func (r *emailVerificationRepo) Create(ctx context.Context, verification *domain.EmailVerification) error {
+	return r.db.WithContext(ctx).Create(verification).Error
+}
+
+func (r *emailVerificationRepo) GetLatestByUserID(ctx context.Context, userID uint) (*domain.EmailVerification, error) {
+	var verification domain.EmailVerification
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&verification).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &verification, err
+}
+
+func (r *emailVerificationRepo) Update(ctx context.Context, verification *domain.EmailVerification) error {
+	return r.db.WithContext(ctx).Save(verification).Error
+}
+
+func (r *emailVerificationRepo) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.EmailVerification{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *emailVerificationRepo) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&domain.EmailVerification{}).Error
+}
+
+// ========== PASSWORD RESET REPOSITORY ==========
+
+type passwordResetRepo struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) domain.PasswordResetRepository {
+	return &passwordResetRepo{db}
+}
+
+func (r *passwordResetRepo) Create(ctx context.Context, reset *domain.PasswordReset) error {
+	return r.db.WithContext(ctx).Create(reset).Error
+}
+
+func (r *passwordResetRepo) GetByTokenHash(ctx context.Context, hash string) (*domain.PasswordReset, error) {
+	var reset domain.PasswordReset
+	err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &reset, err
+}
+
+func (r *passwordResetRepo) Update(ctx context.Context, reset *domain.PasswordReset) error {
+	return r.db.WithContext(ctx).Save(reset).Error
+}
+
+func (r *passwordResetRepo) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.PasswordReset{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *passwordResetRepo) DeleteUnusedByUserID(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Delete(&domain.PasswordReset{}).Error
+}
+
+// ========== LOGIN THROTTLE REPOSITORY ==========
+
+type loginThrottleRepo struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) domain.LoginThrottleRepository {
+	return &loginThrottleRepo{db}
+}
+
+func (r *loginThrottleRepo) Get(ctx context.Context, scope domain.LockoutScope, identifier string) (*domain.LoginThrottle, error) {
+	var throttle domain.LoginThrottle
+	err := r.db.WithContext(ctx).Where("scope = ? AND identifier = ?", scope, identifier).First(&throttle).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &throttle, err
+}
+
+// RegisterFailure menambah penghitung gagal secara atomik (upsert).
+// Penghitung dimulai ulang jika kegagalan terakhir sudah di luar window.
+func (r *loginThrottleRepo) RegisterFailure(ctx context.Context, scope domain.LockoutScope, identifier string, window time.Duration) (*domain.LoginThrottle, error) {
+	var throttle domain.LoginThrottle
+	now := time.Now()
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO login_throttles (scope, identifier, failed_count, last_failed_at, updated_at)
+		VALUES (?, ?, 1, ?, ?)
+		ON CONFLICT (scope, identifier) DO UPDATE SET
+			failed_count = CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failed_count + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING *`,
+		scope, identifier, now, now, now.Add(-window),
+	).Scan(&throttle).Error
+	return &throttle, err
+}
+
+func (r *loginThrottleRepo) Lock(ctx context.Context, id uint, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.LoginThrottle{}).
+		Where("id = ?", id).
+		Update("locked_until", until).Error
+}
+
+func (r *loginThrottleRepo) Reset(ctx context.Context, scope domain.LockoutScope, identifier string) error {
+	return r.db.WithContext(ctx).
+		Where("scope = ? AND identifier = ?", scope, identifier).
+		Delete(&domain.LoginThrottle{}).Error
+}
+
+// ========== LOCKOUT EVENT REPOSITORY ==========
+
+type lockoutEventRepo struct {
+	db *gorm.DB
+}
+
+func NewLockoutEventRepository(db *gorm.DB) domain.LockoutEventRepository {
+	return &lockoutEventRepo{db}
+}
+
+func (r *lockoutEventRepo) Create(ctx context.Context, event *domain.LockoutEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *lockoutEventRepo) GetRecent(ctx context.Context, limit int) ([]domain.LockoutEvent, error) {
+	var events []domain.LockoutEvent
+	err := r.db.WithContext(ctx).
+		Scopes(scopeOrgByUser(ctx, "user_id")).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// ========== MFA REPOSITORY ==========
+
+type mfaRepo struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) domain.MFARepository {
+	return &mfaRepo{db}
+}
+
+func (r *mfaRepo) GetByUserID(ctx context.Context, userID uint) (*domain.UserMFA, error) {
+	var mfa domain.UserMFA
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&mfa).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &mfa, err
+}
+
+func (r *mfaRepo) Save(ctx context.Context, mfa *domain.UserMFA) error {
+	return r.db.WithContext(ctx).Save(mfa).Error
+}
+
+func (r *mfaRepo) Confirm(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.UserMFA{}).
+		Where("user_id = ? AND confirmed_at IS NULL", userID).
+		Updates(map[string]interface{}{"confirmed_at": time.Now(), "last_used_step": step})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *mfaRepo) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *mfaRepo) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.UserMFA{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes menghapus semua recovery code lama dan menyimpan yang baru
+func (r *mfaRepo) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []domain.MFARecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode menandai recovery code terpakai, false jika kode tidak valid/sudah dipakai
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *mfaRepo) CreateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error {
+	return r.db.WithContext(ctx).Create(challenge).Error
+}
+
+func (r *mfaRepo) GetChallengeByTokenHash(ctx context.Context, hash string) (*domain.MFAChallenge, error) {
+	var challenge domain.MFAChallenge
+	err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &challenge, err
+}
+
+func (r *mfaRepo) UpdateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error {
+	return r.db.WithContext(ctx).Save(challenge).Error
+}
+
+func (r *mfaRepo) UseChallenge(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *mfaRepo) GetPolicy(ctx context.Context, role domain.Role) (*domain.MFAPolicy, error) {
+	var policy domain.MFAPolicy
+	err := r.db.WithContext(ctx).Where("role = ?", role).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &policy, err
+}
+
+func (r *mfaRepo) GetPolicies(ctx context.Context) ([]domain.MFAPolicy, error) {
+	var policies []domain.MFAPolicy
+	err := r.db.WithContext(ctx).Order("role ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (r *mfaRepo) SavePolicy(ctx context.Context, policy *domain.MFAPolicy) error {
+	return r.db.WithContext(ctx).Save(policy).Error
+}
+
+// ========== OIDC REPOSITORY ==========
+
+type oidcRepo struct {
+	db *gorm.DB
+}
+
+func NewOIDCRepository(db *gorm.DB) domain.OIDCRepository {
+	return &oidcRepo{db}
+}
+
+func (r *oidcRepo) CreateState(ctx context.Context, state *domain.OIDCLoginState) error {
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+// ConsumeState mengambil sekaligus menghapus state agar tidak bisa dipakai dua kali
+func (r *oidcRepo) ConsumeState(ctx context.Context, stateHash string) (*domain.OIDCLoginState, error) {
+	var states []domain.OIDCLoginState
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ?", stateHash).
+		Delete(&states).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+
+	// Bersihkan state kadaluarsa yang tidak pernah diselesaikan
+	r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&domain.OIDCLoginState{})
+
+	return &states[0], nil
+}
+
+func (r *oidcRepo) GetIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &identity, err
+}
+
+func (r *oidcRepo) CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *oidcRepo) UpdateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	return r.db.WithContext(ctx).Save(identity).Error
+}
+
+// ========== API KEY REPOSITORY ==========
+
+type apiKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) domain.APIKeyRepository {
+	return &apiKeyRepo{db}
+}
+
+func (r *apiKeyRepo) Create(ctx context.Context, key *domain.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *apiKeyRepo) GetByID(ctx context.Context, id uint) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.WithContext(ctx).First(&key, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &key, err
+}
+
+// GetByKeyHash mengambil kunci beserta pemiliknya (dipakai saat autentikasi)
+func (r *apiKeyRepo) GetByKeyHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.WithContext(ctx).Preload("User").Where("key_hash = ?", hash).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &key, err
+}
+
+func (r *apiKeyRepo) GetByUserID(ctx context.Context, userID uint) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepo) Revoke(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&domain.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *apiKeyRepo) UpdateLastUsed(ctx context.Context, id uint, usedAt time.Time, ip string) error {
+	return r.db.WithContext(ctx).Model(&domain.APIKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ip}).Error
+}
+
+// ========== AUDIT LOG REPOSITORY ==========
+
+type auditLogRepo struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) domain.AuditLogRepository {
+	return &auditLogRepo{db}
+}
+
+func (r *auditLogRepo) Create(ctx context.Context, entry *domain.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *auditLogRepo) List(ctx context.Context, filter domain.AuditLogFilter) ([]domain.AuditLog, int64, error) {
+	query := r.db.WithContext(ctx).Model(&domain.AuditLog{}).Scopes(scopeOrgByUser(ctx, "actor_id"))
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.ImpersonatorID != nil {
+		query = query.Where("impersonator_id = ?", *filter.ImpersonatorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []domain.AuditLog
+	err := query.Preload("Actor").
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// ========== INVITATION REPOSITORY ==========
+
+type invitationRepo struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) domain.InvitationRepository {
+	return &invitationRepo{db}
+}
+
+func (r *invitationRepo) Create(ctx context.Context, invitation *domain.Invitation) error {
+	orgID, err := organizationForCreate(ctx, r.db, invitation.OrganizationID)
+	if err != nil {
+		return err
+	}
+	invitation.OrganizationID = orgID
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+func (r *invitationRepo) GetByID(ctx context.Context, id uint) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	err := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).First(&invitation, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrInvitationNotFound
+	}
+	return &invitation, err
+}
+
+func (r *invitationRepo) GetByTokenHash(ctx context.Context, hash string) (*domain.Invitation, error) {
+	var invitation domain.Invitation
+	err := r.db.WithContext(ctx).Preload("Course").Preload("Inviter").Where("token_hash = ?", hash).First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &invitation, err
+}
+
+func (r *invitationRepo) List(ctx context.Context, invitedBy *uint) ([]domain.Invitation, error) {
+	query := r.db.WithContext(ctx).Scopes(scopeOrg(ctx, "organization_id")).Preload("Course").Preload("Inviter")
+	if invitedBy != nil {
+		query = query.Where("invited_by = ?", *invitedBy)
+	}
+	var invitations []domain.Invitation
+	err := query.Order("created_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *invitationRepo) Update(ctx context.Context, invitation *domain.Invitation) error {
+	return r.db.WithContext(ctx).Omit("Course", "Inviter").Save(invitation).Error
+}
+
+// RevokePendingByEmail mencabut undangan lama yang belum dipakai untuk email yang sama
+func (r *invitationRepo) RevokePendingByEmail(ctx context.Context, email string) error {
+	return r.db.WithContext(ctx).Model(&domain.Invitation{}).
+		Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ========== ACCOUNT REPOSITORY ==========
+
+type accountRepo struct {
+	db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) domain.AccountRepository {
+	return &accountRepo{db}
+}
+
+// GetExport mengumpulkan semua baris milik user dari setiap tabel
+func (r *accountRepo) GetExport(ctx context.Context, userID uint) (*domain.AccountExport, error) {
+	db := r.db.WithContext(ctx)
+
+	export := &domain.AccountExport{ExportedAt: time.Now()}
+	if err := db.First(&export.User, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	queries := []struct {
+		query *gorm.DB
+		dest  interface{}
+	}{
+		{db.Preload("Course").Where("user_id = ?", userID), &export.Enrollments},
+		{db.Where("user_id = ?", userID), &export.ModuleProgress},
+		{db.Where("user_id = ?", userID), &export.Assignments},
+		{db.Preload("Lab").Where("user_id = ?", userID), &export.LabGrades},
+		{db.Where("user_id = ?", userID), &export.Certificates},
+		{db.Where("user_id = ?", userID), &export.Sessions},
+		{db.Where("user_id = ?", userID), &export.APIKeys},
+		{db.Where("user_id = ?", userID), &export.Identities},
+		{db.Where("actor_id = ?", userID).Order("created_at"), &export.AuditLog},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var mfa domain.UserMFA
+	if err := db.Where("user_id = ?", userID).First(&mfa).Error; err == nil {
+		export.MFA = &mfa
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var deletion domain.AccountDeletion
+	if err := db.Where("user_id = ?", userID).First(&deletion).Error; err == nil {
+		export.Deletion = &deletion
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// Purge menghapus user beserta data pribadinya dalam satu transaksi. Data milik orang
+// lain yang merujuk user ini (nilai, approval, audit log) dipertahankan tanpa identitasnya.
+func (r *accountRepo) Purge(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrUserNotFound
+			}
+			return err
+		}
+
+		// Lab tidak boleh kehilangan pemilik; usecase sudah menolak, ini menjaga lab
+		// yang dialihkan ke user ini setelah pengecekan
+		var labs int64
+		if err := tx.Model(&domain.Lab{}).Where("instructor_id = ?", userID).Count(&labs).Error; err != nil {
+			return err
+		}
+		if labs > 0 {
+			return domain.ErrAccountHasLabs
+		}
+
+		// Data pribadi user
+		owned := []interface{}{
+			&domain.Enrollment{},
+			&domain.ModuleProgress{},
+			&domain.Assignment{},
+			&domain.LabGrade{},
+			&domain.Certificate{},
+			&domain.EmailVerification{},
+			&domain.PasswordReset{},
+			&domain.MFARecoveryCode{},
+			&domain.UserMFA{},
+			&domain.MFAChallenge{},
+			&domain.UserIdentity{},
+			&domain.APIKey{},
+			&domain.AccountDeletion{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_id = ? OR impersonator_id = ?", userID, userID).Delete(&domain.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope = ? AND identifier = ?", domain.LockoutScopeAccount, user.Email).Delete(&domain.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope = ? AND identifier = ?", domain.LockoutScopeVerification, strconv.FormatUint(uint64(userID), 10)).Delete(&domain.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+
+		// Rujukan dari data orang lain: lepaskan tanpa menghapus datanya
+		anonymize := []struct {
+			model  interface{}
+			column string
+			value  interface{}
+		}{
+			{&domain.Assignment{}, "graded_by_id", nil},
+			{&domain.Certificate{}, "approved_by", nil},
+			{&domain.MFAPolicy{}, "updated_by", 0},
+			{&domain.LockoutEvent{}, "actor_id", nil},
+			{&domain.AuditLog{}, "impersonator_id", nil},
+		}
+		for _, a := range anonymize {
+			if err := tx.Model(a.model).Where(a.column+" = ?", userID).Update(a.column, a.value).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&domain.LockoutEvent{}).
+			Where("user_id = ? OR identifier = ?", userID, user.Email).
+			Updates(map[string]interface{}{"user_id": nil, "identifier": "deleted-user", "ip_address": "", "user_agent": ""}).Error; err != nil {
+			return err
+		}
+
+		// Audit log tetap ada, tapi tanpa identitas user
+		if err := tx.Model(&domain.AuditLog{}).
+			Where("actor_id = ?", userID).
+			Updates(map[string]interface{}{"actor_id": nil, "ip_address": "", "user_agent": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.AuditLog{}).
+			Where("target_type = ? AND target_id = ?", domain.AuditTargetUser, strconv.FormatUint(uint64(userID), 10)).
+			Update("changes", domain.AuditChanges{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+}
+
+func (r *accountRepo) CreateDeletion(ctx context.Context, deletion *domain.AccountDeletion) error {
+	return r.db.WithContext(ctx).Create(deletion).Error
+}
+
+func (r *accountRepo) GetDeletionByUserID(ctx context.Context, userID uint) (*domain.AccountDeletion, error) {
+	var deletion domain.AccountDeletion
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&deletion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &deletion, err
+}
+
+func (r *accountRepo) DeleteDeletion(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&domain.AccountDeletion{}).Error
+}
+
+func (r *accountRepo) GetDueDeletions(ctx context.Context, now time.Time) ([]domain.AccountDeletion, error) {
+	var deletions []domain.AccountDeletion
+	err := r.db.WithContext(ctx).Where("scheduled_for <= ?", now).Order("scheduled_for").Find(&deletions).Error
+	return deletions, err
+}
+
+// ========== ORGANIZATION REPOSITORY ==========
+
+type organizationRepo struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) domain.OrganizationRepository {
+	return &organizationRepo{db}
+}
+
+func (r *organizationRepo) Create(ctx context.Context, org *domain.Organization) error {
+	return r.db.WithContext(ctx).Create(org).Error
+}
+
+func (r *organizationRepo) GetByID(ctx context.Context, id uint) (*domain.Organization, error) {
+	var org domain.Organization
+	err := r.db.WithContext(ctx).First(&org, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrOrganizationNotFound
+	}
+	return &org, err
+}
+
+func (r *organizationRepo) GetBySlug(ctx context.Context, slug string) (*domain.Organization, error) {
+	var org domain.Organization
+	err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&org).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrOrganizationNotFound
+	}
+	return &org, err
+}
+
+func (r *organizationRepo) GetAll(ctx context.Context) ([]domain.Organization, error) {
+	var orgs []domain.Organization
+	err := r.db.WithContext(ctx).Order("name ASC").Find(&orgs).Error
+	return orgs, err
+}
+
+func (r *organizationRepo) Update(ctx context.Context, org *domain.Organization) error {
+	return r.db.WithContext(ctx).Save(org).Error
+}
+
+func (r *organizationRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.Organization{}, id).Error
+}
+
 // func (r *certRepo) CountByUserID(ctx context.Context, userID uint) (int64, error) {
 // 	var count int64
 // 	err := r.db.WithContext(ctx).Model(&domain.Certificate{}).Where("user_id = ?", userID).Count(&count).Error
@@ -0,0 +1,352 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/utils"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccountDeletionGracePeriod - Jeda antara permintaan hapus akun dan penghapusan data
+const AccountDeletionGracePeriod = 14 * 24 * time.Hour
+
+type accountUsecase struct {
+	accountRepo domain.AccountRepository
+	userRepo    domain.UserRepository
+	courseRepo  domain.CourseRepository
+	labRepo     domain.LabRepository
+	files       domain.UserFileStore
+	audit       domain.AuditUsecase
+	logger      *slog.Logger
+}
+
+func NewAccountUsecase(
+	ar domain.AccountRepository,
+	ur domain.UserRepository,
+	cr domain.CourseRepository,
+	lr domain.LabRepository,
+	files domain.UserFileStore,
+	audit domain.AuditUsecase,
+	logger *slog.Logger,
+) domain.AccountUsecase {
+	return &accountUsecase{
+		accountRepo: ar,
+		userRepo:    ur,
+		courseRepo:  cr,
+		labRepo:     lr,
+		files:       files,
+		audit:       audit,
+		logger:      logger,
+	}
+}
+
+// GetExport mengumpulkan data user dari Postgres dan daftar file GridFS miliknya
+func (uc *accountUsecase) GetExport(ctx context.Context, userID uint) (*domain.AccountExport, error) {
+	export, err := uc.accountRepo.GetExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := uc.files.ListByUploader(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	export.Files = files
+
+	uc.audit.Record(ctx, userID, domain.AuditAccountExport, domain.AuditTargetUser, strconv.FormatUint(uint64(userID), 10), nil, nil)
+	return export, nil
+}
+
+// WriteExportArchive menulis arsip zip: account.json, file GridFS di files/ dan
+// upload lokal (foto profil, tugas, sertifikat) di uploads/
+func (uc *accountUsecase) WriteExportArchive(ctx context.Context, export *domain.AccountExport, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	entry, err := zw.Create("account.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(entry)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return err
+	}
+
+	for _, file := range export.Files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := uc.copyStoredFile(ctx, zw, file); err != nil {
+			return err
+		}
+	}
+
+	for _, url := range localUploadURLs(export) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := copyLocalUpload(zw, url); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (uc *accountUsecase) copyStoredFile(ctx context.Context, zw *zip.Writer, file domain.StoredFile) error {
+	stream, err := uc.files.Open(ctx, file.ID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	entry, err := zw.Create(fmt.Sprintf("files/%s-%s", file.ID, filepath.Base(file.OriginalName)))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, stream)
+	return err
+}
+
+func copyLocalUpload(zw *zip.Writer, url string) error {
+	diskPath, ok := localUploadPath(url)
+	if !ok {
+		return nil
+	}
+
+	f, err := os.Open(diskPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	entry, err := zw.Create(path.Join("uploads", strings.TrimPrefix(url, uploadURLPrefix())))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, f)
+	return err
+}
+
+// RequestDeletion menjadwalkan penghapusan akun setelah masa tenggang
+func (uc *accountUsecase) RequestDeletion(ctx context.Context, userID uint, reason string) (*domain.AccountDeletion, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	existing, err := uc.accountRepo.GetDeletionByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, domain.NewConflict("account_deletion_scheduled", "account deletion already scheduled")
+	}
+
+	if err := uc.checkDeletable(ctx, user); err != nil {
+		return nil, err
+	}
+
+	deletion := &domain.AccountDeletion{
+		UserID:       userID,
+		Reason:       reason,
+		ScheduledFor: time.Now().Add(AccountDeletionGracePeriod),
+	}
+	if err := uc.accountRepo.CreateDeletion(ctx, deletion); err != nil {
+		return nil, err
+	}
+
+	uc.audit.Record(ctx, userID, domain.AuditAccountDeletionSchedule, domain.AuditTargetUser, strconv.FormatUint(uint64(userID), 10), nil, map[string]interface{}{
+		"scheduled_for": deletion.ScheduledFor,
+	})
+	return deletion, nil
+}
+
+// CancelDeletion membatalkan penghapusan akun selama masa tenggang
+func (uc *accountUsecase) CancelDeletion(ctx context.Context, userID uint) error {
+	existing, err := uc.accountRepo.GetDeletionByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return domain.NewNotFound("account_deletion_not_found", "no pending account deletion")
+	}
+
+	if err := uc.accountRepo.DeleteDeletion(ctx, userID); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, userID, domain.AuditAccountDeletionCancel, domain.AuditTargetUser, strconv.FormatUint(uint64(userID), 10), existing, nil)
+	return nil
+}
+
+// GetDeletion mengembalikan penghapusan yang sedang dijadwalkan; nil jika tidak ada
+func (uc *accountUsecase) GetDeletion(ctx context.Context, userID uint) (*domain.AccountDeletion, error) {
+	return uc.accountRepo.GetDeletionByUserID(ctx, userID)
+}
+
+// DeleteAccount langsung menghapus akun di kedua store. actorID 0 berarti penghapusan
+// terjadwal oleh sistem. File GridFS diproses lebih dulu; jika transaksi Postgres gagal,
+// pemanggilan ulang aman karena file yang sudah dihapus/dianonimkan tidak muncul lagi.
+func (uc *accountUsecase) DeleteAccount(ctx context.Context, userID, actorID uint) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+	if err := uc.checkDeletable(ctx, user); err != nil {
+		return err
+	}
+
+	// Upload lokal dicatat sebelum barisnya dihapus
+	export, err := uc.accountRepo.GetExport(ctx, userID)
+	if err != nil {
+		return err
+	}
+	localUploads := localUploadURLs(export)
+
+	files, err := uc.files.ListByUploader(ctx, userID)
+	if err != nil {
+		return err
+	}
+	deleted, kept := 0, 0
+	for _, file := range files {
+		// Materi course tetap dipakai student lain, hanya identitas uploader yang dihapus
+		if file.CourseID != 0 {
+			kept++
+			continue
+		}
+		if err := uc.files.Delete(ctx, file.ID); err != nil {
+			return err
+		}
+		deleted++
+	}
+	if err := uc.files.AnonymizeUploader(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := uc.accountRepo.Purge(ctx, userID); err != nil {
+		return err
+	}
+
+	for _, url := range localUploads {
+		diskPath, ok := localUploadPath(url)
+		if !ok {
+			continue
+		}
+		if err := os.Remove(diskPath); err != nil && !os.IsNotExist(err) {
+			uc.logger.WarnContext(ctx, "Failed to remove upload of deleted user", "path", diskPath, "deleted_user_id", userID, "error", err)
+		}
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditUserDelete, domain.AuditTargetUser, strconv.FormatUint(uint64(userID), 10), nil, map[string]interface{}{
+		"role":          user.Role,
+		"files_deleted": deleted,
+		"files_kept":    kept,
+	})
+	return nil
+}
+
+// PurgeDueDeletions menghapus akun yang masa tenggangnya sudah lewat.
+// Kegagalan satu akun tidak menghentikan yang lain; akun tersebut dicoba lagi nanti.
+func (uc *accountUsecase) PurgeDueDeletions(ctx context.Context) (int, error) {
+	due, err := uc.accountRepo.GetDueDeletions(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, deletion := range due {
+		if err := uc.DeleteAccount(ctx, deletion.UserID, 0); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to delete account", "deleted_user_id", deletion.UserID, "error", err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// checkDeletable menolak penghapusan yang akan merusak data orang lain
+func (uc *accountUsecase) checkDeletable(ctx context.Context, user *domain.User) error {
+	courses, err := uc.courseRepo.GetByInstructorID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(courses) > 0 {
+		return domain.NewConflict("account_has_courses", "transfer or delete your courses before deleting the account")
+	}
+
+	// Lab tanpa pemilik tidak bisa dikelola instruktur mana pun (lihat Authorize)
+	labs, err := uc.labRepo.CountByInstructorID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if labs > 0 {
+		return domain.ErrAccountHasLabs
+	}
+
+	if user.Role.IsAdmin() {
+		// Admin organisasi dihitung per organisasi, super admin di seluruh platform
+		countCtx := ctx
+		if user.OrganizationID != nil {
+			countCtx = domain.WithOrganization(ctx, *user.OrganizationID)
+		}
+		admins, err := uc.userRepo.CountByRole(countCtx, user.Role)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return domain.NewConflict("last_admin_account", "cannot delete the last admin account")
+		}
+	}
+	return nil
+}
+
+// localUploadURLs mengumpulkan URL file yang disimpan utils.HandleUpload di disk
+func localUploadURLs(export *domain.AccountExport) []string {
+	var urls []string
+	if export.User.ProfilePicture != "" {
+		urls = append(urls, export.User.ProfilePicture)
+	}
+	for _, a := range export.Assignments {
+		if a.FileURL != "" {
+			urls = append(urls, a.FileURL)
+		}
+	}
+	for _, cert := range export.Certificates {
+		if cert.URL != "" {
+			urls = append(urls, cert.URL)
+		}
+	}
+	return urls
+}
+
+func uploadURLPrefix() string {
+	return "/" + path.Clean(filepath.ToSlash(utils.UploadDirectory)) + "/"
+}
+
+// localUploadPath mengubah URL "/uploads/..." menjadi path di disk; false untuk URL
+// di luar direktori upload
+func localUploadPath(url string) (string, bool) {
+	prefix := uploadURLPrefix()
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	rel := path.Clean(strings.TrimPrefix(url, prefix))
+	if rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.Join(utils.UploadDirectory, filepath.FromSlash(rel)), true
+}
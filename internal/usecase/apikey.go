@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/utils"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeyDefaultTTL     = 90 * 24 * time.Hour
+	apiKeyMaxTTL         = 365 * 24 * time.Hour
+	apiKeyMaxActive      = 20
+	apiKeyLastUsedWindow = 1 * time.Minute // Batasi write last_used_at untuk script yang sering memanggil API
+)
+
+// CreateAPIKey membuat kunci baru. Scope harus termasuk permission role user;
+// kunci mentah hanya dikembalikan sekali dan yang disimpan hanya hash-nya.
+func (uc *authUsecase) CreateAPIKey(ctx context.Context, userID uint, name string, scopes []domain.Permission, ttl time.Duration) (*domain.NewAPIKey, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, domain.NewFieldError("name", "api_key_name_required", "api key name is required")
+	}
+	if len(scopes) == 0 {
+		return nil, domain.NewFieldError("scopes", "api_key_scopes_required", "at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !user.Role.Can(scope) {
+			return nil, domain.NewFieldError("scopes", "api_key_scope_not_allowed", "scope not allowed for your role: "+string(scope))
+		}
+	}
+
+	if ttl <= 0 {
+		ttl = apiKeyDefaultTTL
+	}
+	if ttl > apiKeyMaxTTL {
+		return nil, domain.NewFieldError("expires_in_days", "api_key_expiry_too_long", "api key expiry cannot exceed 365 days")
+	}
+
+	existing, err := uc.apiKeyRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	now := time.Now()
+	for i := range existing {
+		if existing[i].IsActive(now) {
+			active++
+		}
+	}
+	if active >= apiKeyMaxActive {
+		return nil, domain.NewConflict("api_key_limit_reached", "too many active api keys, revoke an existing key first")
+	}
+
+	prefix, err := utils.GenerateSecureToken(6)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	prefix = domain.APIKeyPrefix + prefix
+	rawKey := prefix + "." + secret
+
+	expiresAt := now.Add(ttl)
+	key := &domain.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(rawKey),
+		Scopes:    scopes,
+		ExpiresAt: &expiresAt,
+	}
+	if err := uc.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &domain.NewAPIKey{APIKey: *key, Key: rawKey}, nil
+}
+
+func (uc *authUsecase) ListAPIKeys(ctx context.Context, userID uint) ([]domain.APIKey, error) {
+	return uc.apiKeyRepo.GetByUserID(ctx, userID)
+}
+
+// RevokeAPIKey mencabut kunci milik user; kunci user lain diperlakukan tidak ada
+func (uc *authUsecase) RevokeAPIKey(ctx context.Context, userID, keyID uint) error {
+	key, err := uc.apiKeyRepo.GetByID(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	if key == nil || key.UserID != userID {
+		return domain.ErrAPIKeyNotFound
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return uc.apiKeyRepo.Revoke(ctx, keyID)
+}
+
+// AuthenticateAPIKey memvalidasi kunci mentah dan mengembalikannya beserta pemiliknya
+func (uc *authUsecase) AuthenticateAPIKey(ctx context.Context, rawKey string, client domain.ClientInfo) (*domain.APIKey, error) {
+	if !strings.HasPrefix(rawKey, domain.APIKeyPrefix) {
+		return nil, domain.NewUnauthorized("invalid_api_key", "invalid api key")
+	}
+
+	key, err := uc.apiKeyRepo.GetByKeyHash(ctx, utils.HashToken(rawKey))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key == nil || !key.IsActive(now) || key.User.ID == 0 {
+		return nil, domain.NewUnauthorized("invalid_api_key", "invalid, expired or revoked api key")
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyLastUsedWindow {
+		if err := uc.apiKeyRepo.UpdateLastUsed(ctx, key.ID, now, client.IPAddress); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to update api key last used", "api_key_id", key.ID, "error", err)
+		}
+		key.LastUsedAt = &now
+		key.LastUsedIP = client.IPAddress
+	}
+
+	return key, nil
+}
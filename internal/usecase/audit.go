@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"onlearn-backend/internal/domain"
+	"reflect"
+)
+
+const (
+	auditDefaultLimit = 50
+	auditMaxLimit     = 500
+)
+
+type auditUsecase struct {
+	auditRepo domain.AuditLogRepository
+	logger    *slog.Logger
+}
+
+func NewAuditUsecase(ar domain.AuditLogRepository, logger *slog.Logger) domain.AuditUsecase {
+	return &auditUsecase{auditRepo: ar, logger: logger}
+}
+
+// Record menyimpan satu entri audit. before/after adalah snapshot entity (nil untuk
+// create/delete); hanya field yang berubah yang disimpan. IP, user agent dan admin
+// yang sedang impersonasi diambil dari context request.
+func (uc *auditUsecase) Record(ctx context.Context, actorID uint, action domain.AuditAction, targetType, targetID string, before, after interface{}) {
+	client := domain.ClientInfoFromContext(ctx)
+	entry := &domain.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    diffSnapshots(before, after),
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+	}
+	if actorID != 0 {
+		entry.ActorID = &actorID
+	}
+	if adminID, ok := domain.ImpersonatorFromContext(ctx); ok {
+		entry.ImpersonatorID = &adminID
+	}
+
+	// Context request bisa sudah dibatalkan saat aksi utama selesai
+	if err := uc.auditRepo.Create(context.WithoutCancel(ctx), entry); err != nil {
+		uc.logger.ErrorContext(ctx, "Failed to record audit log", "action", action, "target_type", targetType, "target_id", targetID, "error", err)
+	}
+}
+
+func (uc *auditUsecase) GetLogs(ctx context.Context, filter domain.AuditLogFilter) ([]domain.AuditLog, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = auditDefaultLimit
+	}
+	if filter.Limit > auditMaxLimit {
+		filter.Limit = auditMaxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return uc.auditRepo.List(ctx, filter)
+}
+
+// diffSnapshots membandingkan representasi JSON dua snapshot per field.
+// Relasi (object/array bersarang) dilewati karena bukan bagian dari entity itu sendiri.
+func diffSnapshots(before, after interface{}) domain.AuditChanges {
+	b := snapshotFields(before)
+	a := snapshotFields(after)
+
+	changes := domain.AuditChanges{}
+	for key, bv := range b {
+		if av, ok := a[key]; !ok || !reflect.DeepEqual(av, bv) {
+			changes[key] = domain.AuditChange{Before: bv, After: a[key]}
+		}
+	}
+	for key, av := range a {
+		if _, ok := b[key]; !ok {
+			changes[key] = domain.AuditChange{After: av}
+		}
+	}
+	return changes
+}
+
+func snapshotFields(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+
+	for key, value := range fields {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			delete(fields, key)
+		}
+	}
+	delete(fields, "updated_at")
+	return fields
+}
@@ -2,25 +2,103 @@ package usecase
 
 import (
 	"context"
-	"errors"
-	"log"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"onlearn-backend/internal/domain"
 	"onlearn-backend/pkg/utils"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	verificationCodeLength  = 6
+	verificationCodeTTL     = 30 * time.Minute
+	verificationResendDelay = 60 * time.Second
+	verificationMaxAttempts = 5 // Per kode
+
+	// Batas salah kode per user dalam verificationAttemptWindow, termasuk lintas kirim ulang.
+	// Setelah tercapai verifikasi dan kirim ulang ditolak selama verificationLockDuration.
+	verificationMaxTotalAttempts = 15
+	verificationAttemptWindow    = 24 * time.Hour
+	verificationLockDuration     = 1 * time.Hour
+
+	passwordResetTTL  = 1 * time.Hour
+	passwordMinLength = 6
+
+	// Brute-force protection: setelah threshold tercapai, durasi kunci naik 2x
+	// setiap kegagalan berikutnya (dibatasi lockoutMaxDuration)
+	accountLockThreshold = 5
+	ipLockThreshold      = 20
+	loginFailureWindow   = 15 * time.Minute
+	lockoutBaseDuration  = 1 * time.Minute
+	lockoutMaxDuration   = 1 * time.Hour
+
+	// Refresh token lama yang dipakai lagi dalam jendela ini setelah rotasi dianggap
+	// request paralel dari klien yang sama (mis. beberapa tab), bukan pencurian
+	refreshReuseGrace = 30 * time.Second
+)
+
+var errPasswordTooShort = domain.NewFieldError("password", "password_too_short",
+	fmt.Sprintf("password must be at least %d characters", passwordMinLength))
+
 type authUsecase struct {
-	userRepo domain.UserRepository
+	userRepo         domain.UserRepository
+	sessionRepo      domain.SessionRepository
+	verificationRepo domain.EmailVerificationRepository
+	resetRepo        domain.PasswordResetRepository
+	throttleRepo     domain.LoginThrottleRepository
+	lockoutRepo      domain.LockoutEventRepository
+	mfaRepo          domain.MFARepository
+	apiKeyRepo       domain.APIKeyRepository
+	audit            domain.AuditUsecase
+	mailer           domain.Mailer
+	unverifiedPolicy domain.UnverifiedLoginPolicy
+	appURL           string
+	logger           *slog.Logger
 }
 
-func NewAuthUsecase(ur domain.UserRepository) domain.AuthUsecase {
-	return &authUsecase{userRepo: ur}
+func NewAuthUsecase(
+	ur domain.UserRepository,
+	sr domain.SessionRepository,
+	vr domain.EmailVerificationRepository,
+	prr domain.PasswordResetRepository,
+	ltr domain.LoginThrottleRepository,
+	ler domain.LockoutEventRepository,
+	mr domain.MFARepository,
+	akr domain.APIKeyRepository,
+	audit domain.AuditUsecase,
+	mailer domain.Mailer,
+	unverifiedPolicy domain.UnverifiedLoginPolicy,
+	appURL string,
+	logger *slog.Logger,
+) domain.AuthUsecase {
+	if unverifiedPolicy != domain.UnverifiedLoginBlock {
+		unverifiedPolicy = domain.UnverifiedLoginLimited
+	}
+	return &authUsecase{
+		userRepo:         ur,
+		sessionRepo:      sr,
+		verificationRepo: vr,
+		resetRepo:        prr,
+		throttleRepo:     ltr,
+		lockoutRepo:      ler,
+		mfaRepo:          mr,
+		apiKeyRepo:       akr,
+		audit:            audit,
+		mailer:           mailer,
+		unverifiedPolicy: unverifiedPolicy,
+		appURL:           strings.TrimRight(appURL, "/"),
+		logger:           logger,
+	}
 }
 
 func (uc *authUsecase) Register(ctx context.Context, user *domain.User) error {
 	existing, _ := uc.userRepo.GetByEmail(ctx, user.Email)
 	if existing != nil && existing.ID != 0 {
-		return errors.New("email already exists")
+		return domain.ErrEmailExists
 	}
 
 	hashed, err := utils.HashPassword(user.Password)
@@ -35,32 +113,372 @@ func (uc *authUsecase) Register(ctx context.Context, user *domain.User) error {
 		return err
 	}
 
-	go utils.SendEmail(user.Email, "Verify Your OnLearn Account", "Here is your verification code: FAKE-CODE")
+	if !user.IsVerified {
+		if err := uc.sendVerificationCode(ctx, user); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to send verification code", "target_user_id", user.ID, "error", err)
+		}
+	}
 	return nil
 }
 
-func (uc *authUsecase) Login(ctx context.Context, email, password string) (string, error) {
+func (uc *authUsecase) Login(ctx context.Context, email, password string, client domain.ClientInfo) (*domain.LoginResult, error) {
+	accountKey := strings.ToLower(strings.TrimSpace(email))
+
+	// Tolak lebih dulu jika akun atau IP sedang dikunci
+	if err := uc.checkLoginLock(ctx, accountKey, client.IPAddress); err != nil {
+		return nil, err
+	}
+
 	user, err := uc.userRepo.GetByEmail(ctx, email)
 	if err != nil || user.ID == 0 {
-		return "", errors.New("invalid credentials")
+		return nil, uc.registerLoginFailure(ctx, accountKey, nil, client)
+	}
+
+	// Akun tanpa password (import, OIDC) hanya bisa login setelah memasang password
+	if user.Password == "" || !utils.CheckPasswordHash(password, user.Password) {
+		return nil, uc.registerLoginFailure(ctx, accountKey, &user.ID, client)
+	}
+
+	// Password benar: penghitung akun dimulai ulang. Penghitung IP sengaja tidak
+	// direset agar penyerang tidak bisa menghapusnya dengan login ke akunnya sendiri.
+	if err := uc.throttleRepo.Reset(ctx, domain.LockoutScopeAccount, accountKey); err != nil {
+		uc.logger.WarnContext(ctx, "Failed to reset login throttle", "account", accountKey, "error", err)
 	}
 
-	if !utils.CheckPasswordHash(password, user.Password) {
-		return "", errors.New("invalid credentials")
+	return uc.authenticated(ctx, user, client)
+}
+
+// LoginWithIdentity melanjutkan login untuk user yang sudah diautentikasi pihak luar (mis. OIDC)
+func (uc *authUsecase) LoginWithIdentity(ctx context.Context, user *domain.User, client domain.ClientInfo) (*domain.LoginResult, error) {
+	return uc.authenticated(ctx, user, client)
+}
+
+// authenticated menerapkan aturan setelah identitas user terbukti (verifikasi email, 2FA)
+func (uc *authUsecase) authenticated(ctx context.Context, user *domain.User, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if !user.IsVerified && uc.unverifiedPolicy == domain.UnverifiedLoginBlock {
+		return nil, domain.ErrEmailNotVerified
 	}
 
+	// 2FA: login belum selesai sampai kode TOTP diverifikasi lewat VerifyMFA
+	purpose, err := uc.mfaPurpose(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if purpose != "" {
+		return uc.createMFAChallenge(ctx, user, purpose)
+	}
+
+	return uc.completeLogin(ctx, user, client)
+}
+
+// completeLogin mencatat waktu login lalu membuat session baru
+func (uc *authUsecase) completeLogin(ctx context.Context, user *domain.User, client domain.ClientInfo) (*domain.LoginResult, error) {
 	// Update last login timestamp
 	if err := uc.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
-		log.Printf("Warning: Failed to update last login: %v", err)
+		uc.logger.WarnContext(ctx, "Failed to update last login", "target_user_id", user.ID, "error", err)
+	}
+
+	tokens, err := uc.createSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LoginResult{Tokens: tokens}, nil
+}
+
+// checkLoginLock mengembalikan LoginLockedError jika akun atau IP masih dikunci
+func (uc *authUsecase) checkLoginLock(ctx context.Context, accountKey, ipAddress string) error {
+	now := time.Now()
+	var retryAfter time.Duration
+
+	for _, t := range uc.loginThrottleKeys(accountKey, ipAddress) {
+		throttle, err := uc.throttleRepo.Get(ctx, t.scope, t.identifier)
+		if err != nil {
+			return err
+		}
+		if throttle != nil && throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			if wait := throttle.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return &domain.LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// registerLoginFailure mencatat login gagal untuk akun dan IP, lalu mengunci
+// dengan exponential backoff jika threshold terlampaui
+func (uc *authUsecase) registerLoginFailure(ctx context.Context, accountKey string, userID *uint, client domain.ClientInfo) error {
+	var lockedFor time.Duration
+
+	for _, t := range uc.loginThrottleKeys(accountKey, client.IPAddress) {
+		throttle, err := uc.throttleRepo.RegisterFailure(ctx, t.scope, t.identifier, loginFailureWindow)
+		if err != nil {
+			uc.logger.WarnContext(ctx, "Failed to register login failure", "scope", t.scope, "identifier", t.identifier, "error", err)
+			continue
+		}
+		if throttle.FailedCount < t.threshold {
+			continue
+		}
+
+		duration := lockoutDuration(throttle.FailedCount - t.threshold)
+		lockedUntil := time.Now().Add(duration)
+		if err := uc.throttleRepo.Lock(ctx, throttle.ID, lockedUntil); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to lock login", "scope", t.scope, "identifier", t.identifier, "error", err)
+			continue
+		}
+		if duration > lockedFor {
+			lockedFor = duration
+		}
+
+		uc.logger.WarnContext(ctx, "Login locked after repeated failures",
+			"scope", t.scope,
+			"identifier", t.identifier,
+			"failed_count", throttle.FailedCount,
+			"locked_until", lockedUntil.Format(time.RFC3339),
+		)
+		event := &domain.LockoutEvent{
+			Action:      domain.LockoutActionLocked,
+			Scope:       t.scope,
+			Identifier:  t.identifier,
+			IPAddress:   client.IPAddress,
+			UserAgent:   client.UserAgent,
+			FailedCount: throttle.FailedCount,
+			LockedUntil: &lockedUntil,
+		}
+		if t.scope == domain.LockoutScopeAccount {
+			event.UserID = userID
+		}
+		if err := uc.lockoutRepo.Create(ctx, event); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to record lockout event", "error", err)
+		}
+	}
+
+	if lockedFor > 0 {
+		return &domain.LoginLockedError{RetryAfter: lockedFor}
+	}
+	return domain.ErrInvalidCredentials
+}
+
+type loginThrottleKey struct {
+	scope      domain.LockoutScope
+	identifier string
+	threshold  int
+}
+
+func (uc *authUsecase) loginThrottleKeys(accountKey, ipAddress string) []loginThrottleKey {
+	keys := []loginThrottleKey{{domain.LockoutScopeAccount, accountKey, accountLockThreshold}}
+	if ipAddress != "" {
+		keys = append(keys, loginThrottleKey{domain.LockoutScopeIP, ipAddress, ipLockThreshold})
 	}
+	return keys
+}
 
-	return utils.GenerateJWT(user.ID, string(user.Role))
+// lockoutDuration menghitung durasi kunci: base * 2^n, maksimal lockoutMaxDuration
+func lockoutDuration(n int) time.Duration {
+	duration := lockoutBaseDuration
+	for i := 0; i < n && duration < lockoutMaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > lockoutMaxDuration {
+		duration = lockoutMaxDuration
+	}
+	return duration
+}
+
+// UnlockAccount membuka kunci login akun (oleh admin) dan mencatat event-nya
+func (uc *authUsecase) UnlockAccount(ctx context.Context, userID, adminID uint) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+
+	accountKey := strings.ToLower(strings.TrimSpace(user.Email))
+	if err := uc.throttleRepo.Reset(ctx, domain.LockoutScopeAccount, accountKey); err != nil {
+		return err
+	}
+
+	return uc.lockoutRepo.Create(ctx, &domain.LockoutEvent{
+		Action:     domain.LockoutActionUnlocked,
+		Scope:      domain.LockoutScopeAccount,
+		Identifier: accountKey,
+		UserID:     &user.ID,
+		ActorID:    &adminID,
+	})
+}
+
+func (uc *authUsecase) GetLockoutEvents(ctx context.Context, limit int) ([]domain.LockoutEvent, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return uc.lockoutRepo.GetRecent(ctx, limit)
+}
+
+// createSession membuat session baru di server dan mengembalikan pasangan token
+func (uc *authUsecase) createSession(ctx context.Context, user *domain.User, client domain.ClientInfo) (*domain.AuthTokens, error) {
+	refreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &domain.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		ExpiresAt:        now.Add(utils.RefreshTokenTTL),
+		LastUsedAt:       now,
+	}
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return uc.issueTokens(user, session.ID, refreshToken)
+}
+
+func (uc *authUsecase) issueTokens(user *domain.User, sessionID uint, refreshToken string) (*domain.AuthTokens, error) {
+	accessToken, err := utils.GenerateJWT(utils.Claims{
+		UserID:         user.ID,
+		Role:           string(user.Role),
+		SessionID:      sessionID,
+		Verified:       user.IsVerified,
+		OrganizationID: user.OrgID(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// RefreshToken menukar refresh token dengan pasangan token baru (rotasi).
+// Refresh token lama yang dipakai ulang dianggap bocor dan session-nya dicabut,
+// kecuali masih dalam refreshReuseGrace sejak rotasi terakhir.
+func (uc *authUsecase) RefreshToken(ctx context.Context, refreshToken string, client domain.ClientInfo) (*domain.AuthTokens, error) {
+	hash := utils.HashToken(refreshToken)
+
+	session, err := uc.sessionRepo.GetByRefreshTokenHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return uc.refreshReused(ctx, hash)
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, domain.NewUnauthorized("session_expired", "session expired or revoked")
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	newRefreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session.PreviousTokenHash = session.RefreshTokenHash
+	session.RefreshTokenHash = utils.HashToken(newRefreshToken)
+	session.ExpiresAt = now.Add(utils.RefreshTokenTTL)
+	session.LastUsedAt = now
+	if client.IPAddress != "" {
+		session.IPAddress = client.IPAddress
+	}
+	if client.UserAgent != "" {
+		session.UserAgent = client.UserAgent
+	}
+	rotated, err := uc.sessionRepo.Rotate(ctx, session, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Request paralel dengan token yang sama menang lebih dulu
+		return uc.refreshReused(ctx, hash)
+	}
+
+	return uc.issueTokens(user, session.ID, newRefreshToken)
+}
+
+// refreshReused menangani refresh token yang sudah dirotasi. Dalam refreshReuseGrace
+// hanya access token baru yang diterbitkan (refresh token pemenang rotasi tetap berlaku);
+// di luar itu session dicabut karena token kemungkinan bocor.
+func (uc *authUsecase) refreshReused(ctx context.Context, hash string) (*domain.AuthTokens, error) {
+	invalid := domain.NewUnauthorized("invalid_refresh_token", "invalid refresh token")
+
+	reused, err := uc.sessionRepo.GetByPreviousTokenHash(ctx, hash)
+	if err != nil || reused == nil || reused.RevokedAt != nil {
+		return nil, invalid
+	}
+
+	now := time.Now()
+	if now.Sub(reused.LastUsedAt) <= refreshReuseGrace && now.Before(reused.ExpiresAt) {
+		user, err := uc.userRepo.GetByID(ctx, reused.UserID)
+		if err != nil {
+			return nil, domain.ErrUserNotFound
+		}
+		return uc.issueTokens(user, reused.ID, "")
+	}
+
+	uc.logger.WarnContext(ctx, "Refresh token reuse detected, revoking session", "session_id", reused.ID, "target_user_id", reused.UserID)
+	if err := uc.sessionRepo.Revoke(ctx, reused.ID); err != nil {
+		uc.logger.WarnContext(ctx, "Failed to revoke session", "session_id", reused.ID, "error", err)
+	}
+	return nil, invalid
+}
+
+func (uc *authUsecase) Logout(ctx context.Context, refreshToken string) error {
+	session, err := uc.sessionRepo.GetByRefreshTokenHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return domain.NewUnauthorized("invalid_refresh_token", "invalid refresh token")
+	}
+	return uc.sessionRepo.Revoke(ctx, session.ID)
+}
+
+func (uc *authUsecase) RevokeAllSessions(ctx context.Context, userID uint) error {
+	// User di luar organisasi pemanggil dianggap tidak ada
+	if _, err := uc.userRepo.GetByID(ctx, userID); err != nil {
+		return domain.ErrUserNotFound
+	}
+	return uc.sessionRepo.RevokeAllByUserID(ctx, userID)
+}
+
+// ValidateSession memastikan session milik access token masih aktif
+func (uc *authUsecase) ValidateSession(ctx context.Context, sessionID uint) error {
+	if sessionID == 0 {
+		return domain.NewUnauthorized("session_invalid", "token has no session")
+	}
+
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return domain.NewUnauthorized("session_invalid", "session not found")
+	}
+	if session.RevokedAt != nil {
+		return domain.NewUnauthorized("session_revoked", "session revoked")
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return domain.NewUnauthorized("session_expired", "session expired")
+	}
+	return nil
 }
 
 func (uc *authUsecase) UpdateUser(ctx context.Context, user *domain.User) error {
 	existingUser, err := uc.userRepo.GetByID(ctx, user.ID)
 	if err != nil {
-		return errors.New("user not found")
+		return domain.ErrUserNotFound
 	}
 
 	if user.Name != "" {
@@ -69,27 +487,310 @@ func (uc *authUsecase) UpdateUser(ctx context.Context, user *domain.User) error
 	if user.ProfilePicture != "" {
 		existingUser.ProfilePicture = user.ProfilePicture
 	}
-	if user.Password != "" {
-		hashedPassword, err := utils.HashPassword(user.Password)
-		if err != nil {
-			return err
-		}
-		existingUser.Password = hashedPassword
-	}
 
 	return uc.userRepo.Update(ctx, existingUser)
 }
 
+// ChangePassword mengganti password user sendiri setelah password lama dicocokkan.
+// Akun tanpa password (mis. dibuat lewat OIDC) harus memakai alur lupa password.
+// Admin yang sedang impersonasi tidak boleh mengambil alih akun dengan mengganti password.
+func (uc *authUsecase) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) error {
+	if _, impersonating := domain.ImpersonatorFromContext(ctx); impersonating {
+		return domain.NewForbidden("not_allowed_while_impersonating", "This action is not allowed while impersonating")
+	}
+	if len(newPassword) < passwordMinLength {
+		return errPasswordTooShort
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+	if user.Password == "" || !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return domain.NewFieldError("current_password", "invalid_current_password", "current password is incorrect")
+	}
+
+	hashed, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	return uc.userRepo.Update(ctx, user)
+}
+
 func (uc *authUsecase) VerifyEmail(ctx context.Context, email, code string) error {
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil || user.ID == 0 {
+		return domain.NewFieldError("code", "invalid_verification_code", "invalid verification code")
+	}
+	if user.IsVerified {
+		return nil
+	}
+	if err := uc.checkVerificationLock(ctx, user.ID); err != nil {
+		return err
+	}
+
+	verification, err := uc.verificationRepo.GetLatestByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if verification == nil || verification.UsedAt != nil {
+		return domain.NewFieldError("code", "invalid_verification_code", "invalid verification code")
+	}
+	if time.Now().After(verification.ExpiresAt) {
+		return domain.NewFieldError("code", "verification_code_expired", "verification code expired")
+	}
+	if verification.Attempts >= verificationMaxAttempts {
+		return domain.NewTooManyRequests("verification_attempts_exceeded", "too many attempts, please request a new code")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(verificationCodeHash(user.ID, code)), []byte(verification.CodeHash)) != 1 {
+		verification.Attempts++
+		if err := uc.verificationRepo.Update(ctx, verification); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to record verification attempt", "error", err)
+		}
+		return uc.registerVerificationFailure(ctx, user.ID)
+	}
+
+	used, err := uc.verificationRepo.MarkUsed(ctx, verification.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.NewFieldError("code", "invalid_verification_code", "invalid verification code")
+	}
+	if err := uc.throttleRepo.Reset(ctx, domain.LockoutScopeVerification, verificationThrottleKey(user.ID)); err != nil {
+		uc.logger.WarnContext(ctx, "Failed to reset verification throttle", "target_user_id", user.ID, "error", err)
+	}
+
 	return uc.userRepo.UpdateVerified(ctx, email)
 }
 
+func (uc *authUsecase) ResendVerification(ctx context.Context, email string) error {
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil || user.ID == 0 || user.IsVerified {
+		// Jangan bocorkan apakah email terdaftar
+		return nil
+	}
+	if err := uc.checkVerificationLock(ctx, user.ID); err != nil {
+		return err
+	}
+
+	latest, err := uc.verificationRepo.GetLatestByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if latest != nil {
+		wait := time.Until(latest.CreatedAt.Add(verificationResendDelay))
+		if wait > 0 {
+			return domain.NewTooManyRequests("verification_resend_too_soon",
+				fmt.Sprintf("please wait %d seconds before requesting a new code", int(wait.Seconds())+1))
+		}
+	}
+
+	if err := uc.sendVerificationCode(ctx, user); err != nil {
+		uc.logger.ErrorContext(ctx, "Failed to send verification code", "target_user_id", user.ID, "error", err)
+		return domain.NewUnavailable("verification_email_failed", "could not send verification code, please try again later")
+	}
+	return nil
+}
+
+// sendVerificationCode membuat kode baru (kode lama tidak berlaku lagi) lalu mengirimkannya via email
+func (uc *authUsecase) sendVerificationCode(ctx context.Context, user *domain.User) error {
+	code, err := utils.GenerateNumericCode(verificationCodeLength)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.verificationRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	verification := &domain.EmailVerification{
+		UserID:    user.ID,
+		CodeHash:  verificationCodeHash(user.ID, code),
+		ExpiresAt: time.Now().Add(verificationCodeTTL),
+	}
+	if err := uc.verificationRepo.Create(ctx, verification); err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(ctx, domain.Email{
+		To:       user.Email,
+		Template: domain.EmailTemplateVerification,
+		Locale:   user.Locale,
+		Data: map[string]interface{}{
+			"Name":             user.Name,
+			"Code":             code,
+			"ExpiresInMinutes": int(verificationCodeTTL.Minutes()),
+		},
+	})
+}
+
+// verificationCodeHash - Kode 6 digit hanya punya 10^6 kemungkinan, jadi di-HMAC dengan
+// secret server dan diikat ke user agar hash di database tidak bisa di-brute-force offline
+func verificationCodeHash(userID uint, code string) string {
+	return utils.HashCode("email-verification:"+verificationThrottleKey(userID), code)
+}
+
+func verificationThrottleKey(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// checkVerificationLock menolak verifikasi/kirim ulang selama user dikunci karena terlalu banyak salah kode
+func (uc *authUsecase) checkVerificationLock(ctx context.Context, userID uint) error {
+	throttle, err := uc.throttleRepo.Get(ctx, domain.LockoutScopeVerification, verificationThrottleKey(userID))
+	if err != nil {
+		return err
+	}
+	if throttle != nil && throttle.LockedUntil != nil && throttle.LockedUntil.After(time.Now()) {
+		return domain.NewTooManyRequests("verification_locked",
+			fmt.Sprintf("too many incorrect codes, try again in %d minutes", int(time.Until(*throttle.LockedUntil).Minutes())+1))
+	}
+	return nil
+}
+
+// registerVerificationFailure mencatat salah kode; penghitungnya per user sehingga tidak
+// ikut mulai dari nol saat kode baru diminta
+func (uc *authUsecase) registerVerificationFailure(ctx context.Context, userID uint) error {
+	invalid := domain.NewFieldError("code", "invalid_verification_code", "invalid verification code")
+
+	key := verificationThrottleKey(userID)
+	throttle, err := uc.throttleRepo.RegisterFailure(ctx, domain.LockoutScopeVerification, key, verificationAttemptWindow)
+	if err != nil {
+		uc.logger.WarnContext(ctx, "Failed to register verification failure", "target_user_id", userID, "error", err)
+		return invalid
+	}
+	if throttle.FailedCount < verificationMaxTotalAttempts {
+		return invalid
+	}
+
+	if err := uc.throttleRepo.Lock(ctx, throttle.ID, time.Now().Add(verificationLockDuration)); err != nil {
+		uc.logger.WarnContext(ctx, "Failed to lock email verification", "target_user_id", userID, "error", err)
+	}
+	uc.logger.WarnContext(ctx, "Email verification locked after repeated failures", "target_user_id", userID, "failed_count", throttle.FailedCount)
+	return domain.NewTooManyRequests("verification_locked",
+		fmt.Sprintf("too many incorrect codes, try again in %d minutes", int(verificationLockDuration.Minutes())))
+}
+
 func (uc *authUsecase) ForgotPassword(ctx context.Context, email string) error {
 	user, err := uc.userRepo.GetByEmail(ctx, email)
 	if err != nil || user.ID == 0 {
 		return nil
 	}
-	go utils.SendEmail(email, "Password Reset Request", "Here is your password reset link: FAKE-LINK")
+
+	link, err := uc.createPasswordResetLink(ctx, user.ID, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.mailer.Send(ctx, domain.Email{
+		To:       user.Email,
+		Template: domain.EmailTemplatePasswordReset,
+		Locale:   user.Locale,
+		Data: map[string]interface{}{
+			"Name":             user.Name,
+			"ResetLink":        link,
+			"ExpiresInMinutes": int(passwordResetTTL.Minutes()),
+		},
+	}); err != nil {
+		uc.logger.WarnContext(ctx, "Failed to send password reset email", "target_user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// createPasswordResetLink membuat token reset sekali pakai dan mengembalikan link-nya.
+// Hanya link terbaru yang berlaku.
+func (uc *authUsecase) createPasswordResetLink(ctx context.Context, userID uint, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := uc.resetRepo.DeleteUnusedByUserID(ctx, userID); err != nil {
+		return "", err
+	}
+
+	reset := &domain.PasswordReset{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := uc.resetRepo.Create(ctx, reset); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/reset-password?token=%s", uc.appURL, url.QueryEscape(token)), nil
+}
+
+// ResetPassword mengganti password memakai token reset, lalu mencabut semua session user
+func (uc *authUsecase) ResetPassword(ctx context.Context, token string, newPassword string) error {
+	if len(newPassword) < passwordMinLength {
+		return errPasswordTooShort
+	}
+
+	reset, err := uc.resetRepo.GetByTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if reset == nil || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return domain.NewFieldError("token", "invalid_reset_token", "invalid or expired reset token")
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, reset.UserID)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+
+	// Tandai token terpakai lebih dulu agar tidak bisa dipakai dua kali; hanya satu
+	// dari beberapa request paralel dengan token yang sama yang berhasil
+	used, err := uc.resetRepo.MarkUsed(ctx, reset.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.NewFieldError("token", "invalid_reset_token", "invalid or expired reset token")
+	}
+
+	hashed, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	// Token dikirim ke email user, jadi kepemilikan email sudah terbukti (mis. akun undangan)
+	user.IsVerified = true
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	return uc.sessionRepo.RevokeAllByUserID(ctx, user.ID)
+}
+
+// SetPassword - Admin (atau CLI) mengganti password user secara langsung.
+// Semua sesi user dicabut agar password lama tidak bisa dipakai lagi.
+func (uc *authUsecase) SetPassword(ctx context.Context, userID uint, newPassword string, actorID uint) error {
+	if len(newPassword) < passwordMinLength {
+		return errPasswordTooShort
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+
+	hashed, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	if err := uc.sessionRepo.RevokeAllByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditUserPasswordReset, domain.AuditTargetUser, strconv.FormatUint(uint64(user.ID), 10), nil, nil)
 	return nil
 }
 
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/utils"
+)
+
+func TestChangePassword(t *testing.T) {
+	hashed, err := utils.HashPassword("old-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		password      string // Hash password yang tersimpan; "" = akun tanpa password
+		current       string
+		newPassword   string
+		impersonating bool
+		wantErr       string // Kode error domain
+	}{
+		{name: "valid", password: hashed, current: "old-secret", newPassword: "new-secret"},
+		{name: "wrong current password", password: hashed, current: "guess", newPassword: "new-secret", wantErr: "invalid_current_password"},
+		{name: "missing current password", password: hashed, newPassword: "new-secret", wantErr: "invalid_current_password"},
+		{name: "account without password", current: "", newPassword: "new-secret", wantErr: "invalid_current_password"},
+		{name: "new password too short", password: hashed, current: "old-secret", newPassword: "abc", wantErr: "password_too_short"},
+		{name: "impersonating", password: hashed, current: "old-secret", newPassword: "new-secret", impersonating: true, wantErr: "not_allowed_while_impersonating"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeUserRepo{users: []*domain.User{{ID: 1, Email: "budi@example.com", Password: tt.password}}}
+			uc := &authUsecase{userRepo: repo}
+
+			ctx := context.Background()
+			if tt.impersonating {
+				ctx = domain.WithImpersonator(ctx, 99)
+			}
+			err := uc.ChangePassword(ctx, 1, tt.current, tt.newPassword)
+
+			stored := repo.users[0].Password
+			if tt.wantErr != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.wantErr {
+					t.Fatalf("ChangePassword() error = %v, want %s", err, tt.wantErr)
+				}
+				if stored != tt.password {
+					t.Errorf("password was changed despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangePassword() error = %v", err)
+			}
+			if !utils.CheckPasswordHash(tt.newPassword, stored) {
+				t.Errorf("stored hash does not match the new password")
+			}
+		})
+	}
+}
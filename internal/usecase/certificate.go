@@ -2,9 +2,9 @@ package usecase
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"onlearn-backend/internal/domain"
+	"strconv"
 	"time"
 )
 
@@ -15,6 +15,8 @@ type certificateUsecase struct {
 	userRepo   domain.UserRepository
 	courseRepo domain.CourseRepository
 	labRepo    domain.LabRepository
+	policy     domain.Policy
+	audit      domain.AuditUsecase
 }
 
 func NewCertificateUsecase(
@@ -22,12 +24,16 @@ func NewCertificateUsecase(
 	ur domain.UserRepository,
 	cour domain.CourseRepository,
 	lr domain.LabRepository,
+	policy domain.Policy,
+	audit domain.AuditUsecase,
 ) domain.CertificateUsecase {
 	return &certificateUsecase{
 		certRepo:   cr,
 		userRepo:   ur,
 		courseRepo: cour,
 		labRepo:    lr,
+		policy:     policy,
+		audit:      audit,
 	}
 }
 
@@ -35,34 +41,35 @@ func (uc *certificateUsecase) GenerateCertificate(ctx context.Context, userID ui
 	// Verify user exists
 	_, err := uc.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, domain.ErrUserNotFound
 	}
 
 	// Verify course or lab exists
 	if courseID != nil {
 		_, err := uc.courseRepo.GetByID(ctx, *courseID)
 		if err != nil {
-			return nil, errors.New("course not found")
+			return nil, domain.ErrCourseNotFound
 		}
 	}
 
 	if labID != nil {
 		_, err := uc.labRepo.GetByID(ctx, *labID)
 		if err != nil {
-			return nil, errors.New("lab not found")
+			return nil, domain.ErrLabNotFound
 		}
 	}
 
 	// In production, you would generate actual PDF here
 	// For now, use dummy URL
+	now := time.Now()
 	cert := &domain.Certificate{
 		UserID:    userID,
 		CourseID:  courseID,
 		LabID:     labID,
 		Title:     title,
-		URL:       fmt.Sprintf("/uploads/certificates/%s-%d.pdf", title, time.Now().Unix()),
+		URL:       certificateURL(title, now),
 		Status:    "pending",
-		IssueDate: time.Now(),
+		IssueDate: now,
 	}
 
 	if err := uc.certRepo.Create(ctx, cert); err != nil {
@@ -72,6 +79,50 @@ func (uc *certificateUsecase) GenerateCertificate(ctx context.Context, userID ui
 	return cert, nil
 }
 
+// certificateURL - Lokasi file PDF sertifikat; judul di-slug agar URL selalu valid
+func certificateURL(title string, issued time.Time) string {
+	return fmt.Sprintf("/uploads/certificates/%s-%d.pdf", slugify(title), issued.Unix())
+}
+
+// RegenerateCertificates membuat ulang file/URL sertifikat dari judul dan tanggal terbitnya
+// (semua sertifikat jika ids kosong). Status persetujuan tidak berubah.
+func (uc *certificateUsecase) RegenerateCertificates(ctx context.Context, ids []uint, actorID uint) (int, error) {
+	var certs []domain.Certificate
+	if len(ids) == 0 {
+		all, err := uc.certRepo.GetAll(ctx)
+		if err != nil {
+			return 0, err
+		}
+		certs = all
+	}
+	for _, id := range ids {
+		cert, err := uc.certRepo.GetByID(ctx, id)
+		if err != nil {
+			return 0, fmt.Errorf("certificate %d: %w", id, err)
+		}
+		certs = append(certs, *cert)
+	}
+
+	regenerated := 0
+	for i := range certs {
+		cert := &certs[i]
+		url := certificateURL(cert.Title, cert.IssueDate)
+		if cert.URL == url {
+			continue
+		}
+
+		before := *cert
+		cert.URL = url
+		if err := uc.certRepo.Update(ctx, cert); err != nil {
+			return regenerated, fmt.Errorf("certificate %d: %w", cert.ID, err)
+		}
+		regenerated++
+
+		uc.audit.Record(ctx, actorID, domain.AuditCertificateRegenerate, domain.AuditTargetCertificate, strconv.FormatUint(uint64(cert.ID), 10), before, cert)
+	}
+	return regenerated, nil
+}
+
 func (uc *certificateUsecase) GetUserCertificates(ctx context.Context, userID uint) ([]domain.Certificate, error) {
 	return uc.certRepo.GetByUserID(ctx, userID)
 }
@@ -84,28 +135,34 @@ func (uc *certificateUsecase) GetPendingCertificates(ctx context.Context) ([]dom
 	return uc.certRepo.GetPending(ctx)
 }
 
+func (uc *certificateUsecase) ListPendingCertificates(ctx context.Context, q domain.ListQuery) ([]domain.Certificate, *domain.PageInfo, error) {
+	q.Status = "pending"
+	return uc.certRepo.List(ctx, q)
+}
+
 func (uc *certificateUsecase) ApproveCertificate(ctx context.Context, certID uint, approverID uint) error {
 	cert, err := uc.certRepo.GetByID(ctx, certID)
 	if err != nil {
 		return err
 	}
 
-	// Verify approver
-	approver, err := uc.userRepo.GetByID(ctx, approverID)
-	if err != nil {
-		return errors.New("approver not found")
-	}
-
-	if approver.Role != domain.RoleInstructor && approver.Role != domain.RoleAdmin {
-		return errors.New("only instructors and admins can approve certificates")
+	if err := uc.authorizeApproval(ctx, cert, approverID); err != nil {
+		return err
 	}
 
+	before := *cert
 	cert.Status = "approved"
 	cert.ApprovedBy = &approverID
 	now := time.Now()
 	cert.ApprovedAt = &now
 
-	return uc.certRepo.Update(ctx, cert)
+	if err := uc.certRepo.Update(ctx, cert); err != nil {
+		return err
+	}
+	certificateDecisionsTotal.WithLabelValues("approved").Inc()
+
+	uc.audit.Record(ctx, approverID, domain.AuditCertificateApprove, domain.AuditTargetCertificate, strconv.FormatUint(uint64(certID), 10), before, cert)
+	return nil
 }
 
 func (uc *certificateUsecase) RejectCertificate(ctx context.Context, certID uint, approverID uint) error {
@@ -114,63 +171,114 @@ func (uc *certificateUsecase) RejectCertificate(ctx context.Context, certID uint
 		return err
 	}
 
-	// Verify approver
-	approver, err := uc.userRepo.GetByID(ctx, approverID)
-	if err != nil {
-		return errors.New("approver not found")
-	}
-
-	if approver.Role != domain.RoleInstructor && approver.Role != domain.RoleAdmin {
-		return errors.New("only instructors and admins can reject certificates")
+	if err := uc.authorizeApproval(ctx, cert, approverID); err != nil {
+		return err
 	}
 
+	before := *cert
 	cert.Status = "rejected"
 	cert.ApprovedBy = &approverID
 	now := time.Now()
 	cert.ApprovedAt = &now
 
-	return uc.certRepo.Update(ctx, cert)
+	if err := uc.certRepo.Update(ctx, cert); err != nil {
+		return err
+	}
+	certificateDecisionsTotal.WithLabelValues("rejected").Inc()
+
+	uc.audit.Record(ctx, approverID, domain.AuditCertificateReject, domain.AuditTargetCertificate, strconv.FormatUint(uint64(certID), 10), before, cert)
+	return nil
+}
+
+// authorizeApproval - Instructor hanya boleh memproses sertifikat dari course/lab miliknya
+func (uc *certificateUsecase) authorizeApproval(ctx context.Context, cert *domain.Certificate, approverID uint) error {
+	var ownerID *uint
+	if cert.CourseID != nil {
+		course, err := uc.courseRepo.GetByID(ctx, *cert.CourseID)
+		if err != nil {
+			return domain.ErrCourseNotFound
+		}
+		ownerID = &course.InstructorID
+	} else if cert.LabID != nil {
+		lab, err := uc.labRepo.GetByID(ctx, *cert.LabID)
+		if err != nil {
+			return domain.ErrLabNotFound
+		}
+		ownerID = lab.InstructorID
+	}
+
+	return uc.policy.Authorize(ctx, approverID, domain.PermCertificateApprove, ownerID)
 }
 
 // ========== USER USECASE (for Admin CRUD) ==========
 
 type userUsecase struct {
-	userRepo domain.UserRepository
+	userRepo       domain.UserRepository
+	courseRepo     domain.CourseRepository
+	enrollmentRepo domain.EnrollmentRepository
+	labRepo        domain.LabRepository
+	invitations    domain.InvitationUsecase // Undangan untuk import massal
+	audit          domain.AuditUsecase
+	account        domain.AccountUsecase
 }
 
-func NewUserUsecase(ur domain.UserRepository) domain.UserUsecase {
+func NewUserUsecase(
+	ur domain.UserRepository,
+	cr domain.CourseRepository,
+	er domain.EnrollmentRepository,
+	lr domain.LabRepository,
+	invitations domain.InvitationUsecase,
+	audit domain.AuditUsecase,
+	account domain.AccountUsecase,
+) domain.UserUsecase {
 	return &userUsecase{
-		userRepo: ur,
+		userRepo:       ur,
+		courseRepo:     cr,
+		enrollmentRepo: er,
+		labRepo:        lr,
+		invitations:    invitations,
+		audit:          audit,
+		account:        account,
 	}
 }
 
-func (uc *userUsecase) CreateUser(ctx context.Context, user *domain.User) error {
+func (uc *userUsecase) CreateUser(ctx context.Context, user *domain.User, actorID uint) error {
+	if err := checkRoleAssignable(ctx, user.Role); err != nil {
+		return err
+	}
+
 	// Check if email exists
 	existing, _ := uc.userRepo.GetByEmail(ctx, user.Email)
 	if existing != nil {
-		return errors.New("email already exists")
+		return domain.ErrEmailExists
+	}
+
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		return err
 	}
 
-	return uc.userRepo.Create(ctx, user)
+	uc.audit.Record(ctx, actorID, domain.AuditUserCreate, domain.AuditTargetUser, strconv.FormatUint(uint64(user.ID), 10), nil, user)
+	return nil
 }
 
 func (uc *userUsecase) GetUserByID(ctx context.Context, id uint) (*domain.User, error) {
 	return uc.userRepo.GetByID(ctx, id)
 }
 
-func (uc *userUsecase) GetAllUsers(ctx context.Context) ([]domain.User, error) {
-	return uc.userRepo.GetAll(ctx)
+func (uc *userUsecase) ListUsers(ctx context.Context, q domain.ListQuery) ([]domain.User, *domain.PageInfo, error) {
+	return uc.userRepo.List(ctx, q)
 }
 
 func (uc *userUsecase) GetUsersByRole(ctx context.Context, role domain.Role) ([]domain.User, error) {
 	return uc.userRepo.GetByRole(ctx, role)
 }
 
-func (uc *userUsecase) UpdateUser(ctx context.Context, user *domain.User) error {
+func (uc *userUsecase) UpdateUser(ctx context.Context, user *domain.User, actorID uint) error {
 	existing, err := uc.userRepo.GetByID(ctx, user.ID)
 	if err != nil {
 		return err
 	}
+	before := *existing
 
 	// Update allowed fields
 	if user.Name != "" {
@@ -180,23 +288,50 @@ func (uc *userUsecase) UpdateUser(ctx context.Context, user *domain.User) error
 		// Check if new email is taken
 		emailCheck, _ := uc.userRepo.GetByEmail(ctx, user.Email)
 		if emailCheck != nil && emailCheck.ID != user.ID {
-			return errors.New("email already taken")
+			return domain.NewConflict("email_already_exists", "email already taken")
 		}
 		existing.Email = user.Email
 	}
-	if user.Role != "" {
+	if user.Role != "" && user.Role != existing.Role {
+		if err := checkRoleAssignable(ctx, user.Role); err != nil {
+			return err
+		}
+		if existing.Role == domain.RoleSuperAdmin {
+			return domain.NewValidation("super_admin_demotion", "demote super admins by assigning them to an organization")
+		}
 		existing.Role = user.Role
+		if existing.Role == domain.RoleSuperAdmin {
+			existing.OrganizationID = nil
+		}
 	}
 	if user.ProfilePicture != "" {
 		existing.ProfilePicture = user.ProfilePicture
 	}
 
-	return uc.userRepo.Update(ctx, existing)
+	if err := uc.userRepo.Update(ctx, existing); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditUserUpdate, domain.AuditTargetUser, strconv.FormatUint(uint64(existing.ID), 10), before, existing)
+	return nil
+}
+
+// checkRoleAssignable - Role super_admin hanya bisa diberikan oleh super admin
+// (request yang tidak dibatasi organisasi)
+func checkRoleAssignable(ctx context.Context, role domain.Role) error {
+	if role != domain.RoleSuperAdmin {
+		return nil
+	}
+	if _, scoped := domain.OrganizationFromContext(ctx); scoped {
+		return &domain.ForbiddenError{Permission: domain.PermOrganizationManage}
+	}
+	return nil
 }
 
-func (uc *userUsecase) DeleteUser(ctx context.Context, id uint) error {
-	// In production, you might want to check if user has enrollments, etc.
-	return uc.userRepo.Delete(ctx, id)
+// DeleteUser langsung menghapus akun beserta data terkait di Postgres dan GridFS
+// (tanpa masa tenggang), sama seperti penghapusan akun terjadwal
+func (uc *userUsecase) DeleteUser(ctx context.Context, id uint, actorID uint) error {
+	return uc.account.DeleteAccount(ctx, id, actorID)
 }
 
 // ========== REPORT USECASE ==========
@@ -266,10 +401,12 @@ func (uc *reportUsecase) GetStudentPerformance(ctx context.Context, userID uint)
 	}, nil
 }
 
-func (uc *reportUsecase) GetAllStudentsPerformance(ctx context.Context) ([]domain.StudentPerformance, error) {
-	students, err := uc.userRepo.GetByRole(ctx, domain.RoleStudent)
+// ListStudentsPerformance menghitung performa untuk satu halaman student saja
+func (uc *reportUsecase) ListStudentsPerformance(ctx context.Context, q domain.ListQuery) ([]domain.StudentPerformance, *domain.PageInfo, error) {
+	q.Role = domain.RoleStudent
+	students, page, err := uc.userRepo.List(ctx, q)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var performances []domain.StudentPerformance
@@ -281,7 +418,7 @@ func (uc *reportUsecase) GetAllStudentsPerformance(ctx context.Context) ([]domai
 		performances = append(performances, *perf)
 	}
 
-	return performances, nil
+	return performances, page, nil
 }
 
 func (uc *reportUsecase) GetCourseReport(ctx context.Context, courseID uint) (interface{}, error) {
@@ -2,9 +2,13 @@ package usecase
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/search"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type courseUsecase struct {
@@ -15,6 +19,8 @@ type courseUsecase struct {
 	assignmentRepo domain.AssignmentRepository
 	certRepo       domain.CertificateRepository
 	userRepo       domain.UserRepository
+	policy         domain.Policy
+	audit          domain.AuditUsecase
 }
 
 func NewCourseUsecase(
@@ -25,6 +31,8 @@ func NewCourseUsecase(
 	ar domain.AssignmentRepository,
 	certr domain.CertificateRepository,
 	ur domain.UserRepository,
+	policy domain.Policy,
+	audit domain.AuditUsecase,
 ) domain.CourseUsecase {
 	return &courseUsecase{
 		courseRepo:     cr,
@@ -34,21 +42,40 @@ func NewCourseUsecase(
 		assignmentRepo: ar,
 		certRepo:       certr,
 		userRepo:       ur,
+		policy:         policy,
+		audit:          audit,
 	}
 }
 
 // ========== COURSE CRUD ==========
 
-func (uc *courseUsecase) CreateCourse(ctx context.Context, course *domain.Course) error {
-	return uc.courseRepo.Create(ctx, course)
+func (uc *courseUsecase) CreateCourse(ctx context.Context, course *domain.Course, actorID uint) error {
+	if err := uc.policy.AuthorizeAction(ctx, actorID, domain.PermCourseCreate); err != nil {
+		return err
+	}
+
+	// Course selalu dimiliki pembuatnya
+	course.InstructorID = actorID
+	if err := uc.courseRepo.Create(ctx, course); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditCourseCreate, domain.AuditTargetCourse, strconv.FormatUint(uint64(course.ID), 10), nil, course)
+	return nil
 }
 
-func (uc *courseUsecase) UpdateCourse(ctx context.Context, course *domain.Course) error {
+func (uc *courseUsecase) UpdateCourse(ctx context.Context, course *domain.Course, actorID uint) error {
 	existing, err := uc.courseRepo.GetByID(ctx, course.ID)
 	if err != nil {
 		return err
 	}
 
+	if err := uc.policy.Authorize(ctx, actorID, domain.PermCourseUpdate, &existing.InstructorID); err != nil {
+		return err
+	}
+
+	before := *existing
+
 	// Update only allowed fields
 	existing.Title = course.Title
 	existing.Description = course.Description
@@ -56,14 +83,28 @@ func (uc *courseUsecase) UpdateCourse(ctx context.Context, course *domain.Course
 		existing.Thumbnail = course.Thumbnail
 	}
 
-	return uc.courseRepo.Update(ctx, existing)
+	if err := uc.courseRepo.Update(ctx, existing); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditCourseUpdate, domain.AuditTargetCourse, strconv.FormatUint(uint64(existing.ID), 10), before, existing)
+	return nil
 }
 
-func (uc *courseUsecase) DeleteCourse(ctx context.Context, id uint) error {
+func (uc *courseUsecase) DeleteCourse(ctx context.Context, id uint, actorID uint) error {
+	course, err := uc.courseRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.policy.Authorize(ctx, actorID, domain.PermCourseDelete, &course.InstructorID); err != nil {
+		return err
+	}
+
 	// Check if course has enrollments
 	enrollments, _ := uc.enrollmentRepo.GetByCourseID(ctx, id)
 	if len(enrollments) > 0 {
-		return errors.New("cannot delete course with existing enrollments")
+		return domain.NewConflict("course_has_enrollments", "cannot delete course with existing enrollments")
 	}
 
 	// Delete all modules (MongoDB)
@@ -72,7 +113,12 @@ func (uc *courseUsecase) DeleteCourse(ctx context.Context, id uint) error {
 		uc.moduleRepo.Delete(ctx, module.ID)
 	}
 
-	return uc.courseRepo.Delete(ctx, id)
+	if err := uc.courseRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditCourseDelete, domain.AuditTargetCourse, strconv.FormatUint(uint64(id), 10), course, nil)
+	return nil
 }
 
 func (uc *courseUsecase) GetAllCourses(ctx context.Context) ([]domain.Course, error) {
@@ -81,6 +127,123 @@ func (uc *courseUsecase) GetAllCourses(ctx context.Context) ([]domain.Course, er
 	return uc.courseRepo.GetPublished(ctx)
 }
 
+// ListCourses mengembalikan satu halaman course. Tanpa filter published hanya course
+// published yang dikembalikan; draft hanya untuk user yang boleh mem-publish course,
+// dan instructor hanya melihat draft miliknya.
+func (uc *courseUsecase) ListCourses(ctx context.Context, q domain.ListQuery, userID uint) ([]domain.Course, *domain.PageInfo, error) {
+	if q.Published == nil {
+		published := true
+		q.Published = &published
+	}
+	if !*q.Published {
+		// Tanpa owner hanya lolos untuk ScopeAny
+		if err := uc.policy.Authorize(ctx, userID, domain.PermCoursePublish, nil); err != nil {
+			if err := uc.policy.Authorize(ctx, userID, domain.PermCoursePublish, &userID); err != nil {
+				return nil, nil, err
+			}
+			q.InstructorID = &userID
+		}
+	}
+	return uc.courseRepo.List(ctx, q)
+}
+
+const (
+	searchMinQueryLen  = 2
+	searchMaxQueryLen  = 100
+	searchDefaultLimit = 10
+	searchMaxLimit     = 50
+	searchSnippetRunes = 160
+)
+
+// Search mencari course published (judul, deskripsi, nama instructor) dan module di
+// dalamnya (judul, deskripsi). limit berlaku untuk masing-masing jenis hasil.
+func (uc *courseUsecase) Search(ctx context.Context, query string, limit int) (*domain.SearchResults, error) {
+	query = strings.TrimSpace(query)
+	if n := utf8.RuneCountInString(query); n < searchMinQueryLen || n > searchMaxQueryLen {
+		return nil, domain.NewFieldError("q", "invalid_search_query",
+			fmt.Sprintf("Search query must be %d to %d characters", searchMinQueryLen, searchMaxQueryLen))
+	}
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return nil, domain.NewFieldError("q", "invalid_search_query", "Search query must contain a word of at least 2 letters or digits")
+	}
+	if limit <= 0 {
+		limit = searchDefaultLimit
+	}
+	if limit > searchMaxLimit {
+		limit = searchMaxLimit
+	}
+
+	courses, err := uc.courseRepo.SearchPublished(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Module hanya dicari di course published milik organisasi pemanggil (difilter di query)
+	modules, err := uc.moduleRepo.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	modules, err = uc.withCourseTitles(ctx, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	hl := search.NewHighlighter(terms)
+	for i := range courses {
+		c := &courses[i]
+		c.TitleHighlight = hl.Highlight(c.Title, 0)
+		c.DescriptionHighlight = hl.Highlight(c.Description, searchSnippetRunes)
+		c.InstructorHighlight = hl.Highlight(c.InstructorName, 0)
+	}
+	for i := range modules {
+		m := &modules[i]
+		m.TitleHighlight = hl.Highlight(m.Title, 0)
+		m.DescriptionHighlight = hl.Highlight(m.Description, searchSnippetRunes)
+	}
+
+	if courses == nil {
+		courses = []domain.CourseSearchHit{}
+	}
+	if modules == nil {
+		modules = []domain.ModuleSearchHit{}
+	}
+	return &domain.SearchResults{Query: query, Courses: courses, Modules: modules}, nil
+}
+
+// withCourseTitles mengisi CourseTitle hanya dari course hasil pencarian. Module yang
+// course-nya tidak terlihat atau sudah tidak published (status di module belum tersalin)
+// dibuang.
+func (uc *courseUsecase) withCourseTitles(ctx context.Context, modules []domain.ModuleSearchHit) ([]domain.ModuleSearchHit, error) {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, m := range modules {
+		if !seen[m.CourseID] {
+			seen[m.CourseID] = true
+			ids = append(ids, m.CourseID)
+		}
+	}
+	courses, err := uc.courseRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[uint]string, len(courses))
+	for _, c := range courses {
+		if c.IsPublished {
+			titles[c.ID] = c.Title
+		}
+	}
+
+	visible := modules[:0]
+	for _, m := range modules {
+		if title, ok := titles[m.CourseID]; ok {
+			m.CourseTitle = title
+			visible = append(visible, m)
+		}
+	}
+	return visible, nil
+}
+
 func (uc *courseUsecase) GetInstructorCourses(ctx context.Context, instructorID uint) ([]domain.Course, error) {
 	return uc.courseRepo.GetByInstructorID(ctx, instructorID)
 }
@@ -114,28 +277,81 @@ func (uc *courseUsecase) GetCourseDetails(ctx context.Context, courseID uint, us
 
 // ========== MODULE CRUD ==========
 
-func (uc *courseUsecase) AddModule(ctx context.Context, module *domain.Module) error {
-	// Verify course exists
-	_, err := uc.courseRepo.GetByID(ctx, module.CourseID)
+func (uc *courseUsecase) AddModule(ctx context.Context, module *domain.Module, actorID uint) error {
+	course, err := uc.authorizeModule(ctx, module.CourseID, actorID)
 	if err != nil {
-		return errors.New("course not found")
+		return err
+	}
+
+	// Module selalu ikut organisasi dan status publish course-nya
+	module.OrganizationID = 0
+	if course.OrganizationID != nil {
+		module.OrganizationID = *course.OrganizationID
+	}
+	module.CoursePublished = course.IsPublished
+	if err := uc.moduleRepo.Create(ctx, module); err != nil {
+		return err
 	}
 
-	return uc.moduleRepo.Create(ctx, module)
+	uc.audit.Record(ctx, actorID, domain.AuditModuleCreate, domain.AuditTargetModule, module.ID, nil, module)
+	return nil
 }
 
 func (uc *courseUsecase) GetModuleByID(ctx context.Context, moduleID string) (*domain.Module, error) {
 	return uc.moduleRepo.GetByID(ctx, moduleID)
 }
 
-func (uc *courseUsecase) UpdateModule(ctx context.Context, module *domain.Module) error {
-	return uc.moduleRepo.Update(ctx, module)
+func (uc *courseUsecase) UpdateModule(ctx context.Context, module *domain.Module, actorID uint) error {
+	existing, err := uc.moduleRepo.GetByID(ctx, module.ID)
+	if err != nil {
+		return err
+	}
+
+	// Module tidak boleh dipindah ke course lain lewat update
+	module.CourseID = existing.CourseID
+	if _, err := uc.authorizeModule(ctx, existing.CourseID, actorID); err != nil {
+		return err
+	}
+
+	if err := uc.moduleRepo.Update(ctx, module); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditModuleUpdate, domain.AuditTargetModule, module.ID, existing, module)
+	return nil
 }
 
-func (uc *courseUsecase) DeleteModule(ctx context.Context, moduleID string) error {
+func (uc *courseUsecase) DeleteModule(ctx context.Context, moduleID string, actorID uint) error {
+	existing, err := uc.moduleRepo.GetByID(ctx, moduleID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := uc.authorizeModule(ctx, existing.CourseID, actorID); err != nil {
+		return err
+	}
+
 	// Check if module has submissions
 	// We'll allow deletion for now, but in production you might want to prevent this
-	return uc.moduleRepo.Delete(ctx, moduleID)
+	if err := uc.moduleRepo.Delete(ctx, moduleID); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditModuleDelete, domain.AuditTargetModule, moduleID, existing, nil)
+	return nil
+}
+
+// authorizeModule mengecek permission module:manage terhadap pemilik course
+func (uc *courseUsecase) authorizeModule(ctx context.Context, courseID uint, actorID uint) (*domain.Course, error) {
+	course, err := uc.courseRepo.GetByID(ctx, courseID)
+	if err != nil {
+		return nil, domain.ErrCourseNotFound
+	}
+
+	if err := uc.policy.Authorize(ctx, actorID, domain.PermModuleManage, &course.InstructorID); err != nil {
+		return nil, err
+	}
+	return course, nil
 }
 
 // ========== ENROLLMENT ==========
@@ -144,13 +360,13 @@ func (uc *courseUsecase) EnrollStudent(ctx context.Context, userID, courseID uin
 	// Check if already enrolled
 	existing, _ := uc.enrollmentRepo.GetByUserAndCourse(ctx, userID, courseID)
 	if existing != nil {
-		return errors.New("already enrolled in this course")
+		return domain.NewConflict("already_enrolled", "already enrolled in this course")
 	}
 
 	// Verify course exists
 	_, err := uc.courseRepo.GetByID(ctx, courseID)
 	if err != nil {
-		return errors.New("course not found")
+		return domain.ErrCourseNotFound
 	}
 
 	enrollment := &domain.Enrollment{
@@ -159,7 +375,11 @@ func (uc *courseUsecase) EnrollStudent(ctx context.Context, userID, courseID uin
 		Progress: 0,
 	}
 
-	return uc.enrollmentRepo.Create(ctx, enrollment)
+	if err := uc.enrollmentRepo.Create(ctx, enrollment); err != nil {
+		return err
+	}
+	enrollmentsTotal.WithLabelValues("course", "self").Inc()
+	return nil
 }
 
 func (uc *courseUsecase) GetStudentEnrollments(ctx context.Context, userID uint) ([]domain.EnrollmentWithCourse, error) {
@@ -222,6 +442,7 @@ func (uc *courseUsecase) MarkModuleComplete(ctx context.Context, userID uint, mo
 			return err
 		}
 	}
+	moduleCompletionsTotal.Inc()
 
 	// Update enrollment progress
 	return uc.updateEnrollmentProgress(ctx, userID, courseID)
@@ -300,34 +521,50 @@ func (uc *courseUsecase) PublishCourse(ctx context.Context, courseID uint, instr
 	// Verify course exists and belongs to instructor
 	course, err := uc.courseRepo.GetByID(ctx, courseID)
 	if err != nil {
-		return errors.New("course not found")
+		return domain.ErrCourseNotFound
 	}
 
-	// Verify ownership
-	if course.InstructorID != instructorID {
-		return errors.New("unauthorized: course does not belong to instructor")
+	if err := uc.policy.Authorize(ctx, instructorID, domain.PermCoursePublish, &course.InstructorID); err != nil {
+		return err
 	}
 
 	// Publish course
+	before := *course
 	course.IsPublished = true
-	return uc.courseRepo.Update(ctx, course)
+	if err := uc.courseRepo.Update(ctx, course); err != nil {
+		return err
+	}
+	if err := uc.moduleRepo.SetCoursePublished(ctx, courseID, true); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, instructorID, domain.AuditCoursePublish, domain.AuditTargetCourse, strconv.FormatUint(uint64(courseID), 10), before, course)
+	return nil
 }
 
 func (uc *courseUsecase) UnpublishCourse(ctx context.Context, courseID uint, instructorID uint) error {
 	// Verify course exists and belongs to instructor
 	course, err := uc.courseRepo.GetByID(ctx, courseID)
 	if err != nil {
-		return errors.New("course not found")
+		return domain.ErrCourseNotFound
 	}
 
-	// Verify ownership
-	if course.InstructorID != instructorID {
-		return errors.New("unauthorized: course does not belong to instructor")
+	if err := uc.policy.Authorize(ctx, instructorID, domain.PermCoursePublish, &course.InstructorID); err != nil {
+		return err
 	}
 
 	// Unpublish course
+	before := *course
 	course.IsPublished = false
-	return uc.courseRepo.Update(ctx, course)
+	if err := uc.courseRepo.Update(ctx, course); err != nil {
+		return err
+	}
+	if err := uc.moduleRepo.SetCoursePublished(ctx, courseID, false); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, instructorID, domain.AuditCourseUnpublish, domain.AuditTargetCourse, strconv.FormatUint(uint64(courseID), 10), before, course)
+	return nil
 }
 
 func (uc *courseUsecase) GetModulesWithProgress(ctx context.Context, userID uint, courseID uint) ([]domain.ModuleWithProgress, error) {
@@ -370,11 +607,15 @@ func (uc *courseUsecase) SubmitAssignment(ctx context.Context, assignment *domai
 	// Check if already submitted
 	existing, _ := uc.assignmentRepo.GetByUserAndModule(ctx, assignment.UserID, assignment.ModuleID)
 	if existing != nil {
-		return errors.New("assignment already submitted")
+		return domain.NewConflict("assignment_already_submitted", "assignment already submitted")
 	}
 
 	assignment.SubmittedAt = time.Now()
-	return uc.assignmentRepo.Create(ctx, assignment)
+	if err := uc.assignmentRepo.Create(ctx, assignment); err != nil {
+		return err
+	}
+	submissionsTotal.Inc()
+	return nil
 }
 
 func (uc *courseUsecase) GradeAssignment(ctx context.Context, assignmentID uint, grade float64, feedback string, gradedByID uint) error {
@@ -383,13 +624,29 @@ func (uc *courseUsecase) GradeAssignment(ctx context.Context, assignmentID uint,
 		return err
 	}
 
+	course, err := uc.courseRepo.GetByID(ctx, assignment.CourseID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.policy.Authorize(ctx, gradedByID, domain.PermAssignmentGrade, &course.InstructorID); err != nil {
+		return err
+	}
+
+	before := *assignment
 	assignment.Grade = &grade
 	assignment.Feedback = feedback
 	assignment.GradedByID = &gradedByID
 	now := time.Now()
 	assignment.GradedAt = &now
 
-	return uc.assignmentRepo.Update(ctx, assignment)
+	if err := uc.assignmentRepo.Update(ctx, assignment); err != nil {
+		return err
+	}
+	gradesTotal.WithLabelValues("assignment").Inc()
+
+	uc.audit.Record(ctx, gradedByID, domain.AuditAssignmentGrade, domain.AuditTargetAssignment, strconv.FormatUint(uint64(assignmentID), 10), before, assignment)
+	return nil
 }
 
 func (uc *courseUsecase) GetCourseAssignments(ctx context.Context, courseID uint) ([]domain.Assignment, error) {
@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"onlearn-backend/internal/domain"
+)
+
+// fakeCourseRepo - GetPublished sengaja tidak diimplementasikan (panic jika dipanggil)
+type fakeCourseRepo struct {
+	domain.CourseRepository
+	courses []domain.Course
+}
+
+func (r *fakeCourseRepo) SearchPublished(ctx context.Context, query string, limit int) ([]domain.CourseSearchHit, error) {
+	return nil, nil
+}
+
+func (r *fakeCourseRepo) GetByID(ctx context.Context, id uint) (*domain.Course, error) {
+	for i := range r.courses {
+		if r.courses[i].ID == id {
+			return &r.courses[i], nil
+		}
+	}
+	return nil, domain.ErrCourseNotFound
+}
+
+func (r *fakeCourseRepo) GetByIDs(ctx context.Context, ids []uint) ([]domain.Course, error) {
+	var courses []domain.Course
+	for _, id := range ids {
+		if c, err := r.GetByID(ctx, id); err == nil {
+			courses = append(courses, *c)
+		}
+	}
+	return courses, nil
+}
+
+func (r *fakeCourseRepo) Update(ctx context.Context, course *domain.Course) error {
+	return nil
+}
+
+type fakeModuleRepo struct {
+	domain.ModuleRepository
+	hits      []domain.ModuleSearchHit
+	published map[uint]bool // Hasil SetCoursePublished per course
+}
+
+func (r *fakeModuleRepo) Search(ctx context.Context, query string, limit int) ([]domain.ModuleSearchHit, error) {
+	return r.hits, nil
+}
+
+func (r *fakeModuleRepo) SetCoursePublished(ctx context.Context, courseID uint, published bool) error {
+	if r.published == nil {
+		r.published = make(map[uint]bool)
+	}
+	r.published[courseID] = published
+	return nil
+}
+
+type allowAllPolicy struct{}
+
+func (allowAllPolicy) Authorize(ctx context.Context, userID uint, perm domain.Permission, ownerID *uint) error {
+	return nil
+}
+
+func (allowAllPolicy) AuthorizeAction(ctx context.Context, userID uint, perm domain.Permission) error {
+	return nil
+}
+
+type discardAudit struct {
+	domain.AuditUsecase
+}
+
+func (discardAudit) Record(ctx context.Context, actorID uint, action domain.AuditAction, targetType, targetID string, before, after interface{}) {
+}
+
+func TestSearchModules(t *testing.T) {
+	courses := []domain.Course{
+		{ID: 1, Title: "Dasar Jaringan", IsPublished: true},
+		{ID: 2, Title: "Draft Jaringan", IsPublished: false},
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		hits        []domain.ModuleSearchHit
+		wantErr     string // Kode error domain
+		wantModules []string
+		wantTitles  []string
+	}{
+		{
+			name:        "module of published course",
+			query:       "jaringan",
+			hits:        []domain.ModuleSearchHit{{ID: "m1", CourseID: 1, Title: "Topologi Jaringan"}},
+			wantModules: []string{"m1"},
+			wantTitles:  []string{"Dasar Jaringan"},
+		},
+		{
+			name:  "stale module of unpublished course is dropped",
+			query: "jaringan",
+			hits: []domain.ModuleSearchHit{
+				{ID: "m1", CourseID: 1, Title: "Topologi Jaringan"},
+				{ID: "m2", CourseID: 2, Title: "Subnet Jaringan"},
+			},
+			wantModules: []string{"m1"},
+			wantTitles:  []string{"Dasar Jaringan"},
+		},
+		{
+			name:        "module of course outside organization is dropped",
+			query:       "jaringan",
+			hits:        []domain.ModuleSearchHit{{ID: "m3", CourseID: 3, Title: "Jaringan Lain"}},
+			wantModules: []string{},
+			wantTitles:  []string{},
+		},
+		{
+			name:        "no module hits",
+			query:       "python",
+			wantModules: []string{},
+			wantTitles:  []string{},
+		},
+		{
+			name:    "query too short",
+			query:   "a",
+			wantErr: "invalid_search_query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &courseUsecase{
+				courseRepo: &fakeCourseRepo{courses: courses},
+				moduleRepo: &fakeModuleRepo{hits: tt.hits},
+			}
+
+			results, err := uc.Search(context.Background(), tt.query, 0)
+
+			if tt.wantErr != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.wantErr {
+					t.Fatalf("Search() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			ids, titles := []string{}, []string{}
+			for _, m := range results.Modules {
+				ids = append(ids, m.ID)
+				titles = append(titles, m.CourseTitle)
+			}
+			if !reflect.DeepEqual(ids, tt.wantModules) {
+				t.Errorf("modules = %v, want %v", ids, tt.wantModules)
+			}
+			if !reflect.DeepEqual(titles, tt.wantTitles) {
+				t.Errorf("course titles = %v, want %v", titles, tt.wantTitles)
+			}
+		})
+	}
+}
+
+func TestPublishCourseSyncsModules(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish bool
+	}{
+		{name: "publish", publish: true},
+		{name: "unpublish", publish: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modules := &fakeModuleRepo{}
+			uc := &courseUsecase{
+				courseRepo: &fakeCourseRepo{courses: []domain.Course{{ID: 1, InstructorID: 5, IsPublished: !tt.publish}}},
+				moduleRepo: modules,
+				policy:     allowAllPolicy{},
+				audit:      discardAudit{},
+			}
+
+			var err error
+			if tt.publish {
+				err = uc.PublishCourse(context.Background(), 1, 5)
+			} else {
+				err = uc.UnpublishCourse(context.Background(), 1, 5)
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if published, ok := modules.published[1]; !ok || published != tt.publish {
+				t.Errorf("modules course_published = %v (set %v), want %v", published, ok, tt.publish)
+			}
+		})
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"onlearn-backend/internal/domain"
 	"sort"
 	"time"
+
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("onlearn-backend/internal/usecase")
+
 type dashboardUsecase struct {
 	userRepo       domain.UserRepository
 	courseRepo     domain.CourseRepository
@@ -40,7 +44,12 @@ func NewDashboardUsecase(
 	}
 }
 
+// GetStudentDashboard memanggil banyak query per course yang diikuti; span-nya
+// mengelompokkan query tersebut di trace
 func (uc *dashboardUsecase) GetStudentDashboard(ctx context.Context, userID uint) (*domain.StudentDashboardData, error) {
+	ctx, span := tracer.Start(ctx, "DashboardUsecase.GetStudentDashboard")
+	defer span.End()
+
 	user, err := uc.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -194,6 +203,9 @@ func sortAndClassifyLabs(labs []domain.Lab) []domain.Lab {
 }
 
 func (uc *dashboardUsecase) GetInstructorDashboard(ctx context.Context, instructorID uint) (*domain.InstructorDashboardData, error) {
+	ctx, span := tracer.Start(ctx, "DashboardUsecase.GetInstructorDashboard")
+	defer span.End()
+
 	// Get courses by instructor
 	courses, err := uc.courseRepo.GetByInstructorID(ctx, instructorID)
 	if err != nil {
@@ -252,6 +264,9 @@ func (uc *dashboardUsecase) GetInstructorDashboard(ctx context.Context, instruct
 }
 
 func (uc *dashboardUsecase) GetAdminDashboard(ctx context.Context) (*domain.AdminDashboardData, error) {
+	ctx, span := tracer.Start(ctx, "DashboardUsecase.GetAdminDashboard")
+	defer span.End()
+
 	// Count users by role
 	totalStudents, _ := uc.userRepo.CountByRole(ctx, domain.RoleStudent)
 	totalInstructors, _ := uc.userRepo.CountByRole(ctx, domain.RoleInstructor)
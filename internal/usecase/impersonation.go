@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/utils"
+	"strconv"
+	"time"
+)
+
+// Impersonate membuat session pendek agar admin bisa melihat aplikasi sebagai user lain.
+// Token membawa ID admin dan user target; refresh token tidak diberikan.
+func (uc *authUsecase) Impersonate(ctx context.Context, adminID, targetID uint, reason string, client domain.ClientInfo) (*domain.ImpersonationResult, error) {
+	if _, nested := domain.ImpersonatorFromContext(ctx); nested {
+		return nil, domain.NewConflict("already_impersonating", "cannot start impersonation while impersonating")
+	}
+
+	admin, err := uc.userRepo.GetByID(ctx, adminID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	if !admin.Role.Can(domain.PermUserImpersonate) {
+		return nil, &domain.ForbiddenError{Permission: domain.PermUserImpersonate}
+	}
+
+	target, err := uc.userRepo.GetByID(ctx, targetID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	if target.ID == admin.ID {
+		return nil, domain.NewValidation("cannot_impersonate_self", "cannot impersonate yourself")
+	}
+	if target.Role.IsAdmin() {
+		return nil, domain.NewForbidden("cannot_impersonate_admin", "cannot impersonate another admin")
+	}
+
+	// Refresh token acak hanya untuk memenuhi constraint tabel; tidak pernah dikirim ke client
+	unusedRefresh, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(utils.ImpersonationTTL)
+	session := &domain.Session{
+		UserID:           target.ID,
+		RefreshTokenHash: utils.HashToken(unusedRefresh),
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		ExpiresAt:        expiresAt,
+		LastUsedAt:       now,
+		ImpersonatorID:   &admin.ID,
+	}
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateJWTWithTTL(utils.Claims{
+		UserID:         target.ID,
+		Role:           string(target.Role),
+		SessionID:      session.ID,
+		Verified:       target.IsVerified,
+		ImpersonatorID: admin.ID,
+		OrganizationID: target.OrgID(),
+	}, utils.ImpersonationTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.audit.Record(ctx, admin.ID, domain.AuditImpersonationStart, domain.AuditTargetUser, strconv.FormatUint(uint64(target.ID), 10), nil, map[string]interface{}{
+		"session_id": session.ID,
+		"reason":     reason,
+		"expires_at": expiresAt,
+	})
+
+	return &domain.ImpersonationResult{
+		Tokens: &domain.AuthTokens{
+			AccessToken: accessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(utils.ImpersonationTTL.Seconds()),
+		},
+		User:      target,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// StopImpersonation mencabut session impersonasi sebelum waktunya habis
+func (uc *authUsecase) StopImpersonation(ctx context.Context, sessionID uint) error {
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.ImpersonatorID == nil {
+		return domain.NewConflict("not_impersonating", "not an impersonation session")
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	if err := uc.sessionRepo.Revoke(ctx, session.ID); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, *session.ImpersonatorID, domain.AuditImpersonationStop, domain.AuditTargetUser, strconv.FormatUint(uint64(session.UserID), 10), nil, map[string]interface{}{
+		"session_id": session.ID,
+	})
+	return nil
+}
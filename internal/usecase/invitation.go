@@ -0,0 +1,383 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	invitationDefaultTTL = 7 * 24 * time.Hour
+	invitationMaxTTL     = 30 * 24 * time.Hour
+)
+
+type invitationUsecase struct {
+	invitationRepo domain.InvitationRepository
+	orgRepo        domain.OrganizationRepository
+	userRepo       domain.UserRepository
+	courseRepo     domain.CourseRepository
+	labRepo        domain.LabRepository
+	enrollmentRepo domain.EnrollmentRepository
+	auth           domain.AuthUsecase
+	policy         domain.Policy
+	mailer         domain.Mailer
+	audit          domain.AuditUsecase
+	signup         domain.SignupPolicy
+	appURL         string
+	logger         *slog.Logger
+}
+
+func NewInvitationUsecase(
+	ir domain.InvitationRepository,
+	or domain.OrganizationRepository,
+	ur domain.UserRepository,
+	cr domain.CourseRepository,
+	lr domain.LabRepository,
+	er domain.EnrollmentRepository,
+	auth domain.AuthUsecase,
+	policy domain.Policy,
+	mailer domain.Mailer,
+	audit domain.AuditUsecase,
+	signup domain.SignupPolicy,
+	appURL string,
+	logger *slog.Logger,
+) domain.InvitationUsecase {
+	return &invitationUsecase{
+		invitationRepo: ir,
+		orgRepo:        or,
+		userRepo:       ur,
+		courseRepo:     cr,
+		labRepo:        lr,
+		enrollmentRepo: er,
+		auth:           auth,
+		policy:         policy,
+		mailer:         mailer,
+		audit:          audit,
+		signup:         signup,
+		appURL:         strings.TrimRight(appURL, "/"),
+		logger:         logger,
+	}
+}
+
+func (uc *invitationUsecase) SignupPolicy() domain.SignupPolicy {
+	return uc.signup
+}
+
+// SignUp adalah pendaftaran publik: selalu role student dan tunduk pada SignupPolicy.
+// Tanpa slug organisasi, akun masuk ke organisasi default. Organisasi yang tidak membuka
+// pendaftaran publik hanya bisa dimasuki lewat undangan.
+func (uc *invitationUsecase) SignUp(ctx context.Context, user *domain.User, organizationSlug string) error {
+	if err := uc.checkSignupPolicy(user.Email); err != nil {
+		return err
+	}
+
+	if organizationSlug == "" {
+		organizationSlug = domain.DefaultOrganizationSlug
+	}
+	org, err := uc.orgRepo.GetBySlug(ctx, strings.ToLower(strings.TrimSpace(organizationSlug)))
+	if err != nil {
+		return err
+	}
+	if !org.OpenSignup {
+		return domain.NewForbidden("organization_signup_closed", "this organization can only be joined by invitation")
+	}
+
+	user.Role = domain.RoleStudent
+	user.IsVerified = false
+	user.OrganizationID = &org.ID
+	return uc.auth.Register(ctx, user)
+}
+
+// SignUpWithIdentity membuat akun untuk login pertama lewat identity provider (OIDC).
+// Undangan yang masih berlaku untuk email yang terverifikasi IdP menentukan role dan
+// organisasi; tanpa undangan berlaku SignupPolicy seperti pendaftaran publik.
+func (uc *invitationUsecase) SignUpWithIdentity(ctx context.Context, user *domain.User) error {
+	var invitation *domain.Invitation
+	if user.IsVerified {
+		var err error
+		if invitation, err = uc.invitationRepo.GetPendingByEmail(ctx, user.Email); err != nil {
+			return err
+		}
+	}
+	if invitation == nil {
+		if err := uc.checkSignupPolicy(user.Email); err != nil {
+			return err
+		}
+		return uc.userRepo.Create(ctx, user)
+	}
+
+	user.Role = invitation.Role
+	user.OrganizationID = invitation.OrganizationID
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+	uc.markAccepted(ctx, invitation, user)
+	return nil
+}
+
+func (uc *invitationUsecase) checkSignupPolicy(email string) error {
+	if uc.signup.InviteOnly {
+		return domain.NewForbidden("registration_invite_only", "registration is by invitation only")
+	}
+	if !uc.signup.AllowsEmail(email) {
+		return domain.NewForbidden("email_domain_not_allowed", "email domain is not allowed to register")
+	}
+	return nil
+}
+
+// CreateInvitation membuat undangan dan mengirim link-nya lewat email. Instructor hanya
+// boleh mengundang student (opsional ke course miliknya); role lain butuh user:manage.
+// Pembatasan domain email tidak berlaku untuk undangan.
+func (uc *invitationUsecase) CreateInvitation(ctx context.Context, email string, role domain.Role, courseID *uint, ttl time.Duration, actorID uint) (*domain.NewInvitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, domain.NewFieldError("email", "invalid_email", "invalid email address")
+	}
+
+	if role == "" {
+		role = domain.RoleStudent
+	}
+	switch role {
+	case domain.RoleStudent:
+	case domain.RoleInstructor, domain.RoleAdmin:
+		if err := uc.policy.AuthorizeAction(ctx, actorID, domain.PermUserManage); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, domain.NewFieldError("role", "invalid_role", fmt.Sprintf("invalid role %q", role))
+	}
+
+	var course *domain.Course
+	if courseID != nil {
+		if role != domain.RoleStudent {
+			return nil, domain.NewFieldError("role", "course_invitation_role", "course invitations are only for students")
+		}
+		var err error
+		if course, err = uc.courseRepo.GetByID(ctx, *courseID); err != nil {
+			return nil, domain.ErrCourseNotFound
+		}
+		if err := uc.policy.Authorize(ctx, actorID, domain.PermInvitationCreate, &course.InstructorID); err != nil {
+			return nil, err
+		}
+	} else if err := uc.policy.AuthorizeAction(ctx, actorID, domain.PermInvitationCreate); err != nil {
+		return nil, err
+	}
+
+	if existing, err := uc.userRepo.GetByEmail(ctx, email); err == nil && existing != nil {
+		return nil, domain.NewConflict("email_already_exists", "email already registered")
+	}
+
+	// Akun baru masuk ke organisasi course; tanpa course, repository memakai organisasi pengundang
+	var orgID *uint
+	if course != nil {
+		orgID = course.OrganizationID
+	}
+
+	if ttl <= 0 {
+		ttl = invitationDefaultTTL
+	}
+	if ttl > invitationMaxTTL {
+		ttl = invitationMaxTTL
+	}
+
+	invitation := &domain.Invitation{
+		Email:     email,
+		Role:      role,
+		CourseID:  courseID,
+		ExpiresAt: time.Now().Add(ttl),
+
+		OrganizationID: orgID,
+	}
+	return uc.issue(ctx, invitation, course, actorID)
+}
+
+// CreateImportInvitation membuat undangan untuk satu baris import massal. Email, role,
+// course dan lab sudah divalidasi import; akun dibuat saat undangan diterima.
+func (uc *invitationUsecase) CreateImportInvitation(ctx context.Context, invitation *domain.Invitation, actorID uint) (*domain.NewInvitation, error) {
+	if err := uc.policy.AuthorizeAction(ctx, actorID, domain.PermUserManage); err != nil {
+		return nil, err
+	}
+	if invitation.Role != domain.RoleStudent && invitation.Role != domain.RoleInstructor {
+		return nil, domain.NewFieldError("role", "invalid_role", fmt.Sprintf("invalid role %q", invitation.Role))
+	}
+	invitation.ExpiresAt = time.Now().Add(invitationDefaultTTL)
+	return uc.issue(ctx, invitation, nil, actorID)
+}
+
+// issue menyimpan undangan dengan token baru, lalu mengirim link-nya lewat email
+func (uc *invitationUsecase) issue(ctx context.Context, invitation *domain.Invitation, course *domain.Course, actorID uint) (*domain.NewInvitation, error) {
+	// Hanya undangan terbaru untuk email yang sama yang berlaku
+	if err := uc.invitationRepo.RevokePendingByEmail(ctx, invitation.Email); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	invitation.TokenHash = utils.HashToken(token)
+	invitation.InvitedBy = actorID
+	if err := uc.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	link := fmt.Sprintf("%s/register?invite=%s", uc.appURL, url.QueryEscape(token))
+	uc.sendInvitation(ctx, invitation, course, link, actorID)
+
+	uc.audit.Record(ctx, actorID, domain.AuditInvitationCreate, domain.AuditTargetInvitation, strconv.FormatUint(uint64(invitation.ID), 10), nil, invitation)
+	return &domain.NewInvitation{Invitation: *invitation, Link: link}, nil
+}
+
+func (uc *invitationUsecase) sendInvitation(ctx context.Context, invitation *domain.Invitation, course *domain.Course, link string, actorID uint) {
+	data := map[string]interface{}{
+		"Role":          string(invitation.Role),
+		"AcceptLink":    link,
+		"ExpiresInDays": int(time.Until(invitation.ExpiresAt).Round(time.Hour).Hours() / 24),
+	}
+	if inviter, err := uc.userRepo.GetByID(ctx, actorID); err == nil {
+		data["InviterName"] = inviter.Name
+	}
+	if course != nil {
+		data["CourseTitle"] = course.Title
+	}
+
+	if err := uc.mailer.Send(ctx, domain.Email{
+		To:       invitation.Email,
+		Template: domain.EmailTemplateSignupInvite,
+		Data:     data,
+	}); err != nil {
+		uc.logger.WarnContext(ctx, "Failed to send invitation email", "invitation_id", invitation.ID, "error", err)
+	}
+}
+
+// ListInvitations - Admin melihat semua undangan, instructor hanya undangan miliknya
+func (uc *invitationUsecase) ListInvitations(ctx context.Context, actorID uint) ([]domain.Invitation, error) {
+	// Tanpa owner hanya lolos untuk ScopeAny (admin)
+	if err := uc.policy.Authorize(ctx, actorID, domain.PermUserManage, nil); err == nil {
+		return uc.invitationRepo.List(ctx, nil)
+	}
+	if err := uc.policy.AuthorizeAction(ctx, actorID, domain.PermInvitationCreate); err != nil {
+		return nil, err
+	}
+	return uc.invitationRepo.List(ctx, &actorID)
+}
+
+func (uc *invitationUsecase) RevokeInvitation(ctx context.Context, id, actorID uint) error {
+	invitation, err := uc.invitationRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := uc.policy.Authorize(ctx, actorID, domain.PermInvitationCreate, &invitation.InvitedBy); err != nil {
+		return err
+	}
+	if !invitation.IsPending(time.Now()) {
+		return domain.NewConflict("invitation_not_pending", "invitation is no longer pending")
+	}
+
+	before := *invitation
+	now := time.Now()
+	invitation.RevokedAt = &now
+	if err := uc.invitationRepo.Update(ctx, invitation); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditInvitationRevoke, domain.AuditTargetInvitation, strconv.FormatUint(uint64(invitation.ID), 10), before, invitation)
+	return nil
+}
+
+// GetInvitation mengembalikan undangan yang masih berlaku untuk token ini
+func (uc *invitationUsecase) GetInvitation(ctx context.Context, token string) (*domain.Invitation, error) {
+	invitation, err := uc.invitationRepo.GetByTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || !invitation.IsPending(time.Now()) {
+		return nil, domain.NewFieldError("token", "invalid_invitation", "invalid or expired invitation")
+	}
+	return invitation, nil
+}
+
+// AcceptInvitation membuat akun dengan email dan role dari undangan. Email dianggap
+// terverifikasi karena link diterima lewat email tersebut.
+func (uc *invitationUsecase) AcceptInvitation(ctx context.Context, token, name, password string) (*domain.User, error) {
+	invitation, err := uc.GetInvitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, domain.NewFieldError("name", "name_required", "name is required")
+	}
+	if len(password) < passwordMinLength {
+		return nil, errPasswordTooShort
+	}
+
+	user := &domain.User{
+		Name:           name,
+		Email:          invitation.Email,
+		Password:       password,
+		Role:           invitation.Role,
+		IsVerified:     true,
+		OrganizationID: invitation.OrganizationID,
+	}
+	if err := uc.auth.Register(ctx, user); err != nil {
+		return nil, err
+	}
+	uc.markAccepted(ctx, invitation, user)
+	return user, nil
+}
+
+// markAccepted menandai undangan diterima oleh akun yang baru dibuat lalu mendaftarkan
+// student ke course dan lab dari undangan
+func (uc *invitationUsecase) markAccepted(ctx context.Context, invitation *domain.Invitation, user *domain.User) {
+	before := *invitation
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	invitation.AcceptedUserID = &user.ID
+	if err := uc.invitationRepo.Update(ctx, invitation); err != nil {
+		uc.logger.WarnContext(ctx, "Failed to mark invitation as accepted", "invitation_id", invitation.ID, "error", err)
+	}
+
+	if user.Role == domain.RoleStudent {
+		uc.enrollInvited(ctx, invitation, user.ID)
+	}
+
+	uc.audit.Record(ctx, user.ID, domain.AuditInvitationAccept, domain.AuditTargetInvitation, strconv.FormatUint(uint64(invitation.ID), 10), before, invitation)
+}
+
+// enrollInvited mendaftarkan student ke course dan lab dari undangan. Kegagalan hanya
+// dicatat di log; akun tetap dibuat.
+func (uc *invitationUsecase) enrollInvited(ctx context.Context, invitation *domain.Invitation, userID uint) {
+	courseIDs := invitation.CourseIDs
+	if invitation.CourseID != nil {
+		courseIDs = append(domain.IDList{*invitation.CourseID}, courseIDs...)
+	}
+	seen := make(map[uint]bool, len(courseIDs))
+	for _, courseID := range courseIDs {
+		if seen[courseID] {
+			continue
+		}
+		seen[courseID] = true
+		if err := uc.enrollmentRepo.Create(ctx, &domain.Enrollment{UserID: userID, CourseID: courseID}); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to enroll invited user", "target_user_id", userID, "course_id", courseID, "error", err)
+			continue
+		}
+		enrollmentsTotal.WithLabelValues("course", "invitation").Inc()
+	}
+
+	for _, labID := range invitation.LabIDs {
+		if err := uc.labRepo.CreateGrade(ctx, &domain.LabGrade{UserID: userID, LabID: labID}); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to add invited user to lab", "target_user_id", userID, "lab_id", labID, "error", err)
+			continue
+		}
+		enrollmentsTotal.WithLabelValues("lab", "invitation").Inc()
+	}
+}
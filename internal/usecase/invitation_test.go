@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"onlearn-backend/internal/domain"
+)
+
+type fakeOrganizationRepo struct {
+	domain.OrganizationRepository
+	orgs []domain.Organization
+}
+
+func (r *fakeOrganizationRepo) GetBySlug(ctx context.Context, slug string) (*domain.Organization, error) {
+	for i := range r.orgs {
+		if r.orgs[i].Slug == slug {
+			return &r.orgs[i], nil
+		}
+	}
+	return nil, domain.NewNotFound("organization_not_found", "organization not found")
+}
+
+type fakeRegister struct {
+	domain.AuthUsecase
+	user *domain.User
+}
+
+func (a *fakeRegister) Register(ctx context.Context, user *domain.User) error {
+	a.user = user
+	return nil
+}
+
+func TestSignUpOrganization(t *testing.T) {
+	orgs := []domain.Organization{
+		{ID: 1, Slug: domain.DefaultOrganizationSlug, OpenSignup: true},
+		{ID: 2, Slug: "kampus-terbuka", OpenSignup: true},
+		{ID: 3, Slug: "kampus-tertutup"},
+	}
+
+	tests := []struct {
+		name   string
+		slug   string
+		signup domain.SignupPolicy
+
+		wantErr   string // Kode error domain
+		wantOrgID uint
+	}{
+		{name: "no slug joins default organization", wantOrgID: 1},
+		{name: "organization with open signup", slug: "Kampus-Terbuka", wantOrgID: 2},
+		{name: "organization without open signup", slug: "kampus-tertutup", wantErr: "organization_signup_closed"},
+		{name: "unknown organization", slug: "tidak-ada", wantErr: "organization_not_found"},
+		{name: "invite only", signup: domain.SignupPolicy{InviteOnly: true}, wantErr: "registration_invite_only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &fakeRegister{}
+			uc := &invitationUsecase{
+				orgRepo: &fakeOrganizationRepo{orgs: orgs},
+				auth:    auth,
+				signup:  tt.signup,
+			}
+
+			user := &domain.User{Name: "Siti", Email: "siti@example.com", Password: "rahasia123", Role: domain.RoleAdmin}
+			err := uc.SignUp(context.Background(), user, tt.slug)
+
+			if tt.wantErr != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.wantErr {
+					t.Fatalf("SignUp() error = %v, want %s", err, tt.wantErr)
+				}
+				if auth.user != nil {
+					t.Errorf("user was registered despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SignUp() error = %v", err)
+			}
+			if auth.user == nil || auth.user.OrganizationID == nil || *auth.user.OrganizationID != tt.wantOrgID {
+				t.Fatalf("registered user = %+v, want organization %d", auth.user, tt.wantOrgID)
+			}
+			if auth.user.Role != domain.RoleStudent {
+				t.Errorf("Role = %s, want %s", auth.user.Role, domain.RoleStudent)
+			}
+		})
+	}
+}
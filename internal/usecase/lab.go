@@ -2,44 +2,79 @@ package usecase
 
 import (
 	"context"
-	"errors"
 	"onlearn-backend/internal/domain"
+	"strconv"
 )
 
 type labUsecase struct {
 	labRepo  domain.LabRepository
 	userRepo domain.UserRepository
 	certRepo domain.CertificateRepository
+	policy   domain.Policy
+	audit    domain.AuditUsecase
 }
 
 func NewLabUsecase(
 	lr domain.LabRepository,
 	ur domain.UserRepository,
 	cr domain.CertificateRepository,
+	policy domain.Policy,
+	audit domain.AuditUsecase,
 ) domain.LabUsecase {
 	return &labUsecase{
 		labRepo:  lr,
 		userRepo: ur,
 		certRepo: cr,
+		policy:   policy,
+		audit:    audit,
 	}
 }
 
 // ========== LAB CRUD ==========
 
-func (uc *labUsecase) CreateLab(ctx context.Context, lab *domain.Lab) error {
+func (uc *labUsecase) CreateLab(ctx context.Context, lab *domain.Lab, actorID uint) error {
+	if err := uc.policy.AuthorizeAction(ctx, actorID, domain.PermLabCreate); err != nil {
+		return err
+	}
+
+	lab.InstructorID = &actorID
 	if lab.Status == "" {
 		lab.Status = "scheduled"
 	}
-	return uc.labRepo.Create(ctx, lab)
+	if err := uc.labRepo.Create(ctx, lab); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditLabCreate, domain.AuditTargetLab, labTargetID(lab.ID), nil, lab)
+	return nil
 }
 
-func (uc *labUsecase) UpdateLab(ctx context.Context, lab *domain.Lab) error {
+func (uc *labUsecase) UpdateLab(ctx context.Context, lab *domain.Lab, actorID uint) error {
 	existing, err := uc.labRepo.GetByID(ctx, lab.ID)
 	if err != nil {
 		return err
 	}
 
+	if err := uc.policy.Authorize(ctx, actorID, domain.PermLabUpdate, existing.InstructorID); err != nil {
+		return err
+	}
+
+	// Pindah pemilik (mis. lab lama yang diisi admin oleh migrasi) hanya untuk ScopeAny
+	if lab.InstructorID != nil && (existing.InstructorID == nil || *lab.InstructorID != *existing.InstructorID) {
+		if err := uc.policy.Authorize(ctx, actorID, domain.PermLabUpdate, nil); err != nil {
+			return err
+		}
+		if err := uc.validateLabOwner(ctx, existing, *lab.InstructorID); err != nil {
+			return err
+		}
+	}
+
+	before := *existing
+
 	// Update fields
+	if lab.InstructorID != nil {
+		existing.InstructorID = lab.InstructorID
+	}
 	existing.Title = lab.Title
 	existing.Description = lab.Description
 	existing.StartTime = lab.StartTime
@@ -48,13 +83,36 @@ func (uc *labUsecase) UpdateLab(ctx context.Context, lab *domain.Lab) error {
 		existing.Status = lab.Status
 	}
 
-	return uc.labRepo.Update(ctx, existing)
+	if err := uc.labRepo.Update(ctx, existing); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditLabUpdate, domain.AuditTargetLab, labTargetID(existing.ID), before, existing)
+	return nil
 }
 
-func (uc *labUsecase) UpdateLabStatus(ctx context.Context, labID uint, status string) error {
+// validateLabOwner - Pemilik baru harus boleh mengelola lab dan berada di organisasi lab
+func (uc *labUsecase) validateLabOwner(ctx context.Context, lab *domain.Lab, instructorID uint) error {
+	invalid := domain.NewFieldError("instructor_id", "invalid_lab_owner", "lab owner must be an instructor of the lab's organization")
+
+	owner, err := uc.userRepo.GetByID(ctx, instructorID)
+	if err != nil || !owner.Role.Can(domain.PermLabUpdate) {
+		return invalid
+	}
+	if lab.OrganizationID != nil && (owner.OrganizationID == nil || *owner.OrganizationID != *lab.OrganizationID) {
+		return invalid
+	}
+	return nil
+}
+
+func (uc *labUsecase) UpdateLabStatus(ctx context.Context, labID uint, status string, actorID uint) error {
 	lab, err := uc.labRepo.GetByID(ctx, labID)
 	if err != nil {
-		return errors.New("lab not found")
+		return domain.ErrLabNotFound
+	}
+
+	if err := uc.policy.Authorize(ctx, actorID, domain.PermLabUpdate, lab.InstructorID); err != nil {
+		return err
 	}
 
 	// Validate status
@@ -65,11 +123,17 @@ func (uc *labUsecase) UpdateLabStatus(ctx context.Context, labID uint, status st
 	}
 
 	if !validStatuses[status] {
-		return errors.New("invalid status")
+		return domain.NewFieldError("status", "invalid_status", "invalid status")
 	}
 
+	before := *lab
 	lab.Status = status
-	return uc.labRepo.Update(ctx, lab)
+	if err := uc.labRepo.Update(ctx, lab); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditLabUpdate, domain.AuditTargetLab, labTargetID(labID), before, lab)
+	return nil
 }
 
 func (uc *labUsecase) GetLabByID(ctx context.Context, labID uint) (*domain.Lab, error) {
@@ -80,18 +144,36 @@ func (uc *labUsecase) GetAllLabs(ctx context.Context) ([]domain.Lab, error) {
 	return uc.labRepo.GetAll(ctx)
 }
 
+func (uc *labUsecase) ListLabs(ctx context.Context, q domain.ListQuery) ([]domain.Lab, *domain.PageInfo, error) {
+	return uc.labRepo.List(ctx, q)
+}
+
 func (uc *labUsecase) GetUpcomingLabs(ctx context.Context) ([]domain.Lab, error) {
 	return uc.labRepo.GetUpcoming(ctx)
 }
 
-func (uc *labUsecase) DeleteLab(ctx context.Context, labID uint) error {
+func (uc *labUsecase) DeleteLab(ctx context.Context, labID uint, actorID uint) error {
+	lab, err := uc.labRepo.GetByID(ctx, labID)
+	if err != nil {
+		return domain.ErrLabNotFound
+	}
+
+	if err := uc.policy.Authorize(ctx, actorID, domain.PermLabDelete, lab.InstructorID); err != nil {
+		return err
+	}
+
 	// Check if lab has grades
 	grades, _ := uc.labRepo.GetGradesByLabID(ctx, labID)
 	if len(grades) > 0 {
-		return errors.New("cannot delete lab with existing grades")
+		return domain.NewConflict("lab_has_grades", "cannot delete lab with existing grades")
+	}
+
+	if err := uc.labRepo.Delete(ctx, labID); err != nil {
+		return err
 	}
 
-	return uc.labRepo.Delete(ctx, labID)
+	uc.audit.Record(ctx, actorID, domain.AuditLabDelete, domain.AuditTargetLab, labTargetID(labID), lab, nil)
+	return nil
 }
 
 // ========== LAB GRADING ==========
@@ -103,13 +185,13 @@ func (uc *labUsecase) StudentEnroll(ctx context.Context, userID, labID uint) err
 		return err
 	}
 	if existing != nil {
-		return errors.New("already enrolled in this lab")
+		return domain.NewConflict("already_enrolled", "already enrolled in this lab")
 	}
 
 	// Verify lab exists
 	_, err = uc.labRepo.GetByID(ctx, labID)
 	if err != nil {
-		return errors.New("lab not found")
+		return domain.ErrLabNotFound
 	}
 
 	// Create grade entry (ungraded initially)
@@ -119,18 +201,26 @@ func (uc *labUsecase) StudentEnroll(ctx context.Context, userID, labID uint) err
 		Grade:  nil, // nil = not graded yet
 	}
 
-	return uc.labRepo.CreateGrade(ctx, grade)
+	if err := uc.labRepo.CreateGrade(ctx, grade); err != nil {
+		return err
+	}
+	enrollmentsTotal.WithLabelValues("lab", "self").Inc()
+	return nil
 }
 
 // AddStudentToLab - Instructor adds a student to a lab
-func (uc *labUsecase) AddStudentToLab(ctx context.Context, userID, labID uint) error {
+func (uc *labUsecase) AddStudentToLab(ctx context.Context, userID, labID uint, actorID uint) error {
+	if err := uc.authorizeLab(ctx, labID, actorID, domain.PermLabManageStudents); err != nil {
+		return err
+	}
+
 	// Check if student exists
 	student, err := uc.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return errors.New("student not found")
+		return domain.NewNotFound("student_not_found", "student not found")
 	}
 	if student.Role != domain.RoleStudent {
-		return errors.New("user is not a student")
+		return domain.NewValidation("not_a_student", "user is not a student")
 	}
 
 	// Check if already enrolled
@@ -139,13 +229,7 @@ func (uc *labUsecase) AddStudentToLab(ctx context.Context, userID, labID uint) e
 		return err
 	}
 	if existing != nil {
-		return errors.New("student already enrolled in this lab")
-	}
-
-	// Verify lab exists
-	_, err = uc.labRepo.GetByID(ctx, labID)
-	if err != nil {
-		return errors.New("lab not found")
+		return domain.NewConflict("already_enrolled", "student already enrolled in this lab")
 	}
 
 	// Create grade entry (ungraded initially)
@@ -155,15 +239,19 @@ func (uc *labUsecase) AddStudentToLab(ctx context.Context, userID, labID uint) e
 		Grade:  nil, // nil = not graded yet
 	}
 
-	return uc.labRepo.CreateGrade(ctx, grade)
+	if err := uc.labRepo.CreateGrade(ctx, grade); err != nil {
+		return err
+	}
+	enrollmentsTotal.WithLabelValues("lab", "instructor").Inc()
+
+	uc.audit.Record(ctx, actorID, domain.AuditLabStudentAdd, domain.AuditTargetLabGrade, labGradeTargetID(labID, userID), nil, grade)
+	return nil
 }
 
 // RemoveStudentFromLab - Instructor removes a student from a lab
-func (uc *labUsecase) RemoveStudentFromLab(ctx context.Context, userID, labID uint) error {
-	// Verify lab exists
-	_, err := uc.labRepo.GetByID(ctx, labID)
-	if err != nil {
-		return errors.New("lab not found")
+func (uc *labUsecase) RemoveStudentFromLab(ctx context.Context, userID, labID uint, actorID uint) error {
+	if err := uc.authorizeLab(ctx, labID, actorID, domain.PermLabManageStudents); err != nil {
+		return err
 	}
 
 	// Check if student is enrolled
@@ -172,21 +260,20 @@ func (uc *labUsecase) RemoveStudentFromLab(ctx context.Context, userID, labID ui
 		return err
 	}
 	if existing == nil {
-		return errors.New("student is not enrolled in this lab")
+		return domain.NewConflict("not_enrolled", "student is not enrolled in this lab")
+	}
+
+	if err := uc.labRepo.DeleteGrade(ctx, userID, labID); err != nil {
+		return err
 	}
 
-	return uc.labRepo.DeleteGrade(ctx, userID, labID)
+	uc.audit.Record(ctx, actorID, domain.AuditLabStudentRemove, domain.AuditTargetLabGrade, labGradeTargetID(labID, userID), existing, nil)
+	return nil
 }
 
 func (uc *labUsecase) SubmitGrade(ctx context.Context, instructorID, userID, labID uint, grade *float64, feedback string) error {
-	// Verify instructor exists
-	instructor, err := uc.userRepo.GetByID(ctx, instructorID)
-	if err != nil {
-		return errors.New("instructor not found")
-	}
-
-	if instructor.Role != domain.RoleInstructor && instructor.Role != domain.RoleAdmin {
-		return errors.New("only instructors and admins can grade labs")
+	if err := uc.authorizeLab(ctx, labID, instructorID, domain.PermLabGrade); err != nil {
+		return err
 	}
 
 	// Get existing grade record
@@ -195,12 +282,16 @@ func (uc *labUsecase) SubmitGrade(ctx context.Context, instructorID, userID, lab
 		return err
 	}
 
+	var before *domain.LabGrade
 	if labGrade == nil {
 		// Create a new grade if student not enrolled
 		labGrade = &domain.LabGrade{
 			UserID: userID,
 			LabID:  labID,
 		}
+	} else {
+		snapshot := *labGrade
+		before = &snapshot
 	}
 
 	// Update grade
@@ -215,6 +306,11 @@ func (uc *labUsecase) SubmitGrade(ctx context.Context, instructorID, userID, lab
 			return err
 		}
 	}
+	if grade != nil {
+		gradesTotal.WithLabelValues("lab").Inc()
+	}
+
+	uc.audit.Record(ctx, instructorID, domain.AuditLabGrade, domain.AuditTargetLabGrade, labGradeTargetID(labID, userID), before, labGrade)
 
 	// Auto-generate certificate if grade is good (e.g., >= 75)
 	if grade != nil && *grade >= 75 {
@@ -230,6 +326,26 @@ func (uc *labUsecase) SubmitGrade(ctx context.Context, instructorID, userID, lab
 	return nil
 }
 
+// authorizeLab memastikan lab ada lalu mengecek permission terhadap pembuat lab
+func (uc *labUsecase) authorizeLab(ctx context.Context, labID uint, actorID uint, perm domain.Permission) error {
+	lab, err := uc.labRepo.GetByID(ctx, labID)
+	if err != nil {
+		return domain.ErrLabNotFound
+	}
+
+	return uc.policy.Authorize(ctx, actorID, perm, lab.InstructorID)
+}
+
+func labTargetID(labID uint) string {
+	return strconv.FormatUint(uint64(labID), 10)
+}
+
+// labGradeTargetID - LabGrade tidak punya ID sendiri yang stabil, jadi target
+// audit memakai pasangan lab dan student ("lab:user")
+func labGradeTargetID(labID, userID uint) string {
+	return labTargetID(labID) + ":" + strconv.FormatUint(uint64(userID), 10)
+}
+
 func (uc *labUsecase) GetUngradedStudents(ctx context.Context, labID uint) ([]domain.User, error) {
 	grades, err := uc.labRepo.GetGradesByLabID(ctx, labID)
 	if err != nil {
@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Counter bisnis untuk dashboard dan alert. Hanya dinaikkan setelah perubahan
+// berhasil disimpan; label sengaja tidak memuat ID agar jumlah series tetap kecil.
+var (
+	enrollmentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onlearn_enrollments_total",
+		Help: "Successful enrollments by kind (course or lab) and source.",
+	}, []string{"kind", "source"})
+	moduleCompletionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "onlearn_module_completions_total",
+		Help: "Modules marked complete for the first time.",
+	})
+	submissionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "onlearn_assignment_submissions_total",
+		Help: "Assignments submitted by students.",
+	})
+	gradesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onlearn_grades_total",
+		Help: "Grades given by kind (assignment or lab).",
+	}, []string{"kind"})
+	certificateDecisionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onlearn_certificate_decisions_total",
+		Help: "Certificate approval decisions by outcome (approved or rejected).",
+	}, []string{"decision"})
+)
@@ -0,0 +1,424 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/totp"
+	"onlearn-backend/pkg/utils"
+	"strings"
+	"time"
+)
+
+const (
+	mfaIssuer            = "OnLearn"
+	mfaChallengeTTL      = 5 * time.Minute
+	mfaMaxAttempts       = 5
+	mfaRecoveryCodeCount = 10
+	totpSkew             = 1 // Toleransi 1 step (30 detik) untuk selisih jam perangkat
+)
+
+// mfaPurpose menentukan apakah login user perlu challenge 2FA ("" jika tidak)
+func (uc *authUsecase) mfaPurpose(ctx context.Context, user *domain.User) (domain.MFAChallengePurpose, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if mfa != nil && mfa.ConfirmedAt != nil {
+		return domain.MFAChallengeVerify, nil
+	}
+
+	policy, err := uc.mfaRepo.GetPolicy(ctx, user.Role)
+	if err != nil {
+		return "", err
+	}
+	if policy != nil && policy.Required {
+		return domain.MFAChallengeEnroll, nil
+	}
+	return "", nil
+}
+
+func (uc *authUsecase) createMFAChallenge(ctx context.Context, user *domain.User, purpose domain.MFAChallengePurpose) (*domain.LoginResult, error) {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &domain.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(mfaChallengeTTL),
+	}
+	if err := uc.mfaRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResult{
+		MFARequired:  true,
+		MFAToken:     token,
+		MFAPurpose:   purpose,
+		MFAExpiresIn: int(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// getActiveChallenge mengambil challenge yang masih berlaku dari token
+func (uc *authUsecase) getActiveChallenge(ctx context.Context, mfaToken string) (*domain.MFAChallenge, error) {
+	challenge, err := uc.mfaRepo.GetChallengeByTokenHash(ctx, utils.HashToken(mfaToken))
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.UsedAt != nil || challenge.Attempts >= mfaMaxAttempts || time.Now().After(challenge.ExpiresAt) {
+		return nil, domain.NewUnauthorized("invalid_mfa_challenge", "invalid or expired mfa challenge")
+	}
+	return challenge, nil
+}
+
+// VerifyMFA menyelesaikan login dua langkah. Untuk challenge "enroll", kode pertama
+// yang valid sekaligus mengonfirmasi enrollment dan mengembalikan recovery codes.
+func (uc *authUsecase) VerifyMFA(ctx context.Context, mfaToken, code string, client domain.ClientInfo) (*domain.LoginResult, error) {
+	challenge, err := uc.getActiveChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, domain.NewConflict("mfa_setup_not_started", "mfa setup has not been started")
+	}
+
+	var recoveryCodes []string
+	var valid bool
+	if mfa.ConfirmedAt == nil {
+		// Enrollment wajib: hanya kode TOTP yang diterima
+		if challenge.Purpose != domain.MFAChallengeEnroll {
+			return nil, domain.NewUnauthorized("invalid_mfa_challenge", "invalid or expired mfa challenge")
+		}
+		valid, err = uc.confirmEnrollment(ctx, mfa, code)
+		if err != nil {
+			return nil, err
+		}
+		if valid {
+			recoveryCodes, err = uc.generateRecoveryCodes(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		valid, err = uc.checkMFACode(ctx, mfa, code)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !valid {
+		challenge.Attempts++
+		if err := uc.mfaRepo.UpdateChallenge(ctx, challenge); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to update mfa challenge", "challenge_id", challenge.ID, "error", err)
+		}
+		// Kode 2FA yang salah ikut dihitung oleh brute-force protection
+		var locked *domain.LoginLockedError
+		if err := uc.registerLoginFailure(ctx, strings.ToLower(user.Email), &user.ID, client); errors.As(err, &locked) {
+			return nil, err
+		}
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	used, err := uc.mfaRepo.UseChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, domain.NewUnauthorized("invalid_mfa_challenge", "invalid or expired mfa challenge")
+	}
+
+	result, err := uc.completeLogin(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	result.RecoveryCodes = recoveryCodes
+	return result, nil
+}
+
+// SetupMFAForChallenge memulai enrollment untuk user yang wajib 2FA tapi belum enroll.
+// Mengembalikan nil jika challenge tidak memerlukan enrollment.
+func (uc *authUsecase) SetupMFAForChallenge(ctx context.Context, mfaToken string) (*domain.MFASetup, error) {
+	challenge, err := uc.getActiveChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Purpose != domain.MFAChallengeEnroll {
+		return nil, nil
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.ConfirmedAt != nil {
+		return nil, nil
+	}
+	// Secret yang belum dikonfirmasi dipakai ulang agar QR code tetap sama saat halaman di-refresh
+	if mfa != nil {
+		return &domain.MFASetup{
+			Secret:          mfa.Secret,
+			ProvisioningURI: totp.ProvisioningURI(mfaIssuer, user.Email, mfa.Secret),
+		}, nil
+	}
+	return uc.startEnrollment(ctx, user, nil)
+}
+
+// SetupMFA memulai enrollment 2FA opsional untuk user yang sudah login
+func (uc *authUsecase) SetupMFA(ctx context.Context, userID uint) (*domain.MFASetup, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.ConfirmedAt != nil {
+		return nil, domain.NewConflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	}
+	return uc.startEnrollment(ctx, user, mfa)
+}
+
+func (uc *authUsecase) startEnrollment(ctx context.Context, user *domain.User, mfa *domain.UserMFA) (*domain.MFASetup, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa == nil {
+		mfa = &domain.UserMFA{UserID: user.ID}
+	}
+	mfa.Secret = secret
+	mfa.ConfirmedAt = nil
+	mfa.LastUsedStep = 0
+	if err := uc.mfaRepo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	return &domain.MFASetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA mengaktifkan 2FA setelah user memasukkan kode pertama dari authenticator
+func (uc *authUsecase) ConfirmMFA(ctx context.Context, userID uint, code string) ([]string, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, domain.NewConflict("mfa_setup_not_started", "mfa setup has not been started")
+	}
+	if mfa.ConfirmedAt != nil {
+		return nil, domain.NewConflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	}
+
+	valid, err := uc.confirmEnrollment(ctx, mfa, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	return uc.generateRecoveryCodes(ctx, userID)
+}
+
+func (uc *authUsecase) confirmEnrollment(ctx context.Context, mfa *domain.UserMFA, code string) (bool, error) {
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return false, nil
+	}
+
+	confirmed, err := uc.mfaRepo.Confirm(ctx, mfa.UserID, step)
+	if err != nil {
+		return false, err
+	}
+	if !confirmed {
+		return false, domain.NewConflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	}
+	now := time.Now()
+	mfa.ConfirmedAt = &now
+	mfa.LastUsedStep = step
+	return true, nil
+}
+
+// DisableMFA menonaktifkan 2FA (tidak diizinkan jika 2FA wajib untuk role user)
+func (uc *authUsecase) DisableMFA(ctx context.Context, userID uint, code string) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.ErrUserNotFound
+	}
+
+	policy, err := uc.mfaRepo.GetPolicy(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+	if policy != nil && policy.Required {
+		return domain.NewForbidden("mfa_required_for_role", "two-factor authentication is mandatory for your role")
+	}
+
+	mfa, err := uc.enabledMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	valid, err := uc.checkMFACode(ctx, mfa, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return domain.ErrInvalidMFACode
+	}
+
+	return uc.mfaRepo.DeleteByUserID(ctx, userID)
+}
+
+// RegenerateRecoveryCodes mengganti semua recovery code (yang lama tidak berlaku lagi)
+func (uc *authUsecase) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	mfa, err := uc.enabledMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := uc.checkMFACode(ctx, mfa, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	return uc.generateRecoveryCodes(ctx, userID)
+}
+
+// ResetMFA menghapus 2FA user (oleh admin, misalnya jika perangkat dan recovery code hilang)
+func (uc *authUsecase) ResetMFA(ctx context.Context, userID uint) error {
+	if _, err := uc.userRepo.GetByID(ctx, userID); err != nil {
+		return domain.ErrUserNotFound
+	}
+	return uc.mfaRepo.DeleteByUserID(ctx, userID)
+}
+
+func (uc *authUsecase) GetMFAPolicies(ctx context.Context) ([]domain.MFAPolicy, error) {
+	stored, err := uc.mfaRepo.GetPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byRole := make(map[domain.Role]domain.MFAPolicy, len(stored))
+	for _, p := range stored {
+		byRole[p.Role] = p
+	}
+
+	// Role yang belum pernah diatur dianggap tidak wajib
+	roles := []domain.Role{domain.RoleStudent, domain.RoleInstructor, domain.RoleAdmin, domain.RoleSuperAdmin}
+	policies := make([]domain.MFAPolicy, 0, len(roles))
+	for _, role := range roles {
+		if p, ok := byRole[role]; ok {
+			policies = append(policies, p)
+		} else {
+			policies = append(policies, domain.MFAPolicy{Role: role})
+		}
+	}
+	return policies, nil
+}
+
+func (uc *authUsecase) SetMFAPolicy(ctx context.Context, role domain.Role, required bool, adminID uint) error {
+	if !validRole(role) {
+		return domain.NewFieldError("role", "invalid_role", "invalid role")
+	}
+
+	return uc.mfaRepo.SavePolicy(ctx, &domain.MFAPolicy{
+		Role:      role,
+		Required:  required,
+		UpdatedBy: adminID,
+	})
+}
+
+func (uc *authUsecase) enabledMFA(ctx context.Context, userID uint) (*domain.UserMFA, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || mfa.ConfirmedAt == nil {
+		return nil, domain.NewConflict("mfa_not_enabled", "two-factor authentication is not enabled")
+	}
+	return mfa, nil
+}
+
+// checkMFACode menerima kode TOTP atau recovery code. Kode TOTP yang sudah
+// dipakai (step yang sama atau lebih lama) ditolak untuk mencegah replay.
+func (uc *authUsecase) checkMFACode(ctx context.Context, mfa *domain.UserMFA, code string) (bool, error) {
+	if step, ok := totp.Validate(mfa.Secret, code, time.Now(), totpSkew); ok {
+		if step <= mfa.LastUsedStep {
+			return false, nil
+		}
+		// Step dicatat secara atomik: dari request paralel dengan kode yang sama hanya satu yang lolos
+		used, err := uc.mfaRepo.UseStep(ctx, mfa.UserID, step)
+		if err != nil {
+			return false, err
+		}
+		mfa.LastUsedStep = step
+		return used, nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+	return uc.mfaRepo.UseRecoveryCode(ctx, mfa.UserID, utils.HashToken(normalized))
+}
+
+// generateRecoveryCodes membuat recovery code baru dengan format "xxxxx-xxxxx".
+// Kode hanya ditampilkan sekali; yang disimpan hanya hash-nya.
+func (uc *authUsecase) generateRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	records := make([]domain.MFARecoveryCode, 0, mfaRecoveryCodeCount)
+
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		records = append(records, domain.MFARecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(raw),
+		})
+	}
+
+	if err := uc.mfaRepo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,279 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"onlearn-backend/internal/domain"
+	"onlearn-backend/pkg/utils"
+	"strings"
+	"time"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCOptions mengatur pemetaan claim dari identity provider ke domain.User
+type OIDCOptions struct {
+	DefaultRole   domain.Role // Role untuk akun yang dibuat otomatis
+	AutoProvision bool        // Buat akun baru jika belum ada user dengan email tersebut (tunduk pada undangan dan SignupPolicy)
+	EmailClaim    string      // Default "email"
+	NameClaim     string      // Default "name"
+	RoleClaim     string      // Opsional, mis. "onlearn_role" atau "groups"
+}
+
+type oidcUsecase struct {
+	userRepo    domain.UserRepository
+	oidcRepo    domain.OIDCRepository
+	authUsecase domain.AuthUsecase
+	invitations domain.InvitationUsecase
+	provider    domain.OIDCProvider
+	opts        OIDCOptions
+	logger      *slog.Logger
+}
+
+// NewOIDCUsecase membuat usecase login OIDC. provider boleh nil jika OIDC tidak dikonfigurasi.
+func NewOIDCUsecase(
+	ur domain.UserRepository,
+	or domain.OIDCRepository,
+	au domain.AuthUsecase,
+	iu domain.InvitationUsecase,
+	provider domain.OIDCProvider,
+	opts OIDCOptions,
+	logger *slog.Logger,
+) domain.OIDCUsecase {
+	if !validRole(opts.DefaultRole) {
+		opts.DefaultRole = domain.RoleStudent
+	}
+	if opts.EmailClaim == "" {
+		opts.EmailClaim = "email"
+	}
+	if opts.NameClaim == "" {
+		opts.NameClaim = "name"
+	}
+	return &oidcUsecase{
+		userRepo:    ur,
+		oidcRepo:    or,
+		authUsecase: au,
+		invitations: iu,
+		provider:    provider,
+		opts:        opts,
+		logger:      logger,
+	}
+}
+
+func (uc *oidcUsecase) Enabled() bool {
+	return uc.provider != nil
+}
+
+// BeginLogin membuat state, nonce, dan PKCE code verifier lalu mengembalikan URL login provider
+func (uc *oidcUsecase) BeginLogin(ctx context.Context) (*domain.OIDCAuthRequest, error) {
+	if !uc.Enabled() {
+		return nil, domain.ErrOIDCNotConfigured
+	}
+
+	state, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := utils.GenerateSecureToken(48)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := uc.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		uc.logger.WarnContext(ctx, "OIDC discovery failed", "error", err)
+		return nil, domain.NewUnavailable("oidc_provider_unavailable", "identity provider is unavailable")
+	}
+
+	if err := uc.oidcRepo.CreateState(ctx, &domain.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &domain.OIDCAuthRequest{
+		AuthorizationURL: authURL,
+		State:            state,
+		ExpiresIn:        int(oidcStateTTL.Seconds()),
+	}, nil
+}
+
+// CompleteLogin menukar authorization code, memetakan claims ke user (membuat akun
+// baru jika perlu), lalu melanjutkan login seperti biasa (termasuk 2FA).
+func (uc *oidcUsecase) CompleteLogin(ctx context.Context, state, code string, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if !uc.Enabled() {
+		return nil, domain.ErrOIDCNotConfigured
+	}
+
+	loginState, err := uc.oidcRepo.ConsumeState(ctx, utils.HashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if loginState == nil || time.Now().After(loginState.ExpiresAt) {
+		return nil, domain.NewUnauthorized("invalid_oidc_state", "invalid or expired oidc state")
+	}
+
+	claims, err := uc.provider.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		uc.logger.WarnContext(ctx, "OIDC code exchange failed", "error", err)
+		return nil, domain.NewUnavailable("oidc_authentication_failed", "oidc authentication failed")
+	}
+
+	user, err := uc.resolveUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.authUsecase.LoginWithIdentity(ctx, user, client)
+}
+
+// resolveUser mencari user dari identity yang sudah terhubung, lalu dari email,
+// dan terakhir membuat akun baru (auto-provision)
+func (uc *oidcUsecase) resolveUser(ctx context.Context, claims map[string]interface{}) (*domain.User, error) {
+	issuer := uc.provider.Issuer()
+	subject := claimString(claims, "sub")
+	email := strings.ToLower(strings.TrimSpace(claimString(claims, uc.opts.EmailClaim)))
+
+	identity, err := uc.oidcRepo.GetIdentity(ctx, issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := uc.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, domain.ErrUserNotFound
+		}
+		identity.LastLoginAt = time.Now()
+		if email != "" {
+			identity.Email = email
+		}
+		if err := uc.oidcRepo.UpdateIdentity(ctx, identity); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to update OIDC identity", "identity_id", identity.ID, "error", err)
+		}
+		return user, nil
+	}
+
+	if email == "" {
+		return nil, domain.NewUnavailable("oidc_missing_email_claim", fmt.Sprintf("identity provider did not return the %q claim", uc.opts.EmailClaim))
+	}
+	// Hanya email yang dinyatakan terverifikasi oleh IdP yang boleh dihubungkan ke akun yang ada;
+	// claim yang tidak dikirim dianggap belum terverifikasi
+	emailVerified := claimTrue(claims, "email_verified")
+
+	user, _ := uc.userRepo.GetByEmail(ctx, email)
+	if user != nil && user.ID != 0 {
+		if !emailVerified {
+			return nil, domain.NewForbidden("oidc_email_not_verified", "email is not verified by the identity provider")
+		}
+	} else {
+		if !uc.opts.AutoProvision {
+			return nil, domain.ErrNoOIDCAccount
+		}
+		user, err = uc.provisionUser(ctx, claims, email, emailVerified)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := uc.oidcRepo.CreateIdentity(ctx, &domain.UserIdentity{
+		UserID:      user.ID,
+		Provider:    issuer,
+		Subject:     subject,
+		Email:       email,
+		LastLoginAt: time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// provisionUser membuat akun tanpa password; user hanya bisa login lewat OIDC
+// (atau setelah mengatur password via forgot password)
+func (uc *oidcUsecase) provisionUser(ctx context.Context, claims map[string]interface{}, email string, emailVerified bool) (*domain.User, error) {
+	name := claimString(claims, uc.opts.NameClaim)
+	if name == "" {
+		name = strings.TrimSpace(claimString(claims, "given_name") + " " + claimString(claims, "family_name"))
+	}
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+
+	user := &domain.User{
+		Name:       name,
+		Email:      email,
+		Role:       uc.mapRole(claims),
+		IsVerified: emailVerified,
+	}
+	if locale := claimString(claims, "locale"); strings.HasPrefix(locale, "en") {
+		user.Locale = "en"
+	}
+
+	// Undangan untuk email ini menentukan role; tanpa undangan berlaku SignupPolicy
+	if err := uc.invitations.SignUpWithIdentity(ctx, user); err != nil {
+		return nil, err
+	}
+	uc.logger.InfoContext(ctx, "Provisioned account via OIDC", "target_user_id", user.ID, "provisioned_role", user.Role)
+	return user, nil
+}
+
+// mapRole membaca role dari claim (string atau array) jika dikonfigurasi
+func (uc *oidcUsecase) mapRole(claims map[string]interface{}) domain.Role {
+	if uc.opts.RoleClaim == "" {
+		return uc.opts.DefaultRole
+	}
+
+	var values []string
+	switch v := claims[uc.opts.RoleClaim].(type) {
+	case string:
+		values = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	// Role dengan hak akses tertinggi yang cocok yang dipakai
+	for _, role := range []domain.Role{domain.RoleAdmin, domain.RoleInstructor, domain.RoleStudent} {
+		for _, v := range values {
+			if strings.EqualFold(v, string(role)) {
+				return role
+			}
+		}
+	}
+	return uc.opts.DefaultRole
+}
+
+// claimTrue - true hanya jika claim bernilai boolean true (atau string "true", mis. AWS Cognito)
+func claimTrue(claims map[string]interface{}, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return strings.TrimSpace(s)
+}
+
+func validRole(role domain.Role) bool {
+	switch role {
+	case domain.RoleStudent, domain.RoleInstructor, domain.RoleAdmin:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"onlearn-backend/internal/domain"
+)
+
+const testIssuer = "https://idp.example.com"
+
+type fakeOIDCProvider struct {
+	domain.OIDCProvider
+	claims map[string]interface{}
+}
+
+func (p *fakeOIDCProvider) Issuer() string { return testIssuer }
+
+func (p *fakeOIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (map[string]interface{}, error) {
+	return p.claims, nil
+}
+
+type fakeOIDCRepo struct {
+	domain.OIDCRepository
+	state      *domain.OIDCLoginState
+	identities []domain.UserIdentity
+}
+
+func (r *fakeOIDCRepo) ConsumeState(ctx context.Context, stateHash string) (*domain.OIDCLoginState, error) {
+	state := r.state
+	r.state = nil
+	return state, nil
+}
+
+func (r *fakeOIDCRepo) GetIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	for i := range r.identities {
+		if r.identities[i].Provider == provider && r.identities[i].Subject == subject {
+			return &r.identities[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOIDCRepo) CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	r.identities = append(r.identities, *identity)
+	return nil
+}
+
+func (r *fakeOIDCRepo) UpdateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	return nil
+}
+
+type fakeUserRepo struct {
+	domain.UserRepository
+	users []*domain.User
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id uint) (*domain.User, error) {
+	for _, u := range r.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) Create(ctx context.Context, user *domain.User) error {
+	user.ID = uint(len(r.users) + 1)
+	r.users = append(r.users, user)
+	return nil
+}
+
+func (r *fakeUserRepo) Update(ctx context.Context, user *domain.User) error {
+	for i, u := range r.users {
+		if u.ID == user.ID {
+			r.users[i] = user
+			return nil
+		}
+	}
+	return domain.ErrUserNotFound
+}
+
+type fakeIdentityLogin struct {
+	domain.AuthUsecase
+	user *domain.User
+}
+
+func (a *fakeIdentityLogin) LoginWithIdentity(ctx context.Context, user *domain.User, client domain.ClientInfo) (*domain.LoginResult, error) {
+	a.user = user
+	return &domain.LoginResult{Tokens: &domain.AuthTokens{AccessToken: "access"}}, nil
+}
+
+type fakeInvitationRepo struct {
+	domain.InvitationRepository
+	invitations []domain.Invitation
+}
+
+func (r *fakeInvitationRepo) GetPendingByEmail(ctx context.Context, email string) (*domain.Invitation, error) {
+	for i := range r.invitations {
+		if r.invitations[i].Email == email && r.invitations[i].IsPending(time.Now()) {
+			return &r.invitations[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeInvitationRepo) Update(ctx context.Context, invitation *domain.Invitation) error {
+	return nil
+}
+
+func TestOIDCCompleteLogin(t *testing.T) {
+	existing := func() *domain.User {
+		return &domain.User{ID: 1, Email: "budi@example.com", Role: domain.RoleInstructor, IsVerified: true}
+	}
+
+	tests := []struct {
+		name          string
+		emailVerified interface{} // nil = claim tidak dikirim
+		users         []*domain.User
+		identities    []domain.UserIdentity
+		autoProvision bool
+		expiredState  bool
+		signup        domain.SignupPolicy
+		invitations   []domain.Invitation
+
+		wantErr      string // Kode error domain
+		wantUserID   uint
+		wantVerified bool
+		wantLinked   bool
+		wantRole     domain.Role // Kosong = tidak dicek
+		wantAccepted bool        // Undangan pertama diterima oleh user yang login
+	}{
+		{
+			name:          "existing user, email_verified true",
+			users:         []*domain.User{existing()},
+			emailVerified: true,
+			wantUserID:    1,
+			wantVerified:  true,
+			wantLinked:    true,
+		},
+		{
+			name:          "existing user, email_verified string true",
+			users:         []*domain.User{existing()},
+			emailVerified: "true",
+			wantUserID:    1,
+			wantVerified:  true,
+			wantLinked:    true,
+		},
+		{
+			name:    "existing user, email_verified claim missing",
+			users:   []*domain.User{existing()},
+			wantErr: "oidc_email_not_verified",
+		},
+		{
+			name:          "existing user, email_verified false",
+			users:         []*domain.User{existing()},
+			emailVerified: false,
+			wantErr:       "oidc_email_not_verified",
+		},
+		{
+			name:          "existing user, email_verified string false",
+			users:         []*domain.User{existing()},
+			emailVerified: "false",
+			wantErr:       "oidc_email_not_verified",
+		},
+		{
+			name:         "identity already linked, email_verified missing",
+			users:        []*domain.User{existing()},
+			identities:   []domain.UserIdentity{{UserID: 1, Provider: testIssuer, Subject: "sub-1"}},
+			wantUserID:   1,
+			wantVerified: true,
+			wantLinked:   true,
+		},
+		{
+			name:          "auto provision, email_verified true",
+			autoProvision: true,
+			emailVerified: true,
+			wantUserID:    1,
+			wantVerified:  true,
+			wantLinked:    true,
+		},
+		{
+			name:          "auto provision, email_verified missing",
+			autoProvision: true,
+			wantUserID:    1,
+			wantVerified:  false,
+			wantLinked:    true,
+		},
+		{
+			name:          "invite only, unknown subject without invitation",
+			autoProvision: true,
+			emailVerified: true,
+			signup:        domain.SignupPolicy{InviteOnly: true},
+			wantErr:       "registration_invite_only",
+		},
+		{
+			name:          "invite only, pending invitation",
+			autoProvision: true,
+			emailVerified: true,
+			signup:        domain.SignupPolicy{InviteOnly: true},
+			invitations:   []domain.Invitation{{ID: 7, Email: "budi@example.com", Role: domain.RoleInstructor, ExpiresAt: time.Now().Add(time.Hour)}},
+			wantUserID:    1,
+			wantVerified:  true,
+			wantLinked:    true,
+			wantRole:      domain.RoleInstructor,
+			wantAccepted:  true,
+		},
+		{
+			name:          "invite only, invitation for unverified email",
+			autoProvision: true,
+			signup:        domain.SignupPolicy{InviteOnly: true},
+			invitations:   []domain.Invitation{{ID: 7, Email: "budi@example.com", Role: domain.RoleStudent, ExpiresAt: time.Now().Add(time.Hour)}},
+			wantErr:       "registration_invite_only",
+		},
+		{
+			name:          "invite only, expired invitation",
+			autoProvision: true,
+			emailVerified: true,
+			signup:        domain.SignupPolicy{InviteOnly: true},
+			invitations:   []domain.Invitation{{ID: 7, Email: "budi@example.com", Role: domain.RoleStudent, ExpiresAt: time.Now().Add(-time.Hour)}},
+			wantErr:       "registration_invite_only",
+		},
+		{
+			name:          "email domain not allowed",
+			autoProvision: true,
+			emailVerified: true,
+			signup:        domain.SignupPolicy{AllowedDomains: []string{"kampus.ac.id"}},
+			wantErr:       "email_domain_not_allowed",
+		},
+		{
+			name:          "email domain allowed",
+			autoProvision: true,
+			emailVerified: true,
+			signup:        domain.SignupPolicy{AllowedDomains: []string{"example.com"}},
+			wantUserID:    1,
+			wantVerified:  true,
+			wantLinked:    true,
+			wantRole:      domain.RoleStudent,
+		},
+		{
+			name:          "no account without auto provision",
+			emailVerified: true,
+			wantErr:       domain.ErrNoOIDCAccount.Code,
+		},
+		{
+			name:          "expired state",
+			users:         []*domain.User{existing()},
+			emailVerified: true,
+			expiredState:  true,
+			wantErr:       "invalid_oidc_state",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]interface{}{"sub": "sub-1", "email": "Budi@Example.com", "name": "Budi"}
+			if tt.emailVerified != nil {
+				claims["email_verified"] = tt.emailVerified
+			}
+
+			expiresAt := time.Now().Add(time.Minute)
+			if tt.expiredState {
+				expiresAt = time.Now().Add(-time.Minute)
+			}
+			oidcRepo := &fakeOIDCRepo{
+				state:      &domain.OIDCLoginState{Nonce: "nonce", CodeVerifier: "verifier", ExpiresAt: expiresAt},
+				identities: tt.identities,
+			}
+			userRepo := &fakeUserRepo{users: tt.users}
+			auth := &fakeIdentityLogin{}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			invitationRepo := &fakeInvitationRepo{invitations: tt.invitations}
+			invitations := &invitationUsecase{
+				invitationRepo: invitationRepo,
+				userRepo:       userRepo,
+				audit:          discardAudit{},
+				signup:         tt.signup,
+				logger:         logger,
+			}
+
+			uc := NewOIDCUsecase(userRepo, oidcRepo, auth, invitations, &fakeOIDCProvider{claims: claims},
+				OIDCOptions{AutoProvision: tt.autoProvision}, logger)
+
+			_, err := uc.CompleteLogin(context.Background(), "state", "code", domain.ClientInfo{})
+
+			if tt.wantErr != "" {
+				var domainErr *domain.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.wantErr {
+					t.Fatalf("CompleteLogin() error = %v, want %s", err, tt.wantErr)
+				}
+				if auth.user != nil {
+					t.Errorf("user %d was logged in despite the error", auth.user.ID)
+				}
+				if len(oidcRepo.identities) != len(tt.identities) {
+					t.Errorf("identity was linked despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompleteLogin() error = %v", err)
+			}
+			if auth.user == nil || auth.user.ID != tt.wantUserID {
+				t.Fatalf("logged in user = %+v, want ID %d", auth.user, tt.wantUserID)
+			}
+			if auth.user.IsVerified != tt.wantVerified {
+				t.Errorf("IsVerified = %v, want %v", auth.user.IsVerified, tt.wantVerified)
+			}
+			if tt.wantRole != "" && auth.user.Role != tt.wantRole {
+				t.Errorf("Role = %s, want %s", auth.user.Role, tt.wantRole)
+			}
+			if tt.wantAccepted {
+				if accepted := invitationRepo.invitations[0].AcceptedUserID; accepted == nil || *accepted != auth.user.ID {
+					t.Errorf("invitation accepted by %v, want user %d", accepted, auth.user.ID)
+				}
+			}
+			if linked, _ := oidcRepo.GetIdentity(context.Background(), testIssuer, "sub-1"); (linked != nil) != tt.wantLinked {
+				t.Errorf("identity linked = %v, want %v", linked != nil, tt.wantLinked)
+			}
+		})
+	}
+}
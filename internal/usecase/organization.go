@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"onlearn-backend/internal/domain"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var organizationSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type organizationUsecase struct {
+	orgRepo    domain.OrganizationRepository
+	userRepo   domain.UserRepository
+	courseRepo domain.CourseRepository
+	labRepo    domain.LabRepository
+	auth       domain.AuthUsecase
+	audit      domain.AuditUsecase
+	logger     *slog.Logger
+}
+
+func NewOrganizationUsecase(
+	or domain.OrganizationRepository,
+	ur domain.UserRepository,
+	cr domain.CourseRepository,
+	lr domain.LabRepository,
+	auth domain.AuthUsecase,
+	audit domain.AuditUsecase,
+	logger *slog.Logger,
+) domain.OrganizationUsecase {
+	return &organizationUsecase{
+		orgRepo:    or,
+		userRepo:   ur,
+		courseRepo: cr,
+		labRepo:    lr,
+		auth:       auth,
+		audit:      audit,
+		logger:     logger,
+	}
+}
+
+func (uc *organizationUsecase) ListOrganizations(ctx context.Context) ([]domain.Organization, error) {
+	return uc.orgRepo.GetAll(ctx)
+}
+
+func (uc *organizationUsecase) GetOrganization(ctx context.Context, id uint) (*domain.Organization, error) {
+	return uc.orgRepo.GetByID(ctx, id)
+}
+
+// GetCurrentOrganization - Organisasi tempat user terdaftar (untuk admin organisasi)
+func (uc *organizationUsecase) GetCurrentOrganization(ctx context.Context, userID uint) (*domain.Organization, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.OrganizationID == nil {
+		return nil, domain.NewNotFound("organization_not_found", "user does not belong to an organization")
+	}
+	return uc.orgRepo.GetByID(ctx, *user.OrganizationID)
+}
+
+func (uc *organizationUsecase) CreateOrganization(ctx context.Context, org *domain.Organization, actorID uint) error {
+	if err := uc.normalize(ctx, org, 0); err != nil {
+		return err
+	}
+	if err := uc.orgRepo.Create(ctx, org); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditOrganizationCreate, domain.AuditTargetOrganization, strconv.FormatUint(uint64(org.ID), 10), nil, org)
+	return nil
+}
+
+func (uc *organizationUsecase) UpdateOrganization(ctx context.Context, org *domain.Organization, actorID uint) error {
+	existing, err := uc.orgRepo.GetByID(ctx, org.ID)
+	if err != nil {
+		return err
+	}
+	before := *existing
+
+	existing.Name = org.Name
+	existing.OpenSignup = org.OpenSignup
+	if org.Slug != "" {
+		existing.Slug = org.Slug
+	}
+	if before.Slug == domain.DefaultOrganizationSlug && existing.Slug != before.Slug {
+		return domain.NewFieldError("slug", "default_organization_slug", "the default organization slug cannot be changed")
+	}
+	if err := uc.normalize(ctx, existing, existing.ID); err != nil {
+		return err
+	}
+
+	if err := uc.orgRepo.Update(ctx, existing); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditOrganizationUpdate, domain.AuditTargetOrganization, strconv.FormatUint(uint64(existing.ID), 10), before, existing)
+	*org = *existing
+	return nil
+}
+
+// normalize memvalidasi nama dan slug; slug kosong dibentuk dari nama
+func (uc *organizationUsecase) normalize(ctx context.Context, org *domain.Organization, selfID uint) error {
+	org.Name = strings.TrimSpace(org.Name)
+	if org.Name == "" {
+		return domain.NewFieldError("name", "organization_name_required", "organization name is required")
+	}
+
+	org.Slug = strings.ToLower(strings.TrimSpace(org.Slug))
+	if org.Slug == "" {
+		org.Slug = slugify(org.Name)
+	}
+	if len(org.Slug) > 64 || !organizationSlugPattern.MatchString(org.Slug) {
+		return domain.NewFieldError("slug", "invalid_organization_slug",
+			fmt.Sprintf("invalid organization slug %q: use lowercase letters, digits and dashes", org.Slug))
+	}
+
+	if existing, err := uc.orgRepo.GetBySlug(ctx, org.Slug); err == nil && existing.ID != selfID {
+		return domain.NewConflict("organization_slug_taken", "organization slug already taken")
+	}
+	return nil
+}
+
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// DeleteOrganization hanya untuk organisasi kosong (tanpa anggota, course dan lab)
+func (uc *organizationUsecase) DeleteOrganization(ctx context.Context, id, actorID uint) error {
+	org, err := uc.orgRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if org.Slug == domain.DefaultOrganizationSlug {
+		return domain.NewConflict("default_organization", "cannot delete the default organization")
+	}
+
+	scoped := domain.WithOrganization(ctx, id)
+	members, err := uc.userRepo.GetAll(scoped)
+	if err != nil {
+		return err
+	}
+	if len(members) > 0 {
+		return domain.NewConflict("organization_has_members", "organization still has members")
+	}
+	courses, err := uc.courseRepo.Count(scoped)
+	if err != nil {
+		return err
+	}
+	labs, err := uc.labRepo.Count(scoped)
+	if err != nil {
+		return err
+	}
+	if courses > 0 || labs > 0 {
+		return domain.NewConflict("organization_has_content", "organization still has courses or labs")
+	}
+
+	if err := uc.orgRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditOrganizationDelete, domain.AuditTargetOrganization, strconv.FormatUint(uint64(id), 10), org, nil)
+	return nil
+}
+
+func (uc *organizationUsecase) ListMembers(ctx context.Context, orgID uint) ([]domain.User, error) {
+	if _, err := uc.orgRepo.GetByID(ctx, orgID); err != nil {
+		return nil, err
+	}
+	return uc.userRepo.GetAll(domain.WithOrganization(ctx, orgID))
+}
+
+// SetMember memasukkan user ke organisasi dengan role tertentu (role kosong = tetap).
+// Dengan role admin, user menjadi admin organisasi tersebut.
+func (uc *organizationUsecase) SetMember(ctx context.Context, orgID, userID uint, role domain.Role, actorID uint) (*domain.User, error) {
+	if _, err := uc.orgRepo.GetByID(ctx, orgID); err != nil {
+		return nil, err
+	}
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == actorID {
+		return nil, domain.NewValidation("cannot_change_own_organization", "cannot change your own organization")
+	}
+
+	if role == "" {
+		role = user.Role
+	}
+	switch role {
+	case domain.RoleStudent, domain.RoleInstructor, domain.RoleAdmin:
+	case domain.RoleSuperAdmin:
+		return nil, domain.NewFieldError("role", "super_admin_organization", "super admins cannot belong to an organization")
+	default:
+		return nil, domain.NewFieldError("role", "invalid_role", fmt.Sprintf("invalid role %q", role))
+	}
+
+	moving := user.OrganizationID == nil || *user.OrganizationID != orgID
+	if moving {
+		// Course tetap di organisasi lama, jadi pemiliknya tidak boleh pindah
+		courses, err := uc.courseRepo.GetByInstructorID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(courses) > 0 {
+			return nil, domain.NewConflict("user_has_courses", "transfer the user's courses before moving them to another organization")
+		}
+	}
+
+	before := *user
+	user.OrganizationID = &orgID
+	user.Role = role
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Token lama masih membawa organisasi dan role sebelumnya
+	if moving || before.Role != role {
+		if err := uc.auth.RevokeAllSessions(ctx, userID); err != nil {
+			uc.logger.WarnContext(ctx, "Failed to revoke sessions after organization change", "target_user_id", userID, "error", err)
+		}
+	}
+
+	uc.audit.Record(ctx, actorID, domain.AuditOrganizationMemberSet, domain.AuditTargetUser, strconv.FormatUint(uint64(userID), 10), before, user)
+	return user, nil
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+	"onlearn-backend/internal/domain"
+)
+
+type policy struct {
+	userRepo domain.UserRepository
+}
+
+// NewPolicy membuat policy layer. Role dibaca dari database (bukan dari token)
+// sehingga perubahan role langsung berlaku.
+func NewPolicy(ur domain.UserRepository) domain.Policy {
+	return &policy{userRepo: ur}
+}
+
+func (p *policy) Authorize(ctx context.Context, userID uint, perm domain.Permission, ownerID *uint) error {
+	user, err := p.authorizedUser(ctx, userID, perm)
+	if err != nil {
+		return err
+	}
+
+	switch user.Role.Scope(perm) {
+	case domain.ScopeAny:
+		return nil
+	case domain.ScopeOwn:
+		// Resource tanpa pemilik bukan milik siapa pun, termasuk user ini
+		if ownerID != nil && *ownerID == userID {
+			return nil
+		}
+	}
+	return &domain.ForbiddenError{Permission: perm}
+}
+
+func (p *policy) AuthorizeAction(ctx context.Context, userID uint, perm domain.Permission) error {
+	user, err := p.authorizedUser(ctx, userID, perm)
+	if err != nil {
+		return err
+	}
+	if !user.Role.Can(perm) {
+		return &domain.ForbiddenError{Permission: perm}
+	}
+	return nil
+}
+
+// authorizedUser memuat user dan menerapkan batasan scope API key
+func (p *policy) authorizedUser(ctx context.Context, userID uint, perm domain.Permission) (*domain.User, error) {
+	user, err := p.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	// Request lewat API key dibatasi scope kunci, walaupun role-nya punya permission
+	if scopes, ok := domain.APIKeyScopesFromContext(ctx); ok && !scopes.Allows(perm) {
+		return nil, &domain.ForbiddenError{Permission: perm}
+	}
+	return user, nil
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"onlearn-backend/internal/domain"
+	"strconv"
+	"strings"
+)
+
+// Nama kolom yang dikenali di baris header file import (tidak case-sensitive)
+var userImportColumns = map[string][]string{
+	"name":  {"name", "nama", "full name", "nama lengkap"},
+	"email": {"email", "e-mail", "email address"},
+	"role":  {"role", "peran"},
+}
+
+// ImportUsers membuat banyak user sekaligus dari baris CSV/XLSX. Baris pertama harus
+// header dengan kolom name dan email (role opsional). Baris yang tidak valid dilewati
+// dan dilaporkan; baris lain tetap diproses.
+func (uc *userUsecase) ImportUsers(ctx context.Context, records [][]string, opts domain.UserImportOptions, actorID uint) (*domain.UserImportReport, error) {
+	if len(records) == 0 {
+		return nil, domain.NewFieldError("file", "file_empty", "file is empty")
+	}
+	columns, err := userImportHeader(records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DefaultRole == "" {
+		opts.DefaultRole = domain.RoleStudent
+	}
+	if err := validateImportRole(opts.DefaultRole); err != nil {
+		return nil, err
+	}
+	if err := uc.validateImportTargets(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	report := &domain.UserImportReport{DryRun: opts.DryRun}
+	seen := make(map[string]int) // email -> nomor baris pertama
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row := uc.validateImportRow(ctx, i+2, record, columns, opts.DefaultRole, seen)
+		if len(row.Errors) == 0 && !opts.DryRun {
+			if opts.SendInvites {
+				uc.inviteImportedUser(ctx, &row, opts, actorID)
+			} else {
+				uc.createImportedUser(ctx, &row, opts, actorID)
+			}
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			row.Status = domain.UserImportError
+			report.Failed++
+		case opts.DryRun:
+			row.Status = domain.UserImportValid
+			report.Valid++
+		case opts.SendInvites:
+			row.Status = domain.UserImportInvited
+			report.Valid++
+			report.Invited++
+		default:
+			row.Status = domain.UserImportCreated
+			report.Valid++
+			report.Created++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	report.Total = len(report.Rows)
+
+	return report, nil
+}
+
+func userImportHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, cell := range header {
+		name := strings.ToLower(strings.TrimSpace(cell))
+		for column, aliases := range userImportColumns {
+			for _, alias := range aliases {
+				if name == alias {
+					if _, dup := columns[column]; dup {
+						return nil, domain.NewFieldError("file", "import_duplicate_column", fmt.Sprintf("duplicate column %q in header", column))
+					}
+					columns[column] = i
+				}
+			}
+		}
+	}
+
+	for _, required := range []string{"name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, domain.NewFieldError("file", "import_missing_column", fmt.Sprintf("missing required column %q in header", required))
+		}
+	}
+	return columns, nil
+}
+
+// validateImportTargets memastikan semua course dan lab tujuan ada sebelum import dimulai
+func (uc *userUsecase) validateImportTargets(ctx context.Context, opts domain.UserImportOptions) error {
+	for _, courseID := range opts.CourseIDs {
+		if _, err := uc.courseRepo.GetByID(ctx, courseID); err != nil {
+			return domain.NewFieldError("course_ids", "course_not_found", fmt.Sprintf("course %d not found", courseID))
+		}
+	}
+	for _, labID := range opts.LabIDs {
+		if _, err := uc.labRepo.GetByID(ctx, labID); err != nil {
+			return domain.NewFieldError("lab_ids", "lab_not_found", fmt.Sprintf("lab %d not found", labID))
+		}
+	}
+	return nil
+}
+
+func (uc *userUsecase) validateImportRow(ctx context.Context, rowNumber int, record []string, columns map[string]int, defaultRole domain.Role, seen map[string]int) domain.UserImportRow {
+	cell := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := domain.UserImportRow{
+		Row:   rowNumber,
+		Name:  cell("name"),
+		Email: strings.ToLower(cell("email")),
+		Role:  domain.Role(strings.ToLower(cell("role"))),
+	}
+	if row.Role == "" {
+		row.Role = defaultRole
+	}
+
+	if row.Name == "" {
+		row.Errors = append(row.Errors, "name is required")
+	}
+	if err := validateImportRole(row.Role); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	}
+
+	switch addr, err := mail.ParseAddress(row.Email); {
+	case row.Email == "":
+		row.Errors = append(row.Errors, "email is required")
+	case err != nil || addr.Address != row.Email:
+		row.Errors = append(row.Errors, "invalid email address")
+	default:
+		if first, dup := seen[row.Email]; dup {
+			row.Errors = append(row.Errors, fmt.Sprintf("duplicate email, already used in row %d", first))
+		} else {
+			seen[row.Email] = rowNumber
+			if existing, err := uc.userRepo.GetByEmail(ctx, row.Email); err == nil && existing != nil {
+				row.Errors = append(row.Errors, "email already exists")
+			}
+		}
+	}
+
+	return row
+}
+
+// validateImportRole - Akun admin sengaja tidak bisa dibuat lewat import
+func validateImportRole(role domain.Role) error {
+	switch role {
+	case domain.RoleStudent, domain.RoleInstructor:
+		return nil
+	case domain.RoleAdmin, domain.RoleSuperAdmin:
+		return domain.NewFieldError("role", "admin_import_not_allowed", "admin accounts cannot be imported")
+	default:
+		return domain.NewFieldError("role", "invalid_role", fmt.Sprintf("invalid role %q", role))
+	}
+}
+
+// createImportedUser membuat user tanpa password (tidak bisa login sampai password dipasang
+// lewat lupa password), lalu mendaftarkan student ke course/lab yang dipilih. Password acak
+// tidak di-hash di sini: bcrypt per baris membuat import 10.000 baris berjalan berjam-jam.
+func (uc *userUsecase) createImportedUser(ctx context.Context, row *domain.UserImportRow, opts domain.UserImportOptions, actorID uint) {
+	user := &domain.User{
+		Name:  row.Name,
+		Email: row.Email,
+		Role:  row.Role,
+	}
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		row.Errors = append(row.Errors, "failed to create user: "+err.Error())
+		return
+	}
+	row.UserID = user.ID
+	uc.audit.Record(ctx, actorID, domain.AuditUserCreate, domain.AuditTargetUser, strconv.FormatUint(uint64(user.ID), 10), nil, user)
+
+	if user.Role == domain.RoleStudent {
+		for _, courseID := range opts.CourseIDs {
+			if err := uc.enrollmentRepo.Create(ctx, &domain.Enrollment{UserID: user.ID, CourseID: courseID}); err != nil {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("failed to enroll in course %d: %v", courseID, err))
+				continue
+			}
+			enrollmentsTotal.WithLabelValues("course", "import").Inc()
+		}
+		for _, labID := range opts.LabIDs {
+			grade := &domain.LabGrade{UserID: user.ID, LabID: labID}
+			if err := uc.labRepo.CreateGrade(ctx, grade); err != nil {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("failed to add to lab %d: %v", labID, err))
+				continue
+			}
+			enrollmentsTotal.WithLabelValues("lab", "import").Inc()
+			uc.audit.Record(ctx, actorID, domain.AuditLabStudentAdd, domain.AuditTargetLabGrade, labGradeTargetID(labID, user.ID), nil, grade)
+		}
+	}
+}
+
+// inviteImportedUser mengirim undangan mendaftar; akun baru dibuat dan didaftarkan ke
+// course/lab yang dipilih saat undangan diterima
+func (uc *userUsecase) inviteImportedUser(ctx context.Context, row *domain.UserImportRow, opts domain.UserImportOptions, actorID uint) {
+	invitation := &domain.Invitation{
+		Email: row.Email,
+		Name:  row.Name,
+		Role:  row.Role,
+	}
+	if row.Role == domain.RoleStudent {
+		invitation.CourseIDs = opts.CourseIDs
+		invitation.LabIDs = opts.LabIDs
+	}
+
+	created, err := uc.invitations.CreateImportInvitation(ctx, invitation, actorID)
+	if err != nil {
+		row.Errors = append(row.Errors, "failed to create invitation: "+err.Error())
+		return
+	}
+	row.InvitationID = created.ID
+}
+
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"onlearn-backend/internal/domain"
+)
+
+func TestImportUsersCreatesAccountsWithoutPassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		rows        int
+		wantCreated int
+	}{
+		{name: "single row", rows: 1, wantCreated: 1},
+		// Dengan bcrypt cost 14 per baris, 200 baris butuh beberapa menit
+		{name: "many rows", rows: 200, wantCreated: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := [][]string{{"name", "email"}}
+			for i := 0; i < tt.rows; i++ {
+				records = append(records, []string{fmt.Sprintf("Siswa %d", i), fmt.Sprintf("siswa%d@example.com", i)})
+			}
+			repo := &fakeUserRepo{}
+			uc := &userUsecase{userRepo: repo, audit: discardAudit{}}
+
+			report, err := uc.ImportUsers(context.Background(), records, domain.UserImportOptions{}, 1)
+			if err != nil {
+				t.Fatalf("ImportUsers() error = %v", err)
+			}
+			if report.Created != tt.wantCreated || len(repo.users) != tt.wantCreated {
+				t.Fatalf("created = %d (stored %d), want %d", report.Created, len(repo.users), tt.wantCreated)
+			}
+			for _, u := range repo.users {
+				if u.Password != "" {
+					t.Errorf("user %s has password %q, want none until set via password reset", u.Email, u.Password)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package logging builds the application's log/slog logger. Records are
+// written as JSON (or logfmt-style text for local runs) and are enriched with
+// request-scoped attributes such as the request ID and the authenticated user,
+// which are read from the context passed to the *Context logging methods.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ContextAttrs mengambil atribut dari context request (mis. request_id, user_id)
+type ContextAttrs func(ctx context.Context) []slog.Attr
+
+type Options struct {
+	Level  slog.Level
+	Format string // "json" (default) atau "text"
+	Output io.Writer
+
+	// ContextAttrs ditambahkan ke setiap record yang ditulis dengan context
+	ContextAttrs ContextAttrs
+}
+
+// New membuat logger; log standar (log.Printf) sebaiknya diarahkan ke logger ini
+// lewat slog.SetDefault agar semua output memakai format yang sama
+func New(opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+
+	var handler slog.Handler
+	if opts.Format == "text" {
+		handler = slog.NewTextHandler(opts.Output, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(opts.Output, handlerOpts)
+	}
+	if opts.ContextAttrs != nil {
+		handler = &contextHandler{Handler: handler, attrs: opts.ContextAttrs}
+	}
+	return slog.New(handler)
+}
+
+// ParseLevel menerima "debug", "info", "warn"/"warning" dan "error"
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+type contextHandler struct {
+	slog.Handler
+	attrs ContextAttrs
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		r.AddAttrs(h.attrs(ctx)...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs), attrs: h.attrs}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name), attrs: h.attrs}
+}
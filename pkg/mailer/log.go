@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+type logTransport struct {
+	dir    string
+	logger *slog.Logger
+}
+
+// NewLogTransport creates a transport for local development.
+// If dir is set every email is written to dir as an .eml file, otherwise only
+// the sender, recipient and subject are logged. Bodies are never logged because
+// they carry verification codes and password reset links.
+func NewLogTransport(dir string, logger *slog.Logger) Transport {
+	return &logTransport{dir: dir, logger: logger}
+}
+
+func (t *logTransport) Deliver(ctx context.Context, msg Message) error {
+	if t.dir == "" {
+		t.logger.InfoContext(ctx, "Email (not sent)", "from", msg.From, "to", msg.To, "subject", msg.Subject)
+		return nil
+	}
+
+	if err := os.MkdirAll(t.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	body, err := buildMIME(msg)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102-150405.000000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	path := filepath.Join(t.dir, name)
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return fmt.Errorf("failed to write email file: %w", err)
+	}
+
+	t.logger.InfoContext(ctx, "Email saved", "subject", msg.Subject, "to", msg.To, "path", path)
+	return nil
+}
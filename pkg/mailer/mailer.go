@@ -0,0 +1,158 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"onlearn-backend/internal/domain"
+	"sync"
+	"time"
+)
+
+// Message is a fully rendered email ready to be delivered by a Transport.
+type Message struct {
+	From     string
+	To       string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// Transport delivers a rendered message (SMTP, log/file, ...).
+type Transport interface {
+	Deliver(ctx context.Context, msg Message) error
+}
+
+// Options configures the mailer.
+type Options struct {
+	From          string        // Alamat pengirim, contoh: "OnLearn <no-reply@onlearn.com>"
+	DefaultLocale string        // Bahasa default jika Email.Locale kosong ("id" atau "en")
+	MaxRetries    int           // Jumlah percobaan ulang jika pengiriman gagal
+	RetryBackoff  time.Duration // Jeda awal antar percobaan (naik 2x setiap percobaan)
+	QueueSize     int           // Kapasitas antrian pengiriman
+	Logger        *slog.Logger  // Default slog.Default()
+}
+
+type mailer struct {
+	transport Transport
+	renderer  *renderer
+	opts      Options
+
+	queue chan Message
+	wg    sync.WaitGroup
+	once  sync.Once
+}
+
+// Mailer renders template-based emails and delivers them in the background
+// with retries, so callers never block on the mail server.
+type Mailer interface {
+	domain.Mailer
+	Close()
+}
+
+// New creates a mailer backed by the given transport and starts its delivery worker.
+func New(transport Transport, opts Options) (Mailer, error) {
+	r, err := newRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DefaultLocale == "" {
+		opts.DefaultLocale = "id"
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 2 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	m := &mailer{
+		transport: transport,
+		renderer:  r,
+		opts:      opts,
+		queue:     make(chan Message, opts.QueueSize),
+	}
+
+	m.wg.Add(1)
+	go m.worker()
+
+	return m, nil
+}
+
+// Send renders the email template and enqueues it for delivery.
+// Rendering errors are returned immediately; delivery errors are retried and logged.
+func (m *mailer) Send(ctx context.Context, email domain.Email) error {
+	if email.To == "" {
+		return errors.New("email recipient is required")
+	}
+
+	locale := email.Locale
+	if locale == "" {
+		locale = m.opts.DefaultLocale
+	}
+
+	subject, text, html, err := m.renderer.render(email.Template, locale, email.Data)
+	if err != nil {
+		return err
+	}
+
+	msg := Message{
+		From:     m.opts.From,
+		To:       email.To,
+		Subject:  subject,
+		TextBody: text,
+		HTMLBody: html,
+	}
+
+	select {
+	case m.queue <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return fmt.Errorf("mail queue is full, dropping email to %s", email.To)
+	}
+}
+
+// Close stops accepting new emails and waits until the queue is drained.
+func (m *mailer) Close() {
+	m.once.Do(func() {
+		close(m.queue)
+	})
+	m.wg.Wait()
+}
+
+func (m *mailer) worker() {
+	defer m.wg.Done()
+	for msg := range m.queue {
+		m.deliverWithRetry(msg)
+	}
+}
+
+func (m *mailer) deliverWithRetry(msg Message) {
+	backoff := m.opts.RetryBackoff
+	var err error
+	for attempt := 1; attempt <= m.opts.MaxRetries; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = m.transport.Deliver(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		m.opts.Logger.Warn("Failed to send email", "subject", msg.Subject, "attempt", attempt, "max_attempts", m.opts.MaxRetries, "error", err)
+		if attempt < m.opts.MaxRetries {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	m.opts.Logger.Error("Giving up sending email", "subject", msg.Subject, "error", err)
+}
@@ -0,0 +1,161 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig holds the connection settings for an SMTP server.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // Kosongkan jika server tidak memerlukan autentikasi (mis. MailHog)
+	Password string
+	Timeout  time.Duration
+}
+
+type smtpTransport struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPTransport creates a transport that sends emails through an SMTP server.
+// STARTTLS is used automatically when the server supports it.
+func NewSMTPTransport(cfg SMTPConfig) Transport {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &smtpTransport{cfg: cfg}
+}
+
+func (t *smtpTransport) Deliver(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	body, err := buildMIME(msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(t.cfg.Host, fmt.Sprint(t.cfg.Port))
+	dialer := &net.Dialer{Timeout: t.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, t.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: t.cfg.Host}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	if t.cfg.Username != "" {
+		auth := smtp.PlainAuth("", t.cfg.Username, t.cfg.Password, t.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// buildMIME menyusun pesan multipart/alternative (text + html)
+func buildMIME(msg Message) ([]byte, error) {
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain", msg.TextBody},
+		{"text/html", msg.HTMLBody},
+	}
+	for _, part := range parts {
+		if part.body == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=\"utf-8\"\r\n", part.contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(strings.ReplaceAll(part.body, "\n", "\r\n"))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
+
+func randomBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "onlearn-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"onlearn-backend/internal/domain"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// supportedLocales adalah bahasa yang memiliki template email
+var supportedLocales = []string{"id", "en"}
+
+// renderer holds parsed email templates per locale.
+// Each email has a <name>.txt (subject + plain text body) and a <name>.html file.
+type renderer struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+func newRenderer() (*renderer, error) {
+	r := &renderer{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	for _, locale := range supportedLocales {
+		textTmpl, err := texttemplate.ParseFS(templateFS, "templates/"+locale+"/*.txt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s text email templates: %w", locale, err)
+		}
+		r.text[locale] = textTmpl
+
+		htmlTmpl, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+locale+"/*.html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s html email templates: %w", locale, err)
+		}
+		r.html[locale] = htmlTmpl
+	}
+
+	return r, nil
+}
+
+func (r *renderer) render(name domain.EmailTemplate, locale string, data map[string]interface{}) (subject, text, html string, err error) {
+	textTmpl, ok := r.text[locale]
+	if !ok {
+		textTmpl, locale = r.text[supportedLocales[0]], supportedLocales[0]
+	}
+	htmlTmpl := r.html[locale]
+
+	var buf bytes.Buffer
+	if err = textTmpl.ExecuteTemplate(&buf, string(name)+"_subject", data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render email subject %s: %w", name, err)
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err = textTmpl.ExecuteTemplate(&buf, string(name)+".txt", data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render email text %s: %w", name, err)
+	}
+	text = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err = htmlTmpl.ExecuteTemplate(&buf, string(name)+".html", data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render email html %s: %w", name, err)
+	}
+	html = buf.String()
+
+	return subject, text, html, nil
+}
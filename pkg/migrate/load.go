@@ -0,0 +1,66 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+// sqlFilePattern - Format nama file: 0001_baseline.up.sql / 0001_baseline.down.sql
+var sqlFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// LoadSQL membaca pasangan script up/down dari dir. Script down boleh tidak ada,
+// artinya migrasi tersebut tidak bisa di-rollback.
+func LoadSQL(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	var order []int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := sqlFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+		script, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+			order = append(order, version)
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, mig.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			mig.Up = SQL(string(script))
+		} else {
+			mig.Down = SQL(string(script))
+		}
+	}
+
+	migrations := make([]Migration, 0, len(order))
+	for _, version := range order {
+		mig := byVersion[version]
+		if mig.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	return migrations, nil
+}
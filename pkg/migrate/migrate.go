@@ -0,0 +1,214 @@
+// Package migrate runs versioned schema migrations against PostgreSQL.
+// Applied versions are recorded in a migrations table, and every run holds a
+// PostgreSQL advisory lock so that several replicas booting at the same time
+// apply each migration exactly once. Migrations are either SQL scripts
+// (NNNN_name.up.sql / NNNN_name.down.sql) or Go functions, e.g. for MongoDB.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultTable - Nama tabel riwayat migrasi
+const DefaultTable = "schema_migrations"
+
+// lockKey - Kunci pg_advisory_lock; sama untuk semua replika aplikasi ini
+const lockKey int64 = 0x6f6e6c6561726e // "onlearn"
+
+// Step menjalankan satu arah migrasi. Untuk migrasi SQL, semua perubahan berjalan
+// di dalam tx; migrasi non-SQL (mis. index MongoDB) harus aman diulang karena tidak
+// ikut rollback jika pencatatan versinya gagal.
+type Step func(ctx context.Context, tx *sql.Tx) error
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      Step
+	Down    Step // nil = tidak bisa di-rollback
+}
+
+// SQL membuat Step dari script SQL (boleh berisi beberapa statement)
+func SQL(script string) Step {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, script)
+		return err
+	}
+}
+
+// Status - Migrasi beserta waktu diterapkannya (nil = belum diterapkan)
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+var ErrIrreversible = errors.New("migration cannot be rolled back")
+
+type Migrator struct {
+	db         *sql.DB
+	table      string
+	migrations []Migration
+}
+
+// New mengurutkan migrasi berdasarkan versi dan menolak versi ganda
+func New(db *sql.DB, migrations []Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q has invalid version %d", m.Name, m.Version)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up step", m.Version, m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d (%s, %s)", m.Version, sorted[i-1].Name, m.Name)
+		}
+	}
+	return &Migrator{db: db, table: DefaultTable, migrations: sorted}, nil
+}
+
+// Up menerapkan semua migrasi yang belum diterapkan, berurutan dari versi terkecil.
+// Migrasi yang sudah tercatat tapi tidak dikenal build ini (mis. dari replika yang
+// lebih baru) dibiarkan.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, mig, mig.Up, true); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down me-rollback sejumlah steps migrasi terakhir yang sudah diterapkan
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == nil {
+				return fmt.Errorf("%w: %d_%s", ErrIrreversible, mig.Version, mig.Name)
+			}
+			if err := m.run(ctx, conn, mig, mig.Down, false); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status mengembalikan semua migrasi yang dikenal beserta status penerapannya
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := Status{Version: mig.Version, Name: mig.Name}
+			if at, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock memegang advisory lock selama fn berjalan. Lock level session terikat ke
+// satu koneksi, jadi semua query migrasi memakai koneksi yang sama.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`, m.table)); err != nil {
+		return fmt.Errorf("create %s table: %w", m.table, err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, applied_at FROM %s", m.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// run menjalankan satu step dan mencatat/menghapus versinya dalam transaksi yang sama
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, mig Migration, step Step, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+	if err := step(ctx, tx); err != nil {
+		return fmt.Errorf("migration %d_%s (%s): %w", mig.Version, mig.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version, name) VALUES ($1, $2)", m.table), mig.Version, mig.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = $1", m.table), mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
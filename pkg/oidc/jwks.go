@@ -0,0 +1,114 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type keySet struct {
+	keys map[string]interface{}
+	// Dipakai jika ID token tidak menyertakan kid dan provider hanya punya satu key
+	only interface{}
+}
+
+func (s *keySet) find(kid string) (interface{}, bool) {
+	if kid == "" && s.only != nil {
+		return s.only, true
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+func parseKeySet(jwks []jsonWebKey) (*keySet, error) {
+	set := &keySet{keys: make(map[string]interface{})}
+	for _, jwk := range jwks {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key interface{}
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = parseRSAKey(jwk)
+		case "EC":
+			key, err = parseECKey(jwk)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk %q: %w", jwk.Kid, err)
+		}
+		set.keys[jwk.Kid] = key
+	}
+
+	if len(set.keys) == 0 {
+		return nil, errors.New("jwks does not contain any usable signing key")
+	}
+	if len(set.keys) == 1 {
+		for _, k := range set.keys {
+			set.only = k
+		}
+	}
+	return set, nil
+}
+
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func parseECKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return nil, errors.New("point is not on curve")
+	}
+	return key, nil
+}
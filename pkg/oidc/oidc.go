@@ -0,0 +1,255 @@
+// Package oidc implements an OpenID Connect relying party for the
+// authorization code flow with PKCE (RFC 7636). Provider metadata is loaded
+// lazily from {issuer}/.well-known/openid-configuration so the application can
+// start even when the identity provider is temporarily unreachable.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config holds the relying party settings registered at the identity provider.
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string // Kosongkan untuk public client (hanya PKCE)
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// Provider talks to a single OpenID Connect identity provider.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu        sync.Mutex
+	metadata  *metadata
+	keys      *keySet
+	keysFetch time.Time
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// New creates a provider. No network request is made until the first login.
+func New(cfg Config) *Provider {
+	cfg.IssuerURL = strings.TrimRight(cfg.IssuerURL, "/")
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+// Issuer mengembalikan issuer URL, dipakai sebagai identitas provider
+func (p *Provider) Issuer() string {
+	return p.cfg.IssuerURL
+}
+
+// AuthCodeURL membuat URL redirect ke halaman login identity provider
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", CodeChallengeS256(codeVerifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return md.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange menukar authorization code dengan token, lalu memverifikasi ID token
+// (signature, issuer, audience, expiry, nonce) dan mengembalikan claims-nya.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (map[string]interface{}, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response does not contain an id_token")
+	}
+
+	return p.verifyIDToken(ctx, md, token.IDToken, nonce)
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, md *metadata, rawIDToken, nonce string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, md, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(md.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if exp, err := claims.GetExpirationTime(); err != nil || exp == nil {
+		return nil, errors.New("invalid id_token: missing exp claim")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("invalid id_token: missing sub claim")
+	}
+
+	return claims, nil
+}
+
+// discover memuat metadata provider sekali lalu menyimpannya di cache
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var md metadata
+	if err := p.getJSON(ctx, p.cfg.IssuerURL+"/.well-known/openid-configuration", &md); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimRight(md.Issuer, "/") != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("oidc discovery failed: issuer mismatch (expected %s, got %s)", p.cfg.IssuerURL, md.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, errors.New("oidc discovery failed: incomplete provider metadata")
+	}
+
+	p.metadata = &md
+	return p.metadata, nil
+}
+
+// key mencari public key berdasarkan kid. JWKS diambil ulang jika kid tidak
+// dikenal (rotasi key), dibatasi maksimal sekali per menit.
+func (p *Provider) key(ctx context.Context, md *metadata, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil {
+		if k, ok := p.keys.find(kid); ok {
+			return k, nil
+		}
+		if time.Since(p.keysFetch) < time.Minute {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+
+	var raw struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, md.JWKSURI, &raw); err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	keys, err := parseKeySet(raw.Keys)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysFetch = time.Now()
+
+	if k, ok := p.keys.find(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// CodeChallengeS256 menghitung PKCE code_challenge dari code_verifier
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,330 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "onlearn"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "http://localhost:8080/auth/oidc/callback"
+	testCode         = "auth-code"
+	testKid          = "key-1"
+)
+
+// mockProvider - Identity provider minimal: discovery, JWKS dan token endpoint dengan PKCE
+type mockProvider struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	issuer    string // Issuer di discovery; default URL server
+	challenge string // code_challenge dari authorization request
+	idToken   func(claims jwt.MapClaims) string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockProvider{t: t, key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/jwks", m.jwks)
+	mux.HandleFunc("/token", m.token)
+	m.srv = httptest.NewServer(mux)
+	t.Cleanup(m.srv.Close)
+
+	m.issuer = m.srv.URL
+	m.idToken = m.sign
+	return m
+}
+
+func (m *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 m.issuer,
+		"authorization_endpoint": m.srv.URL + "/authorize",
+		"token_endpoint":         m.srv.URL + "/token",
+		"jwks_uri":               m.srv.URL + "/jwks",
+	})
+}
+
+func (m *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := m.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	user, pass, _ := r.BasicAuth()
+	if user != testClientID || pass != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("code") != testCode ||
+		r.PostForm.Get("redirect_uri") != testRedirectURL ||
+		CodeChallengeS256(r.PostForm.Get("code_verifier")) != m.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   m.srv.URL,
+		"aud":   testClientID,
+		"sub":   "user-123",
+		"email": "budi@example.com",
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": m.idToken(claims), "token_type": "Bearer"})
+}
+
+func (m *mockProvider) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return signed
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (m *mockProvider) provider() *Provider {
+	return New(Config{
+		IssuerURL:    m.srv.URL + "/",
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		HTTPClient:   m.srv.Client(),
+	})
+}
+
+// authorize menjalankan AuthCodeURL dan mencatat code_challenge seperti halaman login provider
+func (m *mockProvider) authorize(p *Provider, state, nonce, verifier string) url.Values {
+	m.t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		m.t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != m.srv.URL+"/authorize" {
+		m.t.Fatalf("authorization endpoint = %s, want %s/authorize", got, m.srv.URL)
+	}
+	q := u.Query()
+	m.challenge = q.Get("code_challenge")
+	return q
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	m := newMockProvider(t)
+	q := m.authorize(m.provider(), "state-1", "nonce-1", "verifier-1")
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallengeS256("verifier-1"),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := q.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	if q.Has("code_verifier") {
+		t.Errorf("authorization URL must not contain the code_verifier")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	const (
+		nonce    = "nonce-1"
+		verifier = "verifier-1"
+	)
+
+	tests := []struct {
+		name     string
+		verifier string
+		claims   func(m *mockProvider, c jwt.MapClaims) // Mengubah claims ID token dari provider
+		token    func(m *mockProvider, c jwt.MapClaims) string
+		wantErr  string
+	}{
+		{name: "valid"},
+		{
+			name:     "wrong code verifier",
+			verifier: "other-verifier",
+			wantErr:  "returned 400",
+		},
+		{
+			name:    "nonce mismatch",
+			claims:  func(m *mockProvider, c jwt.MapClaims) { c["nonce"] = "other-nonce" },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "missing nonce",
+			claims:  func(m *mockProvider, c jwt.MapClaims) { delete(c, "nonce") },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "wrong audience",
+			claims:  func(m *mockProvider, c jwt.MapClaims) { c["aud"] = "another-client" },
+			wantErr: "invalid id_token",
+		},
+		{
+			name:    "wrong issuer",
+			claims:  func(m *mockProvider, c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+			wantErr: "invalid id_token",
+		},
+		{
+			name: "expired",
+			claims: func(m *mockProvider, c jwt.MapClaims) {
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+			},
+			wantErr: "invalid id_token",
+		},
+		{
+			name:    "missing exp",
+			claims:  func(m *mockProvider, c jwt.MapClaims) { delete(c, "exp") },
+			wantErr: "missing exp",
+		},
+		{
+			name:    "missing sub",
+			claims:  func(m *mockProvider, c jwt.MapClaims) { delete(c, "sub") },
+			wantErr: "missing sub",
+		},
+		{
+			name: "unknown key",
+			token: func(m *mockProvider, c jwt.MapClaims) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+				token.Header["kid"] = "rotated-key"
+				signed, _ := token.SignedString(m.key)
+				return signed
+			},
+			wantErr: "unknown signing key",
+		},
+		{
+			name: "signed with another key",
+			token: func(m *mockProvider, c jwt.MapClaims) string {
+				other, _ := rsa.GenerateKey(rand.Reader, 2048)
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+				token.Header["kid"] = testKid
+				signed, _ := token.SignedString(other)
+				return signed
+			},
+			wantErr: "invalid id_token",
+		},
+		{
+			name: "symmetric algorithm",
+			token: func(m *mockProvider, c jwt.MapClaims) string {
+				signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(testClientSecret))
+				return signed
+			},
+			wantErr: "invalid id_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			m.idToken = func(c jwt.MapClaims) string {
+				c["nonce"] = nonce
+				if tt.claims != nil {
+					tt.claims(m, c)
+				}
+				if tt.token != nil {
+					return tt.token(m, c)
+				}
+				return m.sign(c)
+			}
+
+			p := m.provider()
+			m.authorize(p, "state-1", nonce, verifier)
+
+			exchangeVerifier := verifier
+			if tt.verifier != "" {
+				exchangeVerifier = tt.verifier
+			}
+			claims, err := p.Exchange(context.Background(), testCode, exchangeVerifier, nonce)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Exchange() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if claims["sub"] != "user-123" || claims["email"] != "budi@example.com" {
+				t.Errorf("Exchange() claims = %v", claims)
+			}
+		})
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockProvider(t)
+	m.issuer = "https://login.example.com"
+
+	_, err := m.provider().AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("AuthCodeURL() error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	tests := []struct {
+		verifier string
+		want     string
+	}{
+		// RFC 7636 lampiran B
+		{"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		{"", "47DEQpj8HBSa-_TImW-5JCeuQeRkm5NMpJWZG3hSuFU"},
+	}
+	for _, tt := range tests {
+		if got := CodeChallengeS256(tt.verifier); got != tt.want {
+			t.Errorf("CodeChallengeS256(%q) = %s, want %s", tt.verifier, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package search holds the database-independent parts of catalog search:
+// splitting a query into terms, a typo-tolerant regular expression that both
+// MongoDB (PCRE) and Go (RE2) understand, and HTML-safe highlighting of the
+// words that matched.
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxTerms = 8
+
+	// Term sependek ini hanya dicocokkan sebagai prefix; toleransi salah ketik
+	// pada kata pendek menghasilkan terlalu banyak kecocokan palsu
+	minFuzzyLen = 5
+	maxFuzzyLen = 32
+
+	// wordChar - Satu huruf atau angka; sama di RE2 (Go) dan PCRE (MongoDB)
+	wordChar = `[\p{L}\p{N}]`
+)
+
+// Terms memecah query menjadi kata (huruf/angka) huruf kecil tanpa duplikat. Kata satu
+// huruf dibuang (mis. "s" dari "course's") karena sebagai prefix cocok dengan hampir semua teks.
+func Terms(query string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if seen[f] || utf8.RuneCountInString(f) < 2 {
+			continue
+		}
+		seen[f] = true
+		terms = append(terms, f)
+		if len(terms) == maxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// Pattern membuat regex (tanpa flag; pakai case-insensitive) yang cocok dengan kata
+// yang diawali salah satu term, atau diawali variasi term dengan satu salah ketik:
+// satu huruf diganti, hilang, disisipkan atau dua huruf bersebelahan tertukar.
+// Mengembalikan "" jika terms kosong.
+func Pattern(terms []string) string {
+	seen := make(map[string]bool)
+	var alts []string
+	add := func(alt string) {
+		if !seen[alt] {
+			seen[alt] = true
+			alts = append(alts, alt)
+		}
+	}
+
+	for _, term := range terms {
+		r := []rune(term)
+		if len(r) < minFuzzyLen || len(r) > maxFuzzyLen {
+			add(regexp.QuoteMeta(term))
+			continue
+		}
+		for i := range r {
+			// Substitusi (juga mencakup term itu sendiri)
+			add(quote(r[:i]) + wordChar + quote(r[i+1:]))
+			// Huruf hilang
+			add(quote(r[:i]) + quote(r[i+1:]))
+			// Huruf tambahan sebelum r[i]
+			add(quote(r[:i]) + wordChar + quote(r[i:]))
+			// Dua huruf tertukar
+			if i+1 < len(r) {
+				add(quote(r[:i]) + quote([]rune{r[i+1], r[i]}) + quote(r[i+2:]))
+			}
+		}
+	}
+
+	if len(alts) == 0 {
+		return ""
+	}
+	return `\b(?:` + strings.Join(alts, "|") + `)` + wordChar + `*`
+}
+
+func quote(r []rune) string {
+	return regexp.QuoteMeta(string(r))
+}
+
+// Highlighter menandai kata yang cocok dengan Pattern
+type Highlighter struct {
+	re *regexp.Regexp
+}
+
+// NewHighlighter membuat highlighter untuk terms; tanpa terms teks hanya di-escape
+func NewHighlighter(terms []string) *Highlighter {
+	h := &Highlighter{}
+	if p := Pattern(terms); p != "" {
+		h.re = regexp.MustCompile(`(?i)` + p)
+	}
+	return h
+}
+
+// Highlight mengembalikan text sebagai HTML yang sudah di-escape dengan kata yang
+// cocok dibungkus <mark>. Jika maxRunes > 0 dan text lebih panjang, hanya potongan
+// di sekitar kecocokan pertama yang dikembalikan, diberi "…" di sisi yang terpotong.
+func (h *Highlighter) Highlight(text string, maxRunes int) string {
+	var matches [][]int
+	if h.re != nil {
+		matches = h.re.FindAllStringIndex(text, -1)
+	}
+
+	start, end := 0, len(text)
+	if maxRunes > 0 && len([]rune(text)) > maxRunes {
+		center := 0
+		if len(matches) > 0 {
+			center = matches[0][0]
+		}
+		start, end = window(text, center, maxRunes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m[0] < pos || m[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		pos = m[1]
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// window memilih potongan maxRunes rune yang dimulai sedikit sebelum byte offset
+// center, dalam byte offset yang jatuh di batas rune
+func window(text string, center, maxRunes int) (int, int) {
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+
+	centerRune := 0
+	for i, off := range offsets {
+		if off >= center {
+			centerRune = i
+			break
+		}
+	}
+
+	first := centerRune - maxRunes/4
+	if first < 0 {
+		first = 0
+	}
+	last := first + maxRunes
+	if last > len(offsets)-1 {
+		last = len(offsets) - 1
+		first = last - maxRunes
+	}
+	return offsets[first], offsets[last]
+}
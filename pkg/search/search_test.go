@@ -0,0 +1,179 @@
+package search
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Belajar Golang", []string{"belajar", "golang"}},
+		{"  go, GO & Go!  ", []string{"go"}},
+		{"course's basics", []string{"course", "basics"}},
+		{"a b c", []string{}},
+		{"HTML5 dan CSS3", []string{"html5", "dan", "css3"}},
+		{"jaringan-komputer/dasar", []string{"jaringan", "komputer", "dasar"}},
+		{"über Straße", []string{"über", "straße"}},
+		{"one two three four five six seven eight nine ten", []string{"one", "two", "three", "four", "five", "six", "seven", "eight"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		text  string
+		want  bool
+	}{
+		{"exact word", []string{"jaringan"}, "Dasar Jaringan Komputer", true},
+		{"prefix of longer word", []string{"jaring"}, "Dasar Jaringan Komputer", true},
+		{"short term as prefix", []string{"go"}, "Belajar Golang", true},
+		{"short term not inside word", []string{"go"}, "Algoritma", false},
+		{"short term no typo tolerance", []string{"gp"}, "Belajar Golang", false},
+		{"substituted letter", []string{"jarinxan"}, "Dasar Jaringan", true},
+		{"missing letter", []string{"jarngan"}, "Dasar Jaringan", true},
+		{"extra letter", []string{"jaringgan"}, "Dasar Jaringan", true},
+		{"swapped letters", []string{"jairngan"}, "Dasar Jaringan", true},
+		{"two typos", []string{"jairngn"}, "Dasar Jaringan", false},
+		{"case insensitive", []string{"komputer"}, "KOMPUTER", true},
+		{"any term matches", []string{"python", "komputer"}, "Jaringan Komputer", true},
+		{"digits", []string{"html5"}, "Pengenalan HTML5 dan CSS", true},
+		{"regex metacharacters are literal", []string{"c++"}, "Pemrograman cxx", false},
+		{"unrelated word", []string{"database"}, "Dasar Jaringan", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := regexp.Compile(`(?i)` + Pattern(tt.terms))
+			if err != nil {
+				t.Fatalf("Pattern(%q) does not compile: %v", tt.terms, err)
+			}
+			if got := re.MatchString(tt.text); got != tt.want {
+				t.Errorf("Pattern(%q) match %q = %v, want %v", tt.terms, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternEmpty(t *testing.T) {
+	if got := Pattern(nil); got != "" {
+		t.Errorf("Pattern(nil) = %q, want empty", got)
+	}
+}
+
+func TestPatternLongTermIsLiteral(t *testing.T) {
+	long := strings.Repeat("a", maxFuzzyLen+1)
+	if got, want := Pattern([]string{long}), `\b(?:`+long+`)`+wordChar+`*`; got != want {
+		t.Errorf("Pattern(long term) = %q, want %q", got, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		terms    []string
+		text     string
+		maxRunes int
+		want     string
+	}{
+		{
+			name:  "marks matching words",
+			terms: []string{"jaringan"},
+			text:  "Dasar Jaringan Komputer",
+			want:  "Dasar <mark>Jaringan</mark> Komputer",
+		},
+		{
+			name:  "marks whole word from prefix",
+			terms: []string{"komp"},
+			text:  "Komputer dan komponen",
+			want:  "<mark>Komputer</mark> dan <mark>komponen</mark>",
+		},
+		{
+			name:  "marks typo match",
+			terms: []string{"jarngan"},
+			text:  "Dasar Jaringan",
+			want:  "Dasar <mark>Jaringan</mark>",
+		},
+		{
+			name:  "escapes html",
+			terms: []string{"script"},
+			text:  `<b>Script</b> & "quotes"`,
+			want:  `&lt;b&gt;<mark>Script</mark>&lt;/b&gt; &amp; &#34;quotes&#34;`,
+		},
+		{
+			name: "no terms only escapes",
+			text: "a < b",
+			want: "a &lt; b",
+		},
+		{
+			name:  "no match",
+			terms: []string{"python"},
+			text:  "Dasar Jaringan",
+			want:  "Dasar Jaringan",
+		},
+		{
+			name:     "short text is not truncated",
+			terms:    []string{"dasar"},
+			text:     "Dasar Jaringan",
+			maxRunes: 100,
+			want:     "<mark>Dasar</mark> Jaringan",
+		},
+		{
+			name:     "truncates around first match",
+			terms:    []string{"komputer"},
+			text:     "satu dua tiga empat lima enam komputer tujuh delapan sembilan sepuluh",
+			maxRunes: 20,
+			want:     "…enam <mark>komputer</mark> tujuh …",
+		},
+		{
+			name:     "truncates from start without match",
+			terms:    []string{"python"},
+			text:     "satu dua tiga empat lima",
+			maxRunes: 10,
+			want:     "satu dua t…",
+		},
+		{
+			name:     "truncates at end",
+			terms:    []string{"sepuluh"},
+			text:     "satu dua tiga empat lima sepuluh",
+			maxRunes: 12,
+			want:     "…lima <mark>sepuluh</mark>",
+		},
+		{
+			name:     "truncation respects multibyte runes",
+			terms:    []string{"kopi"},
+			text:     "ééééééééé kopi ééééééééé",
+			maxRunes: 8,
+			want:     "…é <mark>kopi</mark> é…",
+		},
+		{
+			name:     "match cut by window is not marked",
+			terms:    []string{"satu", "sepuluh"},
+			text:     "satu dua tiga empat lima enam tujuh sepuluh",
+			maxRunes: 10,
+			want:     "<mark>satu</mark> dua t…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewHighlighter(tt.terms).Highlight(tt.text, tt.maxRunes); got != tt.want {
+				t.Errorf("Highlight(%q, %d) = %q, want %q", tt.text, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
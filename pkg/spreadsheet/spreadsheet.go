@@ -0,0 +1,70 @@
+// Package spreadsheet reads tabular uploads (CSV and XLSX) into rows of strings.
+// Only the first worksheet of an XLSX workbook is read; formulas are returned as
+// their cached value, which is what Excel and LibreOffice save alongside them.
+package spreadsheet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// MaxRows membatasi jumlah baris yang dibaca agar upload besar tidak menghabiskan memori
+const MaxRows = 10000
+
+var ErrTooManyRows = errors.New("spreadsheet has too many rows")
+
+// Read memilih parser berdasarkan ekstensi file (.csv atau .xlsx)
+func Read(filename string, data []byte) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return ReadCSV(bytes.NewReader(data))
+	case ".xlsx":
+		return ReadXLSX(bytes.NewReader(data), int64(len(data)))
+	default:
+		return nil, errors.New("unsupported file type, use .csv or .xlsx")
+	}
+}
+
+// ReadCSV membaca CSV dengan pemisah koma atau titik koma (format default Excel
+// untuk locale Indonesia). BOM UTF-8 di awal file diabaikan.
+func ReadCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if firstLine, _ := br.Peek(br.Buffered()); isSemicolonSeparated(firstLine) {
+		reader.Comma = ';'
+	}
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) >= MaxRows {
+			return nil, ErrTooManyRows
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+func isSemicolonSeparated(buf []byte) bool {
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		buf = buf[:i]
+	}
+	return bytes.Count(buf, []byte{';'}) > bytes.Count(buf, []byte{','})
+}
@@ -0,0 +1,356 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testWorkbook = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Users" sheetId="1" r:id="rId1"/><sheet name="Other" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`
+
+	testRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+	testSharedStrings = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>name</t></si><si><t>email</t></si><si><r><t>Budi </t></r><r><t>Santoso</t></r></si>
+</sst>`
+)
+
+// sheetXML membungkus baris <row> menjadi worksheet
+func sheetXML(rows string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + rows + `</sheetData></worksheet>`
+}
+
+// buildXLSX membuat file XLSX minimal dari isi part; nilai "" berarti part tidak disertakan
+func buildXLSX(t *testing.T, override map[string]string) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testRels,
+		"xl/sharedStrings.xml":       testSharedStrings,
+		"xl/worksheets/sheet1.xml":   sheetXML(`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>`),
+		"xl/worksheets/sheet2.xml":   sheetXML(`<row r="1"><c r="A1" t="inlineStr"><is><t>other sheet</t></is></c></row>`),
+	}
+	for name, content := range override {
+		parts[name] = content
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		if content == "" {
+			continue
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSX(t *testing.T) {
+	tests := []struct {
+		name     string
+		override map[string]string
+		want     [][]string
+		wantErr  string
+	}{
+		{
+			name: "shared strings",
+			want: [][]string{{"name", "email"}},
+		},
+		{
+			name: "inline, rich text and numbers",
+			override: map[string]string{"xl/worksheets/sheet1.xml": sheetXML(
+				`<row r="1"><c r="A1" t="s"><v>2</v></c><c r="B1" t="inlineStr"><is><t>budi@example.com</t></is></c><c r="C1"><v>42</v></c></row>`)},
+			want: [][]string{{"Budi Santoso", "budi@example.com", "42"}},
+		},
+		{
+			name: "empty rows and columns are kept",
+			override: map[string]string{"xl/worksheets/sheet1.xml": sheetXML(
+				`<row r="1"><c r="A1" t="s"><v>0</v></c></row><row r="3"><c r="C3" t="s"><v>1</v></c></row>`)},
+			want: [][]string{{"name"}, nil, {"", "", "email"}},
+		},
+		{
+			name: "cells without references",
+			override: map[string]string{"xl/worksheets/sheet1.xml": sheetXML(
+				`<row><c t="s"><v>0</v></c><c t="s"><v>1</v></c></row><row><c><v>1</v></c></row>`)},
+			want: [][]string{{"name", "email"}, {"1"}},
+		},
+		{
+			name: "without shared strings",
+			override: map[string]string{
+				"xl/sharedStrings.xml":     "",
+				"xl/worksheets/sheet1.xml": sheetXML(`<row r="1"><c r="A1" t="inlineStr"><is><t>name</t></is></c></row>`),
+			},
+			want: [][]string{{"name"}},
+		},
+		{
+			name: "absolute relationship target",
+			override: map[string]string{"xl/_rels/workbook.xml.rels": strings.Replace(testRels,
+				`Target="worksheets/sheet1.xml"`, `Target="/xl/worksheets/sheet1.xml"`, 1)},
+			want: [][]string{{"name", "email"}},
+		},
+		{
+			name: "invalid shared string reference",
+			override: map[string]string{"xl/worksheets/sheet1.xml": sheetXML(
+				`<row r="1"><c r="A1" t="s"><v>9</v></c></row>`)},
+			wantErr: "invalid shared string reference in cell A1",
+		},
+		{
+			name: "invalid cell reference",
+			override: map[string]string{"xl/worksheets/sheet1.xml": sheetXML(
+				`<row r="1"><c r="ABCD1"><v>1</v></c></row>`)},
+			wantErr: `invalid cell reference "ABCD1"`,
+		},
+		{
+			name:     "missing worksheet",
+			override: map[string]string{"xl/worksheets/sheet1.xml": ""},
+			wantErr:  "xlsx part not found: xl/worksheets/sheet1.xml",
+		},
+		{
+			name:     "missing workbook",
+			override: map[string]string{"xl/workbook.xml": ""},
+			wantErr:  "xlsx part not found: xl/workbook.xml",
+		},
+		{
+			name:     "workbook without sheets",
+			override: map[string]string{"xl/workbook.xml": `<workbook><sheets></sheets></workbook>`},
+			wantErr:  "xlsx file has no worksheets",
+		},
+		{
+			name:     "malformed worksheet",
+			override: map[string]string{"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row>`},
+			wantErr:  "invalid xlsx part xl/worksheets/sheet1.xml",
+		},
+		{
+			name: "too many rows",
+			override: map[string]string{"xl/worksheets/sheet1.xml": sheetXML(
+				fmt.Sprintf(`<row r="%d"><c><v>1</v></c></row>`, MaxRows+1))},
+			wantErr: ErrTooManyRows.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildXLSX(t, tt.override)
+			got, err := ReadXLSX(bytes.NewReader(data), int64(len(data)))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadXLSX() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadXLSX() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadXLSX() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// rawPart menulis part terkompresi dengan ukuran asli yang dideklarasikan bebas,
+// seperti zip bomb yang berbohong tentang ukurannya
+func rawPart(t *testing.T, zw *zip.Writer, name string, content []byte, declaredSize uint64) {
+	t.Helper()
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	fw.Close()
+
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(content),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: declaredSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(compressed.Bytes())
+}
+
+func TestReadXLSXZipBomb(t *testing.T) {
+	// Worksheet valid yang diakhiri spasi dalam jumlah besar (terkompresi menjadi sangat kecil)
+	sheet := sheetXML(`<row r="1"><c r="A1"><v>1</v></c></row>`)
+	bomb := append([]byte(sheet), bytes.Repeat([]byte(" "), 1<<20)...)
+
+	tests := []struct {
+		name         string
+		declaredSize uint64
+		wantErr      string
+	}{
+		{"declared size above limit", maxPartSize + 1, "xlsx part xl/worksheets/sheet1.xml is too large"},
+		{"declared size smaller than content", uint64(len(sheet)), "zip: not a valid zip file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			for name, content := range map[string]string{
+				"xl/workbook.xml":            testWorkbook,
+				"xl/_rels/workbook.xml.rels": testRels,
+			} {
+				w, err := zw.Create(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write([]byte(content))
+			}
+			rawPart(t, zw, "xl/worksheets/sheet1.xml", bomb, tt.declaredSize)
+			if err := zw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			data := buf.Bytes()
+			if len(data) > 64*1024 {
+				t.Fatalf("test archive is %d bytes, expected a small compressed bomb", len(data))
+			}
+			_, err := ReadXLSX(bytes.NewReader(data), int64(len(data)))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ReadXLSX() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadXLSXNotZip(t *testing.T) {
+	data := []byte("name,email\n")
+	if _, err := ReadXLSX(bytes.NewReader(data), int64(len(data))); err == nil || err.Error() != "invalid xlsx file" {
+		t.Fatalf("ReadXLSX() error = %v, want invalid xlsx file", err)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    [][]string
+		wantErr error
+	}{
+		{
+			name:  "comma separated",
+			input: "name,email\nBudi,budi@example.com\n",
+			want:  [][]string{{"name", "email"}, {"Budi", "budi@example.com"}},
+		},
+		{
+			name:  "semicolon separated",
+			input: "name;email\nBudi, S.Kom;budi@example.com\n",
+			want:  [][]string{{"name", "email"}, {"Budi, S.Kom", "budi@example.com"}},
+		},
+		{
+			name:  "byte order mark",
+			input: "\xEF\xBB\xBFname,email\r\nBudi,budi@example.com\r\n",
+			want:  [][]string{{"name", "email"}, {"Budi", "budi@example.com"}},
+		},
+		{
+			name:  "rows of different length",
+			input: "name,email,role\nBudi,budi@example.com\n",
+			want:  [][]string{{"name", "email", "role"}, {"Budi", "budi@example.com"}},
+		},
+		{
+			name:  "leading spaces are trimmed",
+			input: "name, email\n",
+			want:  [][]string{{"name", "email"}},
+		},
+		{
+			name:    "too many rows",
+			input:   strings.Repeat("a,b\n", MaxRows+1),
+			wantErr: ErrTooManyRows,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadCSV(strings.NewReader(tt.input))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ReadCSV() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadCSV() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadCSV() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	xlsx := buildXLSX(t, nil)
+	tests := []struct {
+		filename string
+		data     []byte
+		want     [][]string
+		wantErr  bool
+	}{
+		{"users.csv", []byte("name,email\n"), [][]string{{"name", "email"}}, false},
+		{"USERS.CSV", []byte("name,email\n"), [][]string{{"name", "email"}}, false},
+		{"users.xlsx", xlsx, [][]string{{"name", "email"}}, false},
+		{"users.xls", xlsx, nil, true},
+		{"users", []byte("name,email\n"), nil, true},
+	}
+	for _, tt := range tests {
+		got, err := Read(tt.filename, tt.data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Read(%q) error = %v, wantErr %v", tt.filename, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Read(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{"A1", 0, false},
+		{"Z9", 25, false},
+		{"AA10", 26, false},
+		{"AZ1", 51, false},
+		{"XFD1048576", 16383, false}, // Kolom terakhir Excel
+		{"1", 0, true},
+		{"a1", 0, true},
+		{"ABCD1", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := columnIndex(tt.ref)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("columnIndex(%q) = (%d, %v), want (%d, wantErr %v)", tt.ref, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
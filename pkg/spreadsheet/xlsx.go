@@ -0,0 +1,196 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize membatasi ukuran XML yang didekompresi (perlindungan zip bomb)
+const maxPartSize = 50 * 1024 * 1024
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+// xlsxRichText - Teks biasa (<t>) atau teks berformat (<r><t>) di shared strings/inline string
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX membaca worksheet pertama dari file XLSX. Baris kosong di antara
+// data dipertahankan sebagai baris kosong agar nomor baris sama dengan di Excel.
+func ReadXLSX(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.New("invalid xlsx file")
+	}
+
+	sheetPath, err := firstSheetPath(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if err := decodePart(zr, "xl/sharedStrings.xml", &shared); err != nil && !errors.Is(err, errPartNotFound) {
+		return nil, err
+	}
+
+	var sheet xlsxWorksheet
+	if err := decodePart(zr, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		rowIndex := row.Index
+		if rowIndex == 0 {
+			rowIndex = len(rows) + 1
+		}
+		if rowIndex > MaxRows {
+			return nil, ErrTooManyRows
+		}
+		for len(rows) < rowIndex {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				if col, err = columnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("invalid shared string reference in cell %s", cell.Ref)
+				}
+				values[col] = shared.Items[idx].String()
+			case "inlineStr":
+				values[col] = cell.Inline.String()
+			default:
+				values[col] = cell.Value
+			}
+		}
+		rows[rowIndex-1] = values
+	}
+	return rows, nil
+}
+
+var errPartNotFound = errors.New("xlsx part not found")
+
+// firstSheetPath mencari file XML worksheet pertama lewat workbook.xml dan relasinya
+func firstSheetPath(zr *zip.Reader) (string, error) {
+	var wb xlsxWorkbook
+	if err := decodePart(zr, "xl/workbook.xml", &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", errors.New("xlsx file has no worksheets")
+	}
+
+	var rels xlsxRelationships
+	if err := decodePart(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].RelID {
+			continue
+		}
+		// Target relatif terhadap folder xl/, kecuali diawali "/"
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", errors.New("xlsx worksheet not found")
+}
+
+func decodePart(zr *zip.Reader, name string, v interface{}) error {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		if f.UncompressedSize64 > maxPartSize {
+			return fmt.Errorf("xlsx part %s is too large", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v); err != nil {
+			return fmt.Errorf("invalid xlsx part %s: %w", name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errPartNotFound, name)
+}
+
+// columnIndex mengubah referensi sel seperti "C12" menjadi indeks kolom 0-based
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 || n > 3 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
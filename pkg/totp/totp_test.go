@@ -0,0 +1,169 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret - Secret SHA-1 dari lampiran B RFC 6238 ("12345678901234567890") dalam base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// Nilai 8 digit dari RFC 6238 lampiran B; Digits = 6 sehingga yang dibandingkan 6 digit terakhir
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(T=%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeRFC4226(t *testing.T) {
+	// HOTP lampiran D RFC 4226: TOTP adalah HOTP dengan counter = time step
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for step, code := range want {
+		got, err := Code(rfcSecret, int64(step))
+		if err != nil {
+			t.Fatalf("Code(step=%d): %v", step, err)
+		}
+		if got != code {
+			t.Errorf("Code(step=%d) = %s, want %s", step, got, code)
+		}
+	}
+}
+
+func TestCodeSecretFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"lowercase", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", false},
+		{"surrounding whitespace", "  " + rfcSecret + "\n", false},
+		{"invalid base32", "not-a-secret!", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(tt.secret, 1)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Code() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Code() error = %v", err)
+			}
+			if got != "287082" {
+				t.Errorf("Code() = %s, want 287082", got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0) // step 37037037, kode 050471
+	step := Step(now)
+
+	codeAt := func(offset int64) string {
+		code, err := Code(rfcSecret, step+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, "050471", 0, step, true},
+		{"spaces are ignored", rfcSecret, " 050 471 ", 0, step, true},
+		{"previous step within skew", rfcSecret, codeAt(-1), 1, step - 1, true},
+		{"next step within skew", rfcSecret, codeAt(1), 1, step + 1, true},
+		{"previous step without skew", rfcSecret, codeAt(-1), 0, 0, false},
+		{"outside skew", rfcSecret, codeAt(2), 1, 0, false},
+		{"wrong code", rfcSecret, "000000", 1, 0, false},
+		{"too short", rfcSecret, "05047", 1, 0, false},
+		{"too long", rfcSecret, "0504710", 1, 0, false},
+		{"invalid secret", "not-a-secret!", "050471", 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Validate(tt.secret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Validate() = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not unpadded base32: %v", secret, err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("secret decodes to %d bytes, want %d", len(key), secretSize)
+	}
+
+	other, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == secret {
+		t.Errorf("GenerateSecret() returned the same secret twice")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("OnLearn", "budi@example.com", rfcSecret)
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("url.Parse(%q): %v", uri, err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("uri = %q, want otpauth://totp/...", uri)
+	}
+	if u.Path != "/OnLearn:budi@example.com" {
+		t.Errorf("label = %q, want /OnLearn:budi@example.com", u.Path)
+	}
+
+	want := map[string]string{
+		"secret":    rfcSecret,
+		"issuer":    "OnLearn",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	q := u.Query()
+	for key, value := range want {
+		if got := q.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
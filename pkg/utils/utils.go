@@ -1,8 +1,15 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/rand"
+	"math/big"
+	mrand "math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -16,15 +23,28 @@ import (
 
 const (
 	UploadDirectory = "./uploads"
+
+	// AccessTokenTTL adalah masa berlaku access token (JWT)
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL adalah masa berlaku refresh token (session di server)
+	RefreshTokenTTL = 7 * 24 * time.Hour
+	// ImpersonationTTL adalah masa berlaku token "view as" admin (tanpa refresh)
+	ImpersonationTTL = 30 * time.Minute
 )
 
-// getJWTSecret gets JWT secret from environment variable
-func getJWTSecret() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default-secret-key-change-in-production"
+var jwtSecret []byte
+
+// SetJWTSecret sets the key used to sign and verify access tokens. It must be
+// called once at startup, before any token is issued or validated.
+func SetJWTSecret(secret string) {
+	jwtSecret = []byte(secret)
+}
+
+func getJWTSecret() ([]byte, error) {
+	if len(jwtSecret) == 0 {
+		return nil, errors.New("jwt secret is not configured")
 	}
-	return []byte(secret)
+	return jwtSecret, nil
 }
 
 // HashPassword hashes a password using bcrypt.
@@ -41,24 +61,35 @@ func CheckPasswordHash(password, hash string) bool {
 
 // Claims defines the JWT claims.
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
+	Verified  bool   `json:"verified"`
+	// ImpersonatorID is the admin acting as UserID; zero for normal logins.
+	ImpersonatorID uint `json:"imp,omitempty"`
+	// OrganizationID is the tenant of UserID; zero for super admins.
+	OrganizationID uint `json:"org,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT generates a new JWT token.
-func GenerateJWT(userID uint, role string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		UserID: userID,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// GenerateJWT generates a new short-lived access token bound to a server-side session.
+func GenerateJWT(claims Claims) (string, error) {
+	return GenerateJWTWithTTL(claims, AccessTokenTTL)
+}
+
+// GenerateJWTWithTTL generates an access token with a custom lifetime.
+func GenerateJWTWithTTL(claims Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(getJWTSecret())
+	secret, err := getJWTSecret()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
+	return token.SignedString(secret)
 }
 
 // ValidateJWT validates a JWT token.
@@ -68,7 +99,7 @@ func ValidateJWT(tokenStr string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return getJWTSecret(), nil
+		return getJWTSecret()
 	})
 	if err != nil {
 		return nil, err
@@ -79,6 +110,46 @@ func ValidateJWT(tokenStr string) (*Claims, error) {
 	return claims, nil
 }
 
+// GenerateSecureToken generates a URL-safe random token with n bytes of entropy.
+func GenerateSecureToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateNumericCode generates a random numeric code of the given length (e.g. OTP).
+func GenerateNumericCode(length int) (string, error) {
+	const digits = "0123456789"
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = digits[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest of a token, used to store tokens at rest.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashCode returns the hex HMAC-SHA256 of a short code bound to subject (e.g. a user ID),
+// keyed with the server secret. Unlike HashToken, a leaked digest of a low-entropy code
+// cannot be brute-forced offline without the secret.
+func HashCode(subject, code string) string {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(subject))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func HandleUpload(c *gin.Context, formFile string) (string, error) {
 	file, err := c.FormFile(formFile)
 	if err != nil {
@@ -108,13 +179,7 @@ func randString(n int) string {
 	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		b[i] = letters[mrand.Intn(len(letters))]
 	}
 	return string(b)
 }
-
-// SendEmail is a placeholder for email functionality
-func SendEmail(to, subject, body string) error {
-	fmt.Printf("--- EMAIL SENT ---\nTo: %s\nSubject: %s\nBody: %s\n-----------------\n", to, subject, body)
-	return nil
-}